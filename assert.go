@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// assertRule is a single response assertion with its counters
+type assertRule struct {
+	Name   string
+	Passed int
+	Failed int
+	check  func(r *replay.Result) bool
+	// header is the response header the rule needs to be recorded
+	header string
+	// body is set for rules which need the response body
+	body bool
+}
+
+// parseAssertRule parses status=2xx,304 (statuses as in -skip-status), latency<500ms, body~regexp,
+// header:Name (present) and header:Name~regexp rules
+func parseAssertRule(spec string) (*assertRule, error) {
+	spec = strings.TrimSpace(spec)
+	rule := &assertRule{Name: spec}
+
+	switch {
+	case strings.HasPrefix(spec, "status="):
+		statuses, err := parseStatusSet(strings.TrimPrefix(spec, "status="))
+
+		if err != nil {
+			return nil, err
+		}
+
+		rule.check = func(r *replay.Result) bool {
+			return r.Err == nil && statuses.Match(r.Status)
+		}
+	case strings.HasPrefix(spec, "latency<"):
+		limit, err := time.ParseDuration(strings.TrimPrefix(spec, "latency<"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid latency in assertion '%s': %s", spec, err)
+		}
+
+		rule.check = func(r *replay.Result) bool {
+			return r.Err == nil && r.Duration < limit
+		}
+	case strings.HasPrefix(spec, "body~"):
+		re, err := regexp.Compile(strings.TrimPrefix(spec, "body~"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regexp in assertion '%s': %s", spec, err)
+		}
+
+		rule.body = true
+		rule.check = func(r *replay.Result) bool {
+			return r.Err == nil && re.Match(r.Body)
+		}
+	case strings.HasPrefix(spec, "header:"):
+		name := strings.TrimPrefix(spec, "header:")
+		var re *regexp.Regexp
+
+		if i := strings.Index(name, "~"); i >= 0 {
+			var err error
+
+			if re, err = regexp.Compile(name[i+1:]); err != nil {
+				return nil, fmt.Errorf("Invalid regexp in assertion '%s': %s", spec, err)
+			}
+
+			name = name[:i]
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("Missing header name in assertion '%s'", spec)
+		}
+
+		rule.header = name
+		rule.check = func(r *replay.Result) bool {
+			value := r.Headers[name]
+
+			if re != nil {
+				return r.Err == nil && re.MatchString(value)
+			}
+
+			return r.Err == nil && value != ""
+		}
+	default:
+		return nil, fmt.Errorf("Unknown assertion '%s', expected status=..., latency<..., body~... or header:...", spec)
+	}
+
+	return rule, nil
+}
+
+// assertList collects repeated -assert flags
+type assertList struct {
+	rules []*assertRule
+}
+
+func (a *assertList) String() string {
+	if a == nil {
+		return ""
+	}
+
+	var names []string
+
+	for _, rule := range a.rules {
+		names = append(names, rule.Name)
+	}
+
+	return strings.Join(names, " ")
+}
+
+func (a *assertList) Set(spec string) error {
+	rule, err := parseAssertRule(spec)
+
+	if err != nil {
+		return err
+	}
+
+	a.rules = append(a.rules, rule)
+
+	return nil
+}
+
+// load adds rules of the file, one per line, empty lines and lines starting with # are skipped
+func (a *assertList) load(file string) error {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := a.Set(line); err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// needsBody tells if some rule checks the response body
+func (a *assertList) needsBody() bool {
+	for _, rule := range a.rules {
+		if rule.body {
+			return true
+		}
+	}
+
+	return false
+}
+
+// headers returns names of the headers rules check
+func (a *assertList) headers() []string {
+	var names []string
+
+	for _, rule := range a.rules {
+		if rule.header != "" {
+			names = append(names, rule.header)
+		}
+	}
+
+	return names
+}
+
+// failed returns the number of failed checks of all the rules
+func (a *assertList) failed() int {
+	var failed int
+
+	for _, rule := range a.rules {
+		failed += rule.Failed
+	}
+
+	return failed
+}
+
+// assertSink checks every result against the rules, it comes before output sinks and drops
+// bodies captured only for the rules, keepBody tells which were captured with -capture-body
+type assertSink struct {
+	list     *assertList
+	keepBody func(status int) bool
+}
+
+func (s *assertSink) Write(r *replay.Result) error {
+	for _, rule := range s.list.rules {
+		if rule.check(r) {
+			rule.Passed++
+		} else {
+			rule.Failed++
+		}
+	}
+
+	if s.keepBody == nil || !s.keepBody(r.Status) {
+		r.Body = nil
+		r.BodyTruncated = false
+	}
+
+	return nil
+}
+
+// print writes per rule counters
+func (a *assertList) print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Assertions:\tpassed\tfailed\n")
+
+	for _, rule := range a.rules {
+		fmt.Fprintf(w, "  %s\t%d\t%d\n", rule.Name, rule.Passed, rule.Failed)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+func TestParseAssertRule(t *testing.T) {
+	tests := []struct {
+		spec   string
+		header string
+		body   bool
+		err    string
+	}{
+		{spec: "status=2xx,304"},
+		{spec: " latency<500ms "},
+		{spec: "body~\"ok\":true", body: true},
+		{spec: "header:Content-Type", header: "Content-Type"},
+		{spec: "header:Content-Type~^application/json", header: "Content-Type"},
+		{spec: "status=abc", err: "Invalid status 'abc'"},
+		{spec: "status=503-500", err: "Invalid status range '503-500'"},
+		{spec: "latency<fast", err: "Invalid latency in assertion 'latency<fast'"},
+		{spec: "body~(", err: "Invalid regexp in assertion 'body~('"},
+		{spec: "header:X~[", err: "Invalid regexp in assertion 'header:X~['"},
+		{spec: "header:", err: "Missing header name in assertion 'header:'"},
+		{spec: "header:~ok", err: "Missing header name"},
+		{spec: "latency>500ms", err: "Unknown assertion 'latency>500ms'"},
+		{spec: "", err: "Unknown assertion ''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := parseAssertRule(tt.spec)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rule.Name != strings.TrimSpace(tt.spec) || rule.header != tt.header || rule.body != tt.body {
+				t.Errorf("rule %q header %q body %t, expected %q header %q body %t", rule.Name, rule.header, rule.body, tt.spec, tt.header, tt.body)
+			}
+		})
+	}
+}
+
+func TestAssertRuleCheck(t *testing.T) {
+	failed := errors.New("connection refused")
+
+	tests := []struct {
+		spec     string
+		result   replay.Result
+		expected bool
+	}{
+		{spec: "status=2xx,304", result: replay.Result{Status: 204}, expected: true},
+		{spec: "status=2xx,304", result: replay.Result{Status: 304}, expected: true},
+		{spec: "status=2xx,304", result: replay.Result{Status: 404}},
+		{spec: "status=500-599", result: replay.Result{Err: failed}},
+		{spec: "latency<500ms", result: replay.Result{Status: 200, Duration: 499 * time.Millisecond}, expected: true},
+		{spec: "latency<500ms", result: replay.Result{Status: 200, Duration: 500 * time.Millisecond}},
+		{spec: "latency<500ms", result: replay.Result{Err: failed, Duration: time.Millisecond}},
+		{spec: `body~"ok":true`, result: replay.Result{Status: 200, Body: []byte(`{"ok":true}`)}, expected: true},
+		{spec: `body~"ok":true`, result: replay.Result{Status: 200, Body: []byte(`{"ok":false}`)}},
+		{spec: `body~^$`, result: replay.Result{Err: failed}},
+		{spec: "header:ETag", result: replay.Result{Status: 200, Headers: map[string]string{"ETag": `"abc"`}}, expected: true},
+		{spec: "header:ETag", result: replay.Result{Status: 200, Headers: map[string]string{"ETag": ""}}},
+		{spec: "header:ETag", result: replay.Result{Status: 200}},
+		{spec: "header:Content-Type~^application/json", result: replay.Result{Status: 200, Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}}, expected: true},
+		{spec: "header:Content-Type~^application/json", result: replay.Result{Status: 200, Headers: map[string]string{"Content-Type": "text/html"}}},
+		{spec: "header:Content-Type~.*", result: replay.Result{Err: failed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := parseAssertRule(tt.spec)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok := rule.check(&tt.result); ok != tt.expected {
+				t.Errorf("check of %+v returned %t, expected %t", tt.result, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAssertSink(t *testing.T) {
+	list := &assertList{}
+
+	for _, spec := range []string{"status=2xx", "body~ok", "header:ETag"} {
+		if err := list.Set(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !list.needsBody() || strings.Join(list.headers(), ",") != "ETag" {
+		t.Fatalf("needsBody %t, headers %q", list.needsBody(), list.headers())
+	}
+
+	sink := &assertSink{list: list, keepBody: func(status int) bool { return status >= 500 }}
+
+	ok := &replay.Result{Status: 200, Body: []byte("ok"), Headers: map[string]string{"ETag": "1"}}
+	failing := &replay.Result{Status: 503, Body: []byte("down")}
+
+	for _, r := range []*replay.Result{ok, failing} {
+		if err := sink.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, rule := range list.rules {
+		if rule.Passed != 1 || rule.Failed != 1 {
+			t.Errorf("%s passed %d and failed %d, expected 1 and 1", rule.Name, rule.Passed, rule.Failed)
+		}
+	}
+
+	if list.failed() != 3 {
+		t.Errorf("%d failed checks, expected 3", list.failed())
+	}
+
+	// Bodies captured only for the rules are dropped
+	if ok.Body != nil || string(failing.Body) != "down" {
+		t.Errorf("bodies %q and %q, expected only the 503 one to be kept", ok.Body, failing.Body)
+	}
+}
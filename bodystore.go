@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// bodyStore looks up request bodies missing in access logs
+type bodyStore interface {
+	Lookup(method, url string) (string, bool)
+}
+
+// bodyKey is the name of the body file for the url in the body directory
+func bodyKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// dirBodyStore reads bodies from files named by hex encoded SHA-256 of the url (path with query)
+type dirBodyStore struct {
+	dir string
+}
+
+func (d *dirBodyStore) Lookup(method, url string) (string, bool) {
+	body, err := ioutil.ReadFile(filepath.Join(d.dir, bodyKey(url)))
+
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("ERROR %s while reading body of %s %s", err, method, url)
+		}
+
+		return "", false
+	}
+
+	return string(body), true
+}
+
+// bodyRecord is a single line of the JSON lines body file
+type bodyRecord struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body"`
+}
+
+// jsonlBodyStore keeps bodies of the JSON lines body file in memory, when the same request
+// appears several times its bodies are used in the file order, starting over when they run out
+type jsonlBodyStore struct {
+	bodies map[string][]string
+	next   map[string]int
+}
+
+func newJSONLBodyStore(file string) (*jsonlBodyStore, error) {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	store := &jsonlBodyStore{bodies: make(map[string][]string), next: make(map[string]int)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record bodyRecord
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("ERROR %s while parsing line %d of %s", err, line, file)
+		}
+
+		key := record.Method + " " + record.URL
+		store.bodies[key] = append(store.bodies[key], record.Body)
+	}
+
+	return store, scanner.Err()
+}
+
+func (j *jsonlBodyStore) Lookup(method, url string) (string, bool) {
+	key := method + " " + url
+	bodies := j.bodies[key]
+
+	if len(bodies) == 0 {
+		// Method is optional in the body file
+		key = " " + url
+		bodies = j.bodies[key]
+	}
+
+	if len(bodies) == 0 {
+		return "", false
+	}
+
+	i := j.next[key]
+	j.next[key] = (i + 1) % len(bodies)
+
+	return bodies[i], true
+}
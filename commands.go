@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of the tool, flags are registered into its own flag set
+type command struct {
+	Name        string
+	Description string
+	Flags       []func(fs *flag.FlagSet)
+	Run         func(fs *flag.FlagSet)
+}
+
+var commands = []*command{
+	{
+		Name:        "replay",
+		Description: "Replay requests of the input log against the target (default)",
+		Flags:       []func(fs *flag.FlagSet){inputFlags, filterFlags, replayFlags},
+		Run:         runReplay,
+	},
+	{
+		Name:        "parse",
+		Description: "Print requests parsed from the input log without sending them",
+		Flags:       []func(fs *flag.FlagSet){inputFlags, filterFlags},
+		Run:         runParse,
+	},
+	{
+		Name:        "convert",
+		Description: "Convert the input log to jsonl format which is faster to replay",
+		Flags:       []func(fs *flag.FlagSet){inputFlags, filterFlags, convertFlags},
+		Run:         runConvert,
+	},
+	{
+		Name:        "record",
+		Description: "Proxy requests to a service and write them to a jsonl log to replay later",
+		Flags:       []func(fs *flag.FlagSet){recordFlags},
+		Run:         runRecord,
+	},
+	{
+		Name:        "report",
+		Description: "Print summary of a timings log written by replay",
+		Flags:       []func(fs *flag.FlagSet){reportFlags},
+		Run:         runReport,
+	},
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+func printCommands() {
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "Usage: log-replay [command] [flags]\n\nCommands:\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s%s\n", cmd.Name, cmd.Description)
+	}
+
+	fmt.Fprintf(out, "\nRun 'log-replay <command> -h' for flags of a command.\n")
+}
+
+// runCommand runs command named by the first argument, bare flags run replay
+// to keep invocations of the times before subcommands working
+func runCommand(args []string) {
+	cmd := commands[0]
+	bare := true
+
+	if len(args) > 0 {
+		if found := findCommand(args[0]); found != nil {
+			cmd = found
+			args = args[1:]
+			bare = false
+		} else if args[0] == "help" {
+			printCommands()
+			return
+		} else if len(args[0]) > 0 && args[0][0] != '-' {
+			fmt.Fprintf(flag.CommandLine.Output(), "Unknown command '%s'\n\n", args[0])
+			printCommands()
+			os.Exit(2)
+		}
+	}
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ExitOnError)
+
+	for _, register := range cmd.Flags {
+		register(fs)
+	}
+
+	fs.Usage = func() {
+		if bare {
+			printCommands()
+			fmt.Fprintf(fs.Output(), "\n")
+		}
+
+		fmt.Fprintf(fs.Output(), "Flags of %s:\n", cmd.Name)
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+	cmd.Run(fs)
+}
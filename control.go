@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// controlStatus is the response of the control API
+type controlStatus struct {
+	Paused bool `json:"paused"`
+}
+
+// pauseReplay and resumeReplay are shared by the control API and signals, source is logged
+func pauseReplay(replayer *replay.Replayer, source string) {
+	if replayer.Pause() {
+		log.Printf("Pausing replay (%s), requests in flight are completed", source)
+	}
+}
+
+func resumeReplay(replayer *replay.Replayer, source string) {
+	if replayer.Resume() {
+		log.Printf("Resuming replay (%s)", source)
+	}
+}
+
+// serveControl runs the control API: POST /pause, POST /resume and GET /status, all of them respond with the status
+func serveControl(addr string, replayer *replay.Replayer) error {
+	respond := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(controlStatus{Paused: replayer.Paused()})
+	}
+
+	action := func(do func(*replay.Replayer, string)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			do(replayer, "control API")
+			respond(w)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/pause", action(pauseReplay))
+	mux.Handle("/resume", action(resumeReplay))
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		respond(w)
+	})
+
+	return http.ListenAndServe(addr, mux)
+}
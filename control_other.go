@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// handlePauseSignals does nothing, SIGUSR1 and SIGUSR2 exist on unix only
+func handlePauseSignals(ctx context.Context, replayer *replay.Replayer) {}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// handlePauseSignals pauses the replay on SIGUSR1 and resumes it on SIGUSR2 until the context is done
+func handlePauseSignals(ctx context.Context, replayer *replay.Replayer) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			if sig == syscall.SIGUSR1 {
+				pauseReplay(replayer, "SIGUSR1")
+			} else {
+				resumeReplay(replayer, "SIGUSR2")
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+	"github.com/Gonzih/log-replay/pkg/reader/jsonl"
+)
+
+var outputFile string
+
+// convertFlags registers flags of the convert command
+func convertFlags(fs *flag.FlagSet) {
+	fs.StringVar(&outputFile, "out", "-", "File to write the jsonl log to, gzip compressed when the name ends with gz, default is stdout")
+}
+
+// runConvert is the convert command writing entries in the normalized jsonl format,
+// which is read back with -file-type jsonl without the cost of parsing the original log
+func runConvert(fs *flag.FlagSet) {
+	rdr, closeInput := openInput()
+	defer closeInput()
+
+	rdr = filterReader(rdr)
+
+	var errors int
+
+	if outputFile == "-" {
+		errors = convertEntries(rdr, os.Stdout)
+	} else {
+		file, err := os.Create(outputFile)
+		reader.Must(err)
+
+		if strings.HasSuffix(outputFile, "gz") {
+			gz := gzip.NewWriter(file)
+			errors = convertEntries(rdr, gz)
+			reader.Must(gz.Close())
+		} else {
+			errors = convertEntries(rdr, file)
+		}
+
+		reader.Must(file.Close())
+	}
+
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// convertEntries writes entries to out and returns the number of parse errors, lines which
+// can not be parsed are reported to STDERR and skipped
+func convertEntries(rdr reader.LogReader, out io.Writer) int {
+	buffered := bufio.NewWriter(out)
+	writer := jsonl.NewWriter(buffered)
+
+	count := 0
+	errors := 0
+
+	for {
+		entry, err := rdr.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			errors++
+			log.Printf("ERROR after %d entries: %s", count, err)
+
+			// The rest of the input can not be read
+			if reader.IsInputError(err) {
+				break
+			}
+
+			continue
+		}
+
+		reader.Must(writer.Write(entry))
+		count++
+	}
+
+	reader.Must(buffered.Flush())
+	log.Printf("Converted %d entries, %d errors", count, errors)
+
+	return errors
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// failCondition is a -fail-if threshold like p99>500ms evaluated at the end of the run
+type failCondition struct {
+	expr   string
+	metric string
+	op     string
+	value  float64
+}
+
+// Operators are matched longest first
+var failOperators = []string{">=", "<=", ">", "<"}
+
+// Latency metrics are compared in nanoseconds, rates in percent
+var failLatencyQuantiles = map[string]float64{"p50": 50, "p90": 90, "p95": 95, "p99": 99, "p999": 99.9}
+var failRates = map[string]bool{"error_rate": true, "5xx_rate": true, "4xx_rate": true, "mismatch_rate": true}
+
+func parseFailCondition(expr string) (*failCondition, error) {
+	expr = strings.TrimSpace(expr)
+
+	for _, op := range failOperators {
+		i := strings.Index(expr, op)
+
+		if i < 0 {
+			continue
+		}
+
+		cond := &failCondition{expr: expr, metric: strings.TrimSpace(expr[:i]), op: op}
+		value := strings.TrimSpace(expr[i+len(op):])
+		var err error
+
+		switch {
+		case failLatencyQuantiles[cond.metric] > 0, cond.metric == "max", cond.metric == "avg":
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			cond.value = float64(d)
+		case failRates[cond.metric]:
+			cond.value, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		case cond.metric == "requests", cond.metric == "throughput":
+			cond.value, err = strconv.ParseFloat(value, 64)
+		default:
+			return nil, fmt.Errorf("Unknown metric '%s' in '%s', expected error_rate, 5xx_rate, 4xx_rate, mismatch_rate, p50, p90, p95, p99, p999, max, avg, requests or throughput", cond.metric, expr)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value in '%s': %s", expr, err)
+		}
+
+		return cond, nil
+	}
+
+	return nil, fmt.Errorf("Invalid condition '%s', expected metric, operator (>, <, >=, <=) and value, e.g. p99>500ms", expr)
+}
+
+// measure returns the metric of the run and its human readable form
+func (c *failCondition) measure(sum *replay.Summary, verify *replay.VerifySummary) (float64, string) {
+	percent := func(n, total int) (float64, string) {
+		if total == 0 {
+			return 0, "0%"
+		}
+
+		v := float64(n) * 100 / float64(total)
+
+		return v, fmt.Sprintf("%.2f%%", v)
+	}
+
+	latency := func(v int64) (float64, string) {
+		if sum.Total == 0 {
+			return 0, "0s"
+		}
+
+		return float64(v), time.Duration(v).Round(time.Microsecond).String()
+	}
+
+	statusClass := func(class int) int {
+		var n int
+
+		for status, count := range sum.Statuses {
+			if status/100 == class {
+				n += count
+			}
+		}
+
+		return n
+	}
+
+	switch c.metric {
+	case "error_rate":
+		return percent(sum.Errors, sum.Total)
+	case "5xx_rate":
+		return percent(statusClass(5), sum.Total)
+	case "4xx_rate":
+		return percent(statusClass(4), sum.Total)
+	case "mismatch_rate":
+		if verify == nil {
+			return 0, "0% (needs -verify-status)"
+		}
+
+		return percent(verify.Mismatches, verify.Total)
+	case "max":
+		return latency(sum.Latency.Max())
+	case "avg":
+		return latency(int64(sum.Latency.Mean()))
+	case "requests":
+		return float64(sum.Total), strconv.Itoa(sum.Total)
+	case "throughput":
+		elapsed := sum.Last.Sub(sum.First)
+
+		if elapsed <= 0 {
+			return 0, "0 req/s"
+		}
+
+		v := float64(sum.Total) / elapsed.Seconds()
+
+		return v, fmt.Sprintf("%.2f req/s", v)
+	default:
+		return latency(sum.Latency.ValueAtQuantile(failLatencyQuantiles[c.metric]))
+	}
+}
+
+// holds tells whether the run meets the condition and should fail
+func (c *failCondition) holds(v float64) bool {
+	switch c.op {
+	case ">":
+		return v > c.value
+	case "<":
+		return v < c.value
+	case ">=":
+		return v >= c.value
+	default:
+		return v <= c.value
+	}
+}
+
+// failConditions collects repeated -fail-if flags
+type failConditions struct {
+	conditions []*failCondition
+}
+
+func (f *failConditions) String() string {
+	if f == nil {
+		return ""
+	}
+
+	var exprs []string
+
+	for _, cond := range f.conditions {
+		exprs = append(exprs, cond.expr)
+	}
+
+	return strings.Join(exprs, " ")
+}
+
+func (f *failConditions) Set(expr string) error {
+	cond, err := parseFailCondition(expr)
+
+	if err != nil {
+		return err
+	}
+
+	f.conditions = append(f.conditions, cond)
+
+	return nil
+}
+
+// check logs every condition the run meets and returns their count
+func (f *failConditions) check(sum *replay.Summary, verify *replay.VerifySummary) int {
+	var failed int
+
+	for _, cond := range f.conditions {
+		v, text := cond.measure(sum, verify)
+
+		if cond.holds(v) {
+			failed++
+			log.Printf("Failing, %s is %s", cond.expr, text)
+		}
+	}
+
+	return failed
+}
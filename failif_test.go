@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+func TestParseFailCondition(t *testing.T) {
+	tests := []struct {
+		expr   string
+		metric string
+		op     string
+		value  float64
+		err    string
+	}{
+		{expr: "p99>500ms", metric: "p99", op: ">", value: float64(500 * time.Millisecond)},
+		{expr: " p999 >= 1s ", metric: "p999", op: ">=", value: float64(time.Second)},
+		{expr: "avg<=20ms", metric: "avg", op: "<=", value: float64(20 * time.Millisecond)},
+		{expr: "error_rate>1%", metric: "error_rate", op: ">", value: 1},
+		{expr: "5xx_rate>=0.5", metric: "5xx_rate", op: ">=", value: 0.5},
+		{expr: "requests<100", metric: "requests", op: "<", value: 100},
+		{expr: "throughput<50.5", metric: "throughput", op: "<", value: 50.5},
+		{expr: "p99", err: "Invalid condition 'p99'"},
+		{expr: "p99=500ms", err: "Invalid condition"},
+		{expr: "p42>500ms", err: "Unknown metric 'p42'"},
+		{expr: ">500ms", err: "Unknown metric ''"},
+		{expr: "p99>500", err: "Invalid value in 'p99>500'"},
+		{expr: "error_rate>many", err: "Invalid value"},
+		{expr: "requests>1k", err: "Invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cond, err := parseFailCondition(tt.expr)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cond.metric != tt.metric || cond.op != tt.op || cond.value != tt.value {
+				t.Errorf("parsed %s %s %g, expected %s %s %g", cond.metric, cond.op, cond.value, tt.metric, tt.op, tt.value)
+			}
+		})
+	}
+}
+
+func TestFailConditionsCheck(t *testing.T) {
+	sum := replay.NewSummary()
+	start := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	// 10 requests in 2.8 seconds, one transport error, one 503 and two 404s
+	for i := 0; i < 10; i++ {
+		result := &replay.Result{Status: 200, Start: start.Add(time.Duration(i) * 200 * time.Millisecond), Duration: 10 * time.Millisecond}
+
+		switch i {
+		case 0:
+			result.Err = errors.New("connection refused")
+		case 1:
+			result.Status = 503
+		case 2, 3:
+			result.Status = 404
+		case 9:
+			result.Duration = time.Second
+		}
+
+		sum.Write(result)
+	}
+
+	tests := []struct {
+		exprs  []string
+		failed int
+	}{
+		{exprs: nil},
+		{exprs: []string{"error_rate>5%"}, failed: 1},
+		{exprs: []string{"error_rate>10%"}},
+		{exprs: []string{"error_rate>=10%"}, failed: 1},
+		{exprs: []string{"5xx_rate>=10", "4xx_rate>=20"}, failed: 2},
+		{exprs: []string{"4xx_rate>20"}},
+		{exprs: []string{"max>500ms", "p50>500ms"}, failed: 1},
+		{exprs: []string{"requests<10"}},
+		{exprs: []string{"requests<=10", "throughput<10"}, failed: 2},
+		{exprs: []string{"mismatch_rate>0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.exprs, " "), func(t *testing.T) {
+			conditions := &failConditions{}
+
+			for _, expr := range tt.exprs {
+				if err := conditions.Set(expr); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if failed := conditions.check(sum, nil); failed != tt.failed {
+				t.Errorf("%d conditions failed, expected %d", failed, tt.failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// hookTimeout limits how long the replay waits for a trip hook
+const hookTimeout = 10 * time.Second
+
+// tripRecord is the JSON body posted to -window-webhook
+type tripRecord struct {
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason"`
+	ErrorRate float64   `json:"error_rate"`
+	LatencyMs float64   `json:"latency_ms"`
+	Errors    int       `json:"errors"`
+	Status5xx int       `json:"status_5xx"`
+	Status4xx int       `json:"status_4xx"`
+	Cooldown  string    `json:"cooldown,omitempty"`
+	Stopped   bool      `json:"stopped"`
+}
+
+// tripHook posts the trip to the webhook and runs the command with sh -c, failures are only logged
+func tripHook(webhook string, command string) func(replay.Trip) {
+	if webhook == "" && command == "" {
+		return nil
+	}
+
+	return func(trip replay.Trip) {
+		record := tripRecord{
+			Time:      trip.Time,
+			Reason:    trip.Reason,
+			ErrorRate: trip.ErrorRate,
+			LatencyMs: float64(trip.Latency) / float64(time.Millisecond),
+			Errors:    trip.Errors,
+			Status5xx: trip.Status5xx,
+			Status4xx: trip.Status4xx,
+			Stopped:   trip.Cooldown == 0,
+		}
+
+		if trip.Cooldown > 0 {
+			record.Cooldown = trip.Cooldown.String()
+		}
+
+		if webhook != "" {
+			body, _ := json.Marshal(record)
+			client := &http.Client{Timeout: hookTimeout}
+			resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+
+			if err != nil {
+				log.Printf("ERROR %s while calling window webhook", err)
+			} else {
+				resp.Body.Close()
+
+				if resp.StatusCode >= 300 {
+					log.Printf("ERROR window webhook responded with status %d", resp.StatusCode)
+				}
+			}
+		}
+
+		if command != "" {
+			cmd := exec.Command("sh", "-c", command)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			cmd.Env = append(os.Environ(),
+				"LOG_REPLAY_REASON="+record.Reason,
+				fmt.Sprintf("LOG_REPLAY_ERROR_RATE=%.2f", record.ErrorRate),
+				fmt.Sprintf("LOG_REPLAY_LATENCY_MS=%.0f", record.LatencyMs),
+				fmt.Sprintf("LOG_REPLAY_ERRORS=%d", record.Errors),
+				fmt.Sprintf("LOG_REPLAY_STATUS_5XX=%d", record.Status5xx),
+				fmt.Sprintf("LOG_REPLAY_STATUS_4XX=%d", record.Status4xx),
+				"LOG_REPLAY_COOLDOWN="+record.Cooldown,
+				fmt.Sprintf("LOG_REPLAY_STOPPED=%t", record.Stopped),
+			)
+
+			done := make(chan error, 1)
+
+			if err := cmd.Start(); err != nil {
+				log.Printf("ERROR %s while running window command", err)
+				return
+			}
+
+			go func() { done <- cmd.Wait() }()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					log.Printf("ERROR window command failed: %s", err)
+				}
+			case <-time.After(hookTimeout):
+				cmd.Process.Kill()
+				log.Printf("ERROR window command did not finish in %s", hookTimeout)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// hostList is -allow-hosts, *.example.com matches subdomains of example.com
+type hostList []string
+
+func parseHostList(spec string) hostList {
+	var hosts hostList
+
+	for _, host := range strings.Split(spec, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+func (h hostList) Match(host string) bool {
+	host = strings.ToLower(host)
+
+	for _, pattern := range h {
+		if pattern == host || strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowHostsReader skips entries with absolute URLs of hosts not in the list, relative URLs are kept
+func allowHostsReader(rdr reader.LogReader, hosts hostList) reader.LogReader {
+	return reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+		if !strings.Contains(entry.URL, "://") {
+			return true
+		}
+
+		u, err := url.Parse(entry.URL)
+
+		if err != nil || u.Host == "" || !hosts.Match(u.Hostname()) {
+			if debug {
+				log.Printf("Skipping %s, host is not in -allow-hosts", entry.URL)
+			}
+
+			return false
+		}
+
+		return true
+	})
+}
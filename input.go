@@ -0,0 +1,587 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/cloudwatch"
+	"github.com/Gonzih/log-replay/pkg/elastic"
+	"github.com/Gonzih/log-replay/pkg/kafka"
+	"github.com/Gonzih/log-replay/pkg/loki"
+	"github.com/Gonzih/log-replay/pkg/objstore"
+	"github.com/Gonzih/log-replay/pkg/reader"
+	"github.com/Gonzih/log-replay/pkg/reader/alb"
+	"github.com/Gonzih/log-replay/pkg/reader/apache"
+	"github.com/Gonzih/log-replay/pkg/reader/envoy"
+	"github.com/Gonzih/log-replay/pkg/reader/esslowlog"
+	"github.com/Gonzih/log-replay/pkg/reader/grpc"
+	"github.com/Gonzih/log-replay/pkg/reader/haproxy"
+	"github.com/Gonzih/log-replay/pkg/reader/har"
+	"github.com/Gonzih/log-replay/pkg/reader/jsonl"
+	"github.com/Gonzih/log-replay/pkg/reader/mysqlslow"
+	"github.com/Gonzih/log-replay/pkg/reader/nginx"
+	"github.com/Gonzih/log-replay/pkg/reader/nginxjson"
+	"github.com/Gonzih/log-replay/pkg/reader/pcap"
+	"github.com/Gonzih/log-replay/pkg/reader/pgcsvlog"
+	"github.com/Gonzih/log-replay/pkg/reader/regex"
+	"github.com/Gonzih/log-replay/pkg/reader/solr"
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+func parseTimeFlag(name string, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	if timeLayout != "" {
+		if t, err := time.Parse(timeLayout, value); err == nil {
+			return t
+		}
+	}
+
+	t, err := reader.ParseTime("", value)
+
+	if err != nil {
+		log.Fatalf("Unable to parse -%s time '%s'", name, value)
+	}
+
+	return t
+}
+
+// parseShard parses -shard K/N into zero based index and count of the shards
+func parseShard(value string) (int, int, error) {
+	var index, count int
+
+	if n, err := fmt.Sscanf(value, "%d/%d", &index, &count); err != nil || n != 2 || fmt.Sprintf("%d/%d", index, count) != value {
+		return 0, 0, fmt.Errorf("Invalid shard '%s', expected K/N like 2/5", value)
+	}
+
+	if count < 1 || index < 1 || index > count {
+		return 0, 0, fmt.Errorf("Invalid shard '%s', K has to be between 1 and N", value)
+	}
+
+	return index - 1, count, nil
+}
+
+// followInterval is how often -follow checks the file for new data
+const followInterval = 250 * time.Millisecond
+
+// inputFiles expands comma separated -file list, glob matches are sorted by modification time
+// so that rotated logs (access.log.2.gz, access.log.1, access.log) are read oldest first.
+// s3:// and gs:// prefixes and patterns are expanded to the object URLs sorted by key
+func inputFiles(spec string) ([]string, error) {
+	var files []string
+
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.TrimSpace(pattern)
+
+		if pattern == "" {
+			continue
+		}
+
+		if objstore.IsURL(pattern) {
+			urls, err := objstore.List(context.Background(), pattern)
+
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, urls...)
+			continue
+		}
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			// Files are opened when they are reached, missing ones should fail right away
+			if _, err := os.Stat(pattern); err != nil {
+				return nil, err
+			}
+
+			files = append(files, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("No files match '%s'", pattern)
+		}
+
+		modTimes := make(map[string]time.Time)
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+
+			if err != nil {
+				return nil, err
+			}
+
+			modTimes[match] = info.ModTime()
+		}
+
+		sort.SliceStable(matches, func(i, j int) bool {
+			return modTimes[matches[i]].Before(modTimes[matches[j]])
+		})
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+// openFile opens the log file or s3:// and gs:// object, compressed files are decompressed
+func openFile(name string) (io.Reader, func()) {
+	var file io.ReadCloser
+	var err error
+
+	if objstore.IsURL(name) {
+		file, err = objstore.Open(context.Background(), name)
+	} else {
+		file, err = os.Open(name)
+	}
+
+	reader.Must(err)
+
+	var src io.Reader = file
+
+	if inputProgress != nil {
+		src = countingReader{r: file, p: inputProgress}
+	}
+
+	inputReader, closeDecoder, err := decompress(src)
+	reader.Must(err)
+
+	return inputReader, func() {
+		closeDecoder()
+		file.Close()
+	}
+}
+
+// fileReader opens the file when it is read for the first time and closes it at the end,
+// so that only files being read are kept open when many of them are chained
+type fileReader struct {
+	name      string
+	rdr       reader.LogReader
+	closeFile func()
+	done      bool
+}
+
+func (f *fileReader) Read() (*reader.LogEntry, error) {
+	if f.done {
+		return &reader.LogEntry{}, io.EOF
+	}
+
+	if f.rdr == nil {
+		if debug {
+			log.Printf("Reading %s", f.name)
+		}
+
+		var inputReader io.Reader
+
+		inputReader, f.closeFile = openFile(f.name)
+		f.rdr = parallelLogReader(inputReader)
+	}
+
+	entry, err := f.rdr.Read()
+
+	if err == io.EOF {
+		f.done = true
+		f.Close()
+	}
+
+	return entry, err
+}
+
+func (f *fileReader) Close() {
+	if c, ok := f.rdr.(io.Closer); ok {
+		c.Close()
+	}
+
+	if f.closeFile != nil {
+		f.closeFile()
+		f.closeFile = nil
+	}
+}
+
+// openInput creates reader of -file (STDIN for "-", built in sample line for "dummy"), several files
+// are read one after another or merged by time with -merge. Close function has to be called when done
+func openInput() (reader.LogReader, func()) {
+	var inputReader io.Reader
+	closeInput := func() {}
+
+	loadReaderPlugins()
+
+	if debug {
+		log.Printf("Parsing %s log file\n", inputLogFile)
+		log.Printf("Using log type %s", inputFileType)
+	}
+
+	if inputLogFile == "dummy" {
+		switch inputFileType {
+		case "nginx":
+			inputReader = strings.NewReader(`89.234.89.123 [08/Nov/2013:13:39:18 +0000] "GET /t/100x100/foo/bar.jpeg HTTP/1.1" 200 1027 2430 0.014 "100x100" 10 1`)
+		case "nginx-json":
+			inputReader = strings.NewReader(`{"time_local":"08/Nov/2013:13:39:18 +0000","request":"GET /t/100x100/foo/bar.jpeg HTTP/1.1","status":"200","http_user_agent":"curl/7.29.0"}`)
+		case "apache":
+			inputReader = strings.NewReader(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`)
+		case "alb":
+			inputReader = strings.NewReader(`http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`)
+		case "envoy":
+			inputReader = strings.NewReader(`[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`)
+		case "envoy-json":
+			inputReader = strings.NewReader(`{"start_time":"2016-04-15T20:17:00.310Z","method":"GET","path":"/api/v1/locations","protocol":"HTTP/1.1","response_code":200,"user_agent":"nsq2http","authority":"locations"}`)
+		case "jsonl":
+			inputReader = strings.NewReader(`{"time":"2013-11-08T13:39:18Z","method":"GET","url":"/t/100x100/foo/bar.jpeg","ua":"curl/7.29.0","status":200}`)
+		case "regex":
+			inputReader = strings.NewReader(`2013-11-08T13:39:18Z GET /t/100x100/foo/bar.jpeg 200`)
+			regexFormat = `^(?P<time>\S+) (?P<method>\S+) (?P<url>\S+) (?P<status>\d+)`
+		case "haproxy":
+			inputReader = strings.NewReader(`<142>Sep 27 00:15:57 haproxy[28513]: 67.188.214.167:64531 [27/Sep/2013:00:15:43.494] frontend~ test/10.127.57.177-10000 449/0/0/13531/13980 200 13824 - - ---- 6/6/0/1/0 0/0 "GET / HTTP/1.1"`)
+		case "solr":
+			inputReader = strings.NewReader(`2013-11-08 13:39:18,014 INFO  (qtp1-14) [c:products s:shard1 r:core_node2 x:products_shard1_replica_n1] o.a.s.c.S.Request [products_shard1_replica_n1]  webapp=/solr path=/select params={q=name:foo&wt=json} hits=10 status=0 QTime=14`)
+		case "grpc":
+			inputReader = strings.NewReader(`{"time":"2013-11-08T13:39:18Z","method":"/helloworld.Greeter/SayHello","request":{"name":"world"},"authority":"greeter:50051","code":"OK","duration":0.014}`)
+		case "har":
+			inputReader = strings.NewReader(`{"log":{"entries":[{"startedDateTime":"2013-11-08T13:39:18Z","time":14,"request":{"method":"GET","url":"http://example.com/t/100x100/foo/bar.jpeg","headers":[{"name":"User-Agent","value":"curl/7.29.0"}]},"response":{"status":200}}]}}`)
+		case "es-slowlog":
+			inputReader = strings.NewReader(`[2013-11-08T13:39:18,014][WARN ][index.search.slowlog.query] [node-1] [products][0] took[14ms], took_millis[14], total_hits[10], types[], stats[], search_type[QUERY_THEN_FETCH], total_shards[5], source[{"query":{"match":{"name":"foo"}}}], id[],`)
+		case "mysql-slowlog":
+			inputReader = strings.NewReader("# Time: 2013-11-08T13:39:18.014000Z\n# User@Host: app[app] @  [10.0.0.5]  Id:    12\n# Query_time: 0.014000  Lock_time: 0.000010 Rows_sent: 1  Rows_examined: 1\nuse shop;\nSET timestamp=1383917958;\nSELECT * FROM users WHERE id = 1;\n")
+		case "postgres-csvlog":
+			inputReader = strings.NewReader(`2013-11-08 13:39:18.014 UTC,"app","shop",1234,"10.0.0.5:51234",527ce9a6.4d2,1,"SELECT",2013-11-08 13:39:00 UTC,3/12,0,LOG,00000,"duration: 14.000 ms  statement: SELECT * FROM users WHERE id = 1",,,,,,,,,"psql","client backend",,0`)
+		default:
+			// pcap captures are binary and readers of plugins have no sample
+			log.Fatalf("There is no dummy input of file-type '%s', give a log with -file", inputFileType)
+		}
+	} else if follow {
+		if inputLogFile == "-" || strings.ContainsAny(inputLogFile, ",*?[") || compressedFile(inputLogFile) || objstore.IsURL(inputLogFile) {
+			log.Fatalf("follow needs a single uncompressed log file, not '%s'", inputLogFile)
+		}
+
+		file, err := reader.NewFollowReader(inputLogFile, followInterval)
+
+		reader.Must(err)
+		closeInput = func() { file.Close() }
+		inputReader = file
+	} else if elastic.IsURL(inputLogFile) {
+		search, err := elastic.NewReader(context.Background(), inputLogFile)
+
+		reader.Must(err)
+		closeInput = func() { search.Close() }
+		inputReader = search
+	} else if loki.IsURL(inputLogFile) {
+		query, err := loki.NewReader(context.Background(), inputLogFile)
+
+		reader.Must(err)
+		inputReader = query
+	} else if cloudwatch.IsURL(inputLogFile) {
+		events, err := cloudwatch.NewReader(context.Background(), inputLogFile)
+
+		reader.Must(err)
+		inputReader = events
+	} else if kafka.IsURL(inputLogFile) {
+		topic, err := kafka.NewReader(context.Background(), inputLogFile)
+
+		reader.Must(err)
+		closeInput = func() { topic.Close() }
+		inputReader = topic
+	} else if inputLogFile == "-" {
+		var closeDecoder func()
+		var err error
+
+		inputReader, closeDecoder, err = decompress(os.Stdin)
+		reader.Must(err)
+
+		rdr := parallelLogReader(inputReader)
+
+		return rdr, func() {
+			if c, ok := rdr.(io.Closer); ok {
+				c.Close()
+			}
+
+			closeDecoder()
+		}
+	} else {
+		files, err := inputFiles(inputLogFile)
+		reader.Must(err)
+
+		if progressInterval > 0 {
+			if inputProgress = newProgress(files); inputProgress == nil {
+				log.Println("progress is reported for local files only")
+			}
+		}
+
+		var readers []reader.LogReader
+		var fileReaders []*fileReader
+
+		for _, name := range files {
+			f := &fileReader{name: name}
+			readers = append(readers, f)
+			fileReaders = append(fileReaders, f)
+		}
+
+		closeInput = func() {
+			for _, f := range fileReaders {
+				f.Close()
+			}
+		}
+
+		if len(readers) == 1 {
+			return readers[0], closeInput
+		}
+
+		if merge {
+			return reader.NewMergeReader(readers...), closeInput
+		}
+
+		return reader.NewChainReader(readers...), closeInput
+	}
+
+	return newLogReader(inputReader), closeInput
+}
+
+// newLogReader creates reader of -file-type for the input
+func newLogReader(inputReader io.Reader) reader.LogReader {
+	var rdr reader.LogReader
+
+	switch inputFileType {
+	case "nginx":
+		rdr = nginx.NewReader(inputReader, format)
+	case "nginx-json":
+		fields, err := nginxjson.ParseFields(nginxjson.DefaultFields, jsonFields)
+		reader.Must(err)
+		rdr = nginxjson.NewReader(inputReader, fields, timeLayout)
+	case "apache":
+		rdr = apache.NewReader(inputReader)
+	case "alb":
+		rdr = alb.NewReader(inputReader)
+	case "envoy":
+		rdr = envoy.NewReader(inputReader)
+	case "envoy-json":
+		fields, err := nginxjson.ParseFields(envoy.DefaultJSONFields, jsonFields)
+		reader.Must(err)
+		rdr = envoy.NewJSONReader(inputReader, fields, timeLayout)
+	case "haproxy":
+		if haproxyFormat != "" {
+			rdr = haproxy.NewFormatReader(inputReader, haproxyFormat)
+		} else {
+			rdr = haproxy.NewReader(inputReader)
+		}
+	case "solr":
+		rdr = solr.NewOptionsReader(inputReader, solr.Options{Collections: solrCollections, ShardRequests: solrShardRequests})
+	case "jsonl":
+		rdr = jsonl.NewReader(inputReader)
+	case "regex":
+		rdr = regex.NewReader(inputReader, regexFormat, timeLayout)
+	case "pcap":
+		rdr = pcap.NewReader(inputReader)
+	case "grpc":
+		rdr = grpc.NewReader(inputReader)
+	case "har":
+		rdr = har.NewReader(inputReader)
+	case "es-slowlog":
+		rdr = esslowlog.NewReader(inputReader)
+	case "mysql-slowlog":
+		rdr = mysqlslow.NewReader(inputReader)
+	case "postgres-csvlog":
+		rdr = pgcsvlog.NewReader(inputReader)
+	default:
+		factory, ok := reader.Lookup(inputFileType)
+
+		if !ok {
+			log.Fatalf("file-type can be one of %s, not '%s'", fileTypes(), inputFileType)
+		}
+
+		var err error
+
+		rdr, err = factory(inputReader)
+		reader.Must(err)
+	}
+
+	return rdr
+}
+
+// parallelLogReader creates reader of -file-type for the input parsing lines in -parse-workers goroutines
+func parallelLogReader(inputReader io.Reader) reader.LogReader {
+	if parseWorkers < 1 {
+		log.Fatalf("parse-workers has to be positive, not '%d'", parseWorkers)
+	}
+
+	rdr := newLogReader(inputReader)
+
+	if parseWorkers == 1 {
+		return rdr
+	}
+
+	parser, ok := rdr.(reader.LineParser)
+
+	if !ok {
+		log.Printf("%s input is not line based, parse-workers is ignored", inputFileType)
+		return rdr
+	}
+
+	return reader.NewParallelReader(inputReader, parser, parseWorkers)
+}
+
+// filterReader wraps the reader with filters and modifications given by the flags
+func filterReader(rdr reader.LogReader) reader.LogReader {
+	if fromTime != "" || toTime != "" {
+		from := parseTimeFlag("from", fromTime)
+		to := parseTimeFlag("to", toTime)
+
+		rdr = reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+			return (from.IsZero() || !entry.Time.Before(from)) && (to.IsZero() || entry.Time.Before(to))
+		})
+	}
+
+	if skipStatus != "" {
+		statuses, err := parseStatusSet(skipStatus)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rdr = reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+			return !statuses.Match(entry.Status)
+		})
+	}
+
+	if onlyMethods != "" {
+		methods := make(map[string]bool)
+
+		for _, method := range strings.Split(onlyMethods, ",") {
+			methods[strings.ToUpper(strings.TrimSpace(method))] = true
+		}
+
+		rdr = reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+			return methods[strings.ToUpper(entry.Method)]
+		})
+	}
+
+	if onlyOperations != "" || skipOperations != "" {
+		only := make(map[string]bool)
+		skip := make(map[string]bool)
+
+		for _, name := range strings.Split(onlyOperations, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				only[name] = true
+			}
+		}
+
+		for _, name := range strings.Split(skipOperations, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				skip[name] = true
+			}
+		}
+
+		// Names of batched operations are all checked
+		rdr = reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+			operation, ok := replay.GraphQLOperation(entry.Method, entry.URL, entry.Payload)
+
+			if !ok {
+				return len(only) == 0
+			}
+
+			for _, name := range strings.Split(operation, ",") {
+				if skip[name] || (len(only) > 0 && !only[name]) {
+					return false
+				}
+			}
+
+			return true
+		})
+	}
+
+	if sample <= 0 || sample > 1 {
+		log.Fatalf("sample has to be in (0..1] range, not '%g'", sample)
+	}
+
+	if sample < 1 {
+		random := rand.New(rand.NewSource(seed))
+
+		rdr = reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+			return random.Float64() < sample
+		})
+	}
+
+	if shard != "" {
+		index, count, err := parseShard(shard)
+		reader.Must(err)
+
+		// Every Nth entry of those passing the filters above, the same in every run
+		var seen int
+
+		rdr = reader.NewFilterReader(rdr, func(entry *reader.LogEntry) bool {
+			seen++
+
+			return (seen-1)%count == index
+		})
+	}
+
+	var bodies bodyStore
+
+	if bodyDir != "" && bodyFile != "" {
+		log.Fatal("body-dir and body-file can not be used together")
+	} else if bodyDir != "" {
+		bodies = &dirBodyStore{dir: bodyDir}
+	} else if bodyFile != "" {
+		store, err := newJSONLBodyStore(bodyFile)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		bodies = store
+	}
+
+	if bodies != nil {
+		rdr = reader.NewMapReader(rdr, func(entry *reader.LogEntry) {
+			if entry.Payload != "" {
+				return
+			}
+
+			if body, ok := bodies.Lookup(entry.Method, entry.URL); ok {
+				entry.Payload = body
+			}
+		})
+	}
+
+	if len(rewrites) > 0 {
+		rdr = reader.NewMapReader(rdr, func(entry *reader.LogEntry) {
+			entry.URL = rewrites.Apply(entry.URL)
+		})
+	}
+
+	if len(dropQuery) > 0 || len(setQuery) > 0 || len(addQuery) > 0 {
+		editor, err := newQueryEditor(dropQuery, setQuery, addQuery)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rdr = reader.NewMapReader(rdr, func(entry *reader.LogEntry) {
+			entry.URL = editor.Apply(entry.URL)
+		})
+	}
+
+	if forceMethod != "" {
+		method := strings.ToUpper(forceMethod)
+
+		rdr = reader.NewMapReader(rdr, func(entry *reader.LogEntry) {
+			entry.Method = method
+		})
+	}
+
+	return rdr
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Gonzih/log-replay/pkg/reader/apache"
+)
+
+func TestOpenInputDummy(t *testing.T) {
+	inputFlags(flag.NewFlagSet("test", flag.ContinueOnError))
+
+	defer func() {
+		inputLogFile, inputFileType, regexFormat = "", "", ""
+	}()
+
+	// pcap has no sample, -file dummy is rejected for it
+	types := []string{"nginx", "nginx-json", "apache", "alb", "envoy", "envoy-json", "haproxy", "solr", "regex",
+		"grpc", "har", "es-slowlog", "mysql-slowlog", "postgres-csvlog", "jsonl"}
+
+	for _, fileType := range types {
+		inputLogFile, inputFileType = "dummy", fileType
+
+		rdr, closeInput := openInput()
+		entry, err := rdr.Read()
+		closeInput()
+
+		if err != nil {
+			t.Errorf("%s: %v", fileType, err)
+			continue
+		}
+
+		if entry.Method == "" || entry.URL == "" || entry.Time.IsZero() {
+			t.Errorf("%s: incomplete entry %+v", fileType, entry)
+		}
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		value string
+		index int
+		count int
+		err   string
+	}{
+		{value: "1/1", index: 0, count: 1},
+		{value: "2/5", index: 1, count: 5},
+		{value: "5/5", index: 4, count: 5},
+		{value: "0/5", err: "K has to be between 1 and N"},
+		{value: "6/5", err: "K has to be between 1 and N"},
+		{value: "1/0", err: "K has to be between 1 and N"},
+		{value: "0/0", err: "K has to be between 1 and N"},
+		{value: "-1/5", err: "K has to be between 1 and N"},
+		{value: "2", err: "expected K/N like 2/5"},
+		{value: "a/b", err: "expected K/N"},
+		{value: "2/5/7", err: "expected K/N"},
+		{value: " 2/5", err: "expected K/N"},
+		{value: "02/5", err: "expected K/N"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			index, count, err := parseShard(tt.value)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if index != tt.index || count != tt.count {
+				t.Errorf("parsed %d/%d, expected %d/%d", index, count, tt.index, tt.count)
+			}
+		})
+	}
+}
+
+func TestShardFilter(t *testing.T) {
+	filterFlags(flag.NewFlagSet("test", flag.ContinueOnError))
+
+	defer func() {
+		shard = ""
+	}()
+
+	var lines []string
+
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf(`10.0.0.1 - - [01/Jan/2024:10:00:%02d +0000] "GET /%d HTTP/1.1" 200 12`, i, i))
+	}
+
+	input := strings.Join(lines, "\n")
+	seen := make(map[string]int)
+
+	for k := 1; k <= 3; k++ {
+		shard = fmt.Sprintf("%d/3", k)
+		rdr := filterReader(apache.NewReader(strings.NewReader(input)))
+
+		var urls []string
+
+		for {
+			entry, err := rdr.Read()
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			urls = append(urls, entry.URL)
+			seen[entry.URL]++
+		}
+
+		// Every 3rd entry starting with the Kth
+		var expected []string
+
+		for i := k - 1; i < 10; i += 3 {
+			expected = append(expected, fmt.Sprintf("/%d", i))
+		}
+
+		if !reflect.DeepEqual(urls, expected) {
+			t.Errorf("shard %s replays %q, expected %q", shard, urls, expected)
+		}
+	}
+
+	// The shards split the input without overlap
+	if len(seen) != 10 {
+		t.Errorf("%d entries replayed, expected all 10", len(seen))
+	}
+
+	for url, n := range seen {
+		if n != 1 {
+			t.Errorf("%s replayed by %d shards", url, n)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (s *junitTestSuite) add(c junitTestCase) {
+	s.Tests++
+	s.Time += c.Time
+
+	if c.Failure != nil {
+		s.Failures++
+	}
+
+	s.Cases = append(s.Cases, c)
+}
+
+// writeJUnit writes a test per assertion, per -fail-if condition and per endpoint,
+// endpoints fail when some of their requests got no response or 5xx status
+func writeJUnit(out io.Writer, sum *replay.Summary, verify *replay.VerifySummary, endpoints *replay.EndpointSummary) error {
+	var suites junitTestSuites
+
+	if len(asserts.rules) > 0 {
+		suite := junitTestSuite{Name: "assertions"}
+
+		for _, rule := range asserts.rules {
+			c := junitTestCase{Name: rule.Name, Classname: "log-replay.assertions"}
+
+			if rule.Failed > 0 {
+				c.Failure = &junitFailure{
+					Message: fmt.Sprintf("%d of %d checks failed", rule.Failed, rule.Passed+rule.Failed),
+				}
+			}
+
+			suite.add(c)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if len(failIf.conditions) > 0 {
+		suite := junitTestSuite{Name: "thresholds"}
+
+		for _, cond := range failIf.conditions {
+			c := junitTestCase{Name: cond.expr, Classname: "log-replay.thresholds"}
+
+			if v, text := cond.measure(sum, verify); cond.holds(v) {
+				c.Failure = &junitFailure{Message: fmt.Sprintf("%s is %s", cond.expr, text)}
+			}
+
+			suite.add(c)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	var keys []string
+
+	for key := range endpoints.Endpoints {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	suite := junitTestSuite{Name: "endpoints"}
+
+	for _, key := range keys {
+		endpoint := endpoints.Endpoints[key]
+		c := junitTestCase{
+			Name:      key,
+			Classname: "log-replay.endpoints",
+			Time:      (time.Duration(endpoint.Latency.Mean()) * time.Duration(endpoint.Total)).Seconds(),
+		}
+
+		if failed := endpoint.Errors + endpoint.Status5xx; failed > 0 {
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d of %d requests failed", failed, endpoint.Total),
+				Text:    fmt.Sprintf("%d requests without response, %d 5xx responses", endpoint.Errors, endpoint.Status5xx),
+			}
+		}
+
+		suite.add(c)
+	}
+
+	suites.Suites = append(suites.Suites, suite)
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(&suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(out, "\n")
+
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// runParse is the parse command printing entries as tab separated time, method, url and payload
+func runParse(fs *flag.FlagSet) {
+	rdr, closeInput := openInput()
+	defer closeInput()
+
+	if printEntries(filterReader(rdr)) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printEntries writes entries which would be replayed to STDOUT and returns the number of parse errors,
+// lines which can not be parsed are reported to STDERR and skipped, so that all problems show up in one go
+func printEntries(rdr reader.LogReader) int {
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	count := 0
+	errors := 0
+
+	for {
+		entry, err := rdr.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			errors++
+			out.Flush()
+			log.Printf("ERROR after %d entries: %s", count, err)
+
+			// The rest of the input can not be read
+			if reader.IsInputError(err) {
+				break
+			}
+
+			continue
+		}
+
+		count++
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339Nano), entry.Method, entry.URL, entry.Payload)
+	}
+
+	out.Flush()
+	log.Printf("Parsed %d entries, %d errors", count, errors)
+
+	return errors
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader/apache"
+)
+
+func TestPrintEntriesStopsOnInputError(t *testing.T) {
+	input := strings.Join([]string{
+		`10.0.0.1 - - [01/Jan/2024:10:00:00 +0000] "GET /a HTTP/1.1" 200 12`,
+		`not an apache line`,
+		`10.0.0.1 - - [01/Jan/2024:10:00:01 +0000] "GET /` + strings.Repeat("x", 70*1024) + ` HTTP/1.1" 200 12`,
+		`10.0.0.1 - - [01/Jan/2024:10:00:02 +0000] "GET /b HTTP/1.1" 200 12`,
+	}, "\n")
+
+	done := make(chan int, 1)
+
+	go func() {
+		done <- printEntries(apache.NewReader(strings.NewReader(input)))
+	}()
+
+	select {
+	case errors := <-done:
+		// The line which does not match is skipped, the too long one ends the input
+		if errors != 2 {
+			t.Errorf("expected 2 errors, got %d", errors)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("printEntries did not stop after the scanner error")
+	}
+}
@@ -0,0 +1,262 @@
+// Package cloudwatch streams log events of a CloudWatch Logs group
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/awsv4"
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	defaultRange    = time.Hour
+	maxRetries      = 8
+	retryBackoff    = 200 * time.Millisecond
+	retryMaxBackoff = 10 * time.Second
+)
+
+// IsURL tells if the name is cloudwatch:// log group URL
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "cloudwatch://")
+}
+
+// EventsReader is io.Reader of messages of the log group events returned by FilterLogEvents
+// page by page, throttled and failed calls are retried with backoff
+type EventsReader struct {
+	ctx      context.Context
+	client   *http.Client
+	endpoint string
+	region   string
+	creds    awsv4.Credentials
+	request  map[string]interface{}
+	done     bool
+	buf      bytes.Buffer
+}
+
+// parseRangeTime parses start and end parameters, which are either times supported
+// by reader.ParseTime or durations (e.g. 2h) meaning time ago
+func parseRangeTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return reader.ParseTime("", value)
+}
+
+// NewReader creates reader of cloudwatch://log-group-name?start=2h URL, query parameters are start and end
+// (time or duration ago, the last hour by default), stream (log stream name prefix), filter (filter pattern)
+// and region. Credentials, region and endpoint are taken from the standard AWS environment variables
+func NewReader(ctx context.Context, rawURL string) (*EventsReader, error) {
+	rest := strings.TrimPrefix(rawURL, "cloudwatch://")
+	group, rawQuery := rest, ""
+
+	if i := strings.Index(rest, "?"); i >= 0 {
+		group, rawQuery = rest[:i], rest[i+1:]
+	}
+
+	group, err := url.PathUnescape(group)
+
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := url.ParseQuery(rawQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if group == "" {
+		return nil, fmt.Errorf("CloudWatch URL has to be cloudwatch://log-group-name, not '%s'", rawURL)
+	}
+
+	now := time.Now()
+	end := now
+
+	if value := params.Get("end"); value != "" {
+		if end, err = parseRangeTime(value, now); err != nil {
+			return nil, err
+		}
+	}
+
+	start := end.Add(-defaultRange)
+
+	if value := params.Get("start"); value != "" {
+		if start, err = parseRangeTime(value, now); err != nil {
+			return nil, err
+		}
+	}
+
+	request := map[string]interface{}{
+		"logGroupName": group,
+		"startTime":    start.UnixNano() / int64(time.Millisecond),
+		"endTime":      end.UnixNano() / int64(time.Millisecond),
+	}
+
+	if stream := params.Get("stream"); stream != "" {
+		request["logStreamNamePrefix"] = stream
+	}
+
+	if filter := params.Get("filter"); filter != "" {
+		request["filterPattern"] = filter
+	}
+
+	region := params.Get("region")
+
+	if region == "" {
+		region = awsv4.EnvRegion()
+	}
+
+	endpoint := awsv4.EnvEndpoint("CLOUDWATCH_LOGS")
+
+	if endpoint == "" {
+		endpoint = "https://logs." + region + ".amazonaws.com"
+	}
+
+	return &EventsReader{
+		ctx:      ctx,
+		client:   http.DefaultClient,
+		endpoint: endpoint,
+		region:   region,
+		creds:    awsv4.EnvCredentials(),
+		request:  request,
+	}, nil
+}
+
+// filterLogEventsResponse is the part of FilterLogEvents response used by the reader
+type filterLogEventsResponse struct {
+	Events []struct {
+		Message string `json:"message"`
+	} `json:"events"`
+	NextToken string `json:"nextToken"`
+}
+
+// apiError is the error response of CloudWatch Logs API
+type apiError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// retryable tells if the failed call can succeed later: throttling and server errors
+func retryable(status int, errType string) bool {
+	return status >= 500 || strings.HasSuffix(errType, "ThrottlingException") || strings.HasSuffix(errType, "LimitExceededException")
+}
+
+// call sends FilterLogEvents request, retrying throttled and failed ones
+func (r *EventsReader) call() (*filterLogEventsResponse, error) {
+	payload, err := json.Marshal(r.request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(r.ctx, "POST", r.endpoint+"/", bytes.NewReader(payload))
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		req.Header.Set("X-Amz-Target", "Logs_20140328.FilterLogEvents")
+		awsv4.Sign(req, awsv4.PayloadHash(payload), r.creds, "logs", r.region, time.Now())
+
+		resp, err := r.client.Do(req)
+
+		var status int
+		var body []byte
+
+		if err == nil {
+			status = resp.StatusCode
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		if err == nil && status == http.StatusOK {
+			var result filterLogEventsResponse
+
+			if err := json.Unmarshal(body, &result); err != nil {
+				return nil, err
+			}
+
+			return &result, nil
+		}
+
+		if err == nil {
+			var apiErr apiError
+			json.Unmarshal(body, &apiErr)
+
+			err = fmt.Errorf("ERROR %d %s while reading CloudWatch Logs: %s", status, apiErr.Type, apiErr.Message)
+
+			if !retryable(status, apiErr.Type) {
+				return nil, err
+			}
+		}
+
+		if attempt >= maxRetries {
+			return nil, err
+		}
+
+		// Exponential backoff with full jitter, like retries of the replayed requests
+		delay := retryBackoff << uint(attempt)
+
+		if delay > retryMaxBackoff {
+			delay = retryMaxBackoff
+		}
+
+		delay = time.Duration(rand.Int63n(int64(delay)) + 1)
+		log.Printf("%s, retrying in %s", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-r.ctx.Done():
+			return nil, r.ctx.Err()
+		}
+	}
+}
+
+// fetch reads the next page of events into the buffer, pages can be empty while nextToken is still returned
+func (r *EventsReader) fetch() error {
+	result, err := r.call()
+
+	if err != nil {
+		return err
+	}
+
+	for _, event := range result.Events {
+		r.buf.WriteString(strings.TrimSuffix(event.Message, "\n"))
+		r.buf.WriteByte('\n')
+	}
+
+	if result.NextToken == "" {
+		r.done = true
+	} else {
+		r.request["nextToken"] = result.NextToken
+	}
+
+	return nil
+}
+
+func (r *EventsReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+	}
+
+	return r.buf.Read(p)
+}
@@ -0,0 +1,154 @@
+// Package kafka streams access log lines consumed from a Kafka topic and publishes replay results to one
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const (
+	defaultGroup   = "log-replay"
+	commitInterval = time.Second
+	dialTimeout    = 10 * time.Second
+)
+
+// IsURL tells if the name is kafka:// topic URL
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "kafka://")
+}
+
+// TopicReader is io.Reader of log lines, every message of the topic is one or more lines.
+// Read blocks until the next message arrives, offsets are committed to the consumer group periodically
+type TopicReader struct {
+	ctx      context.Context
+	consumer *kafkago.Reader
+	buf      []byte
+}
+
+// NewReader creates reader of kafka://broker1:9092,broker2:9092/topic URL, query parameters are
+// group (consumer group, log-replay by default), offset (first or last, where new groups start),
+// tls=true and sasl (plain, scram-sha-256 or scram-sha-512) with credentials given as user:password@
+func NewReader(ctx context.Context, rawURL string) (*TopicReader, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	topic := strings.Trim(u.Path, "/")
+
+	if u.Host == "" || topic == "" {
+		return nil, fmt.Errorf("Kafka URL has to be kafka://brokers/topic, not '%s'", rawURL)
+	}
+
+	query := u.Query()
+
+	config := kafkago.ReaderConfig{
+		Brokers:        strings.Split(u.Host, ","),
+		Topic:          topic,
+		GroupID:        query.Get("group"),
+		StartOffset:    kafkago.LastOffset,
+		CommitInterval: commitInterval,
+		ErrorLogger:    kafkago.LoggerFunc(log.Printf),
+	}
+
+	if config.GroupID == "" {
+		config.GroupID = defaultGroup
+	}
+
+	switch query.Get("offset") {
+	case "", "last":
+	case "first":
+		config.StartOffset = kafkago.FirstOffset
+	default:
+		return nil, fmt.Errorf("Kafka offset can be first or last, not '%s'", query.Get("offset"))
+	}
+
+	dialer := &kafkago.Dialer{Timeout: dialTimeout, DualStack: true}
+
+	dialer.TLS, dialer.SASLMechanism, err = security(u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config.Dialer = dialer
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &TopicReader{ctx: ctx, consumer: kafkago.NewReader(config)}, nil
+}
+
+// security returns TLS config and SASL mechanism of tls=true and sasl= query parameters, nil when not enabled
+func security(u *url.URL) (*tls.Config, sasl.Mechanism, error) {
+	query := u.Query()
+
+	var config *tls.Config
+
+	if query.Get("tls") == "true" {
+		config = &tls.Config{}
+	}
+
+	mechanism := query.Get("sasl")
+
+	if mechanism == "" {
+		return config, nil, nil
+	}
+
+	password, _ := u.User.Password()
+
+	auth, err := saslMechanism(mechanism, u.User.Username(), password)
+
+	return config, auth, err
+}
+
+func saslMechanism(name, user, password string) (sasl.Mechanism, error) {
+	switch name {
+	case "plain":
+		return plain.Mechanism{Username: user, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, user, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, user, password)
+	default:
+		return nil, fmt.Errorf("Kafka sasl can be one of plain, scram-sha-256 or scram-sha-512, not '%s'", name)
+	}
+}
+
+func (r *TopicReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.consumer.ReadMessage(r.ctx)
+
+		if err != nil {
+			return 0, err
+		}
+
+		r.buf = msg.Value
+
+		if len(r.buf) > 0 && r.buf[len(r.buf)-1] != '\n' {
+			r.buf = append(r.buf, '\n')
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+// Close commits consumed offsets and leaves the consumer group
+func (r *TopicReader) Close() error {
+	return r.consumer.Close()
+}
@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const defaultBatchTimeout = time.Second
+
+// TopicWriter publishes messages to a Kafka topic in the background, delivery errors are
+// returned by the next Publish or Close
+type TopicWriter struct {
+	ctx      context.Context
+	producer *kafkago.Writer
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewWriter creates writer of kafka://broker1:9092,broker2:9092/topic URL, query parameters are
+// batch (interval to send incomplete batches in, 1s by default), tls=true and sasl as for NewReader
+func NewWriter(ctx context.Context, rawURL string) (*TopicWriter, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	topic := strings.Trim(u.Path, "/")
+
+	if u.Host == "" || topic == "" {
+		return nil, fmt.Errorf("Kafka URL has to be kafka://brokers/topic, not '%s'", rawURL)
+	}
+
+	batchTimeout := defaultBatchTimeout
+
+	if value := u.Query().Get("batch"); value != "" {
+		if batchTimeout, err = time.ParseDuration(value); err != nil || batchTimeout <= 0 {
+			return nil, fmt.Errorf("Kafka batch interval has to be a positive duration, not '%s'", value)
+		}
+	}
+
+	transport := &kafkago.Transport{DialTimeout: dialTimeout}
+
+	transport.TLS, transport.SASL, err = security(u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w := &TopicWriter{ctx: ctx}
+
+	w.producer = &kafkago.Writer{
+		Addr:         kafkago.TCP(strings.Split(u.Host, ",")...),
+		Topic:        topic,
+		Balancer:     &kafkago.LeastBytes{},
+		BatchTimeout: batchTimeout,
+		Async:        true,
+		Transport:    transport,
+		Completion:   w.completed,
+	}
+
+	return w, nil
+}
+
+func (w *TopicWriter) completed(messages []kafkago.Message, err error) {
+	if err == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		w.err = fmt.Errorf("%d messages were not published to %s: %s", len(messages), w.producer.Topic, err)
+	}
+}
+
+// failure returns and clears delivery error
+func (w *TopicWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	w.err = nil
+
+	return err
+}
+
+// Publish queues the message, it is sent with the next batch
+func (w *TopicWriter) Publish(value []byte) error {
+	if err := w.failure(); err != nil {
+		return err
+	}
+
+	return w.producer.WriteMessages(w.ctx, kafkago.Message{Value: value})
+}
+
+// Close sends queued messages
+func (w *TopicWriter) Close() error {
+	if err := w.producer.Close(); err != nil {
+		return err
+	}
+
+	return w.failure()
+}
@@ -0,0 +1,243 @@
+// Package loki streams log lines matching a LogQL query from Grafana Loki
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	defaultLimit = 5000
+	defaultRange = time.Hour
+)
+
+// IsURL tells if the name is loki://, loki+http:// or loki+https:// URL
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "loki://") || strings.HasPrefix(name, "loki+http://") || strings.HasPrefix(name, "loki+https://")
+}
+
+// QueryReader is io.Reader of log lines returned by query_range API in time order,
+// the time range is walked forward in batches of limit lines
+type QueryReader struct {
+	ctx    context.Context
+	client *http.Client
+	base   string
+	user   *url.Userinfo
+	org    string
+	query  string
+	limit  int
+	start  time.Time
+	end    time.Time
+	// lines returned for the last timestamp, the next batch starts at that timestamp
+	// again as the limit could have cut entries with the same time
+	lastTime  int64
+	lastLines map[string]bool
+	done      bool
+	buf       bytes.Buffer
+}
+
+// parseRangeTime parses start and end parameters, which are either times supported
+// by reader.ParseTime or durations (e.g. 2h) meaning time ago
+func parseRangeTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return reader.ParseTime("", value)
+}
+
+// NewReader creates reader of loki://host:3100?query={job="nginx"} URL (loki+https:// for TLS), other query
+// parameters are start and end (time or duration ago, the last hour by default), limit (batch size)
+// and org (X-Scope-OrgID tenant), basic auth credentials can be given as user:password@
+func NewReader(ctx context.Context, rawURL string) (*QueryReader, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	params := u.Query()
+
+	if u.Host == "" || params.Get("query") == "" {
+		return nil, fmt.Errorf("Loki URL has to be loki://host:port?query=..., not '%s'", rawURL)
+	}
+
+	scheme := "http"
+
+	if u.Scheme == "loki+https" {
+		scheme = "https"
+	}
+
+	r := &QueryReader{
+		ctx:    ctx,
+		client: http.DefaultClient,
+		base:   scheme + "://" + u.Host + strings.TrimSuffix(u.Path, "/"),
+		user:   u.User,
+		org:    params.Get("org"),
+		query:  params.Get("query"),
+		limit:  defaultLimit,
+	}
+
+	now := time.Now()
+	r.end = now
+
+	if value := params.Get("end"); value != "" {
+		if r.end, err = parseRangeTime(value, now); err != nil {
+			return nil, err
+		}
+	}
+
+	r.start = r.end.Add(-defaultRange)
+
+	if value := params.Get("start"); value != "" {
+		if r.start, err = parseRangeTime(value, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if value := params.Get("limit"); value != "" {
+		if r.limit, err = strconv.Atoi(value); err != nil || r.limit <= 0 {
+			return nil, fmt.Errorf("Loki limit has to be a positive number, not '%s'", value)
+		}
+	}
+
+	r.lastTime = r.start.UnixNano()
+	r.lastLines = make(map[string]bool)
+
+	return r, nil
+}
+
+// queryResponse is the streams result of query_range API, values are [nanosecond timestamp, line] pairs
+type queryResponse struct {
+	Data struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Values [][2]string `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// logLine is a line of any of the returned streams
+type logLine struct {
+	time int64
+	line string
+}
+
+// fetch reads the next batch of lines into the buffer
+func (r *QueryReader) fetch() error {
+	params := url.Values{
+		"query":     {r.query},
+		"start":     {strconv.FormatInt(r.lastTime, 10)},
+		"end":       {strconv.FormatInt(r.end.UnixNano(), 10)},
+		"limit":     {strconv.Itoa(r.limit)},
+		"direction": {"forward"},
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, "GET", r.base+"/loki/api/v1/query_range?"+params.Encode(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	if r.org != "" {
+		req.Header.Set("X-Scope-OrgID", r.org)
+	}
+
+	if r.user != nil {
+		password, _ := r.user.Password()
+		req.SetBasicAuth(r.user.Username(), password)
+	}
+
+	resp, err := r.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("ERROR %s while querying Loki: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var result queryResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	if result.Data.ResultType != "streams" {
+		return fmt.Errorf("Loki query has to return log lines, not %s (metric queries can not be replayed)", result.Data.ResultType)
+	}
+
+	var lines []logLine
+
+	for _, stream := range result.Data.Result {
+		for _, value := range stream.Values {
+			t, err := strconv.ParseInt(value[0], 10, 64)
+
+			if err != nil {
+				return err
+			}
+
+			lines = append(lines, logLine{time: t, line: value[1]})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].time < lines[j].time })
+
+	if len(lines) < r.limit {
+		r.done = true
+	}
+
+	for _, l := range lines {
+		if l.time == r.lastTime && r.lastLines[l.line] {
+			continue
+		}
+
+		if l.time != r.lastTime {
+			r.lastTime = l.time
+			r.lastLines = make(map[string]bool)
+		}
+
+		r.lastLines[l.line] = true
+		r.buf.WriteString(strings.TrimSuffix(l.line, "\n"))
+		r.buf.WriteByte('\n')
+	}
+
+	// Whole batch had the same timestamp as the previous one, lines beyond the limit
+	// at that timestamp can not be fetched, so the next batch starts right after it
+	if r.buf.Len() == 0 && !r.done {
+		r.lastTime++
+		r.lastLines = make(map[string]bool)
+	}
+
+	return nil
+}
+
+func (r *QueryReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+	}
+
+	return r.buf.Read(p)
+}
@@ -0,0 +1,118 @@
+package objstore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcsEndpoint = "https://storage.googleapis.com"
+	gcsScope    = "https://www.googleapis.com/auth/devstorage.read_only"
+)
+
+// gcsStore talks to Cloud Storage JSON API authenticated with Application Default Credentials,
+// requests are sent anonymously when there are none (public buckets). STORAGE_EMULATOR_HOST is respected
+type gcsStore struct {
+	client   *http.Client
+	endpoint string
+}
+
+func newGCSStore(ctx context.Context) *gcsStore {
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+
+		return &gcsStore{client: http.DefaultClient, endpoint: strings.TrimSuffix(host, "/")}
+	}
+
+	client, err := google.DefaultClient(ctx, gcsScope)
+
+	if err != nil {
+		log.Printf("No Google Cloud credentials found, reading gs:// objects anonymously: %s", err)
+		client = http.DefaultClient
+	}
+
+	return &gcsStore{client: client, endpoint: gcsEndpoint}
+}
+
+func (g *gcsStore) get(ctx context.Context, rawURL string, object string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		return nil, responseError(object, resp.Status, resp.Body)
+	}
+
+	return resp, nil
+}
+
+// listObjectsResult is the response of objects list call
+type listObjectsResult struct {
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func (g *gcsStore) list(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+
+	query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+
+	for {
+		resp, err := g.get(ctx, g.endpoint+"/storage/v1/b/"+url.PathEscape(bucket)+"/o?"+query.Encode(), "gs://"+bucket+"/"+prefix)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var result listObjectsResult
+
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			keys = append(keys, item.Name)
+		}
+
+		if result.NextPageToken == "" {
+			return keys, nil
+		}
+
+		query.Set("pageToken", result.NextPageToken)
+	}
+}
+
+func (g *gcsStore) open(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	resp, err := g.get(ctx, g.endpoint+"/storage/v1/b/"+url.PathEscape(bucket)+"/o/"+url.PathEscape(key)+"?alt=media", "gs://"+bucket+"/"+key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,130 @@
+// Package objstore streams log files archived in S3 and Google Cloud Storage
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// store is a bucket based object storage
+type store interface {
+	// list returns keys of the objects starting with the prefix
+	list(ctx context.Context, bucket, prefix string) ([]string, error)
+	open(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]store)
+)
+
+// IsURL tells if the name is s3:// or gs:// object URL
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "s3://") || strings.HasPrefix(name, "gs://")
+}
+
+func parseURL(ctx context.Context, rawURL string) (store, string, string, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if u.Host == "" {
+		return nil, "", "", fmt.Errorf("Bucket is missing in '%s'", rawURL)
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	s, ok := stores[u.Scheme]
+
+	if !ok {
+		switch u.Scheme {
+		case "s3":
+			s = newS3Store()
+		case "gs":
+			s = newGCSStore(ctx)
+		default:
+			return nil, "", "", fmt.Errorf("Unsupported object storage '%s', s3:// and gs:// are supported", u.Scheme)
+		}
+
+		stores[u.Scheme] = s
+	}
+
+	return s, u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// List expands the URL to URLs of objects: all objects under the prefix when the URL ends with "/",
+// objects matching glob pattern (path.Match syntax) when the key contains one. Keys are sorted
+func List(ctx context.Context, rawURL string) ([]string, error) {
+	s, bucket, key, err := parseURL(ctx, rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := strings.IndexAny(key, "*?[")
+
+	if pattern < 0 && !strings.HasSuffix(key, "/") && key != "" {
+		return []string{rawURL}, nil
+	}
+
+	prefix := key
+
+	if pattern >= 0 {
+		prefix = key[:pattern]
+	}
+
+	keys, err := s.list(ctx, bucket, prefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+
+	for _, k := range keys {
+		if pattern >= 0 {
+			if ok, err := path.Match(key, k); err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+		}
+
+		urls = append(urls, rawURL[:strings.Index(rawURL, "://")]+"://"+bucket+"/"+k)
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("No objects match '%s'", rawURL)
+	}
+
+	sort.Strings(urls)
+
+	return urls, nil
+}
+
+// Open streams content of the object
+func Open(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	s, bucket, key, err := parseURL(ctx, rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.open(ctx, bucket, key)
+}
+
+// responseError describes failed storage API response
+func responseError(rawURL string, status string, body io.Reader) error {
+	message, _ := io.ReadAll(io.LimitReader(body, 1024))
+
+	return fmt.Errorf("ERROR %s while requesting %s: %s", status, rawURL, strings.TrimSpace(string(message)))
+}
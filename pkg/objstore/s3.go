@@ -0,0 +1,137 @@
+package objstore
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/awsv4"
+)
+
+// s3Store talks to S3 REST API with requests signed by AWS Signature Version 4. Credentials, region
+// and endpoint of S3 compatible storages are taken from the standard AWS environment variables,
+// requests are sent unsigned when there are no credentials (public buckets)
+type s3Store struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	creds    awsv4.Credentials
+}
+
+func newS3Store() *s3Store {
+	return &s3Store{
+		client:   http.DefaultClient,
+		endpoint: awsv4.EnvEndpoint("S3"),
+		region:   awsv4.EnvRegion(),
+		creds:    awsv4.EnvCredentials(),
+	}
+}
+
+// newRequest creates GET request of the object, virtual hosted style is used with AWS
+// and path style with custom endpoints
+func (s *s3Store) newRequest(ctx context.Context, bucket, key string, query url.Values) (*http.Request, error) {
+	u := &url.URL{Scheme: "https", Host: bucket + ".s3." + s.region + ".amazonaws.com", Path: "/" + key}
+
+	if s.endpoint != "" {
+		endpoint, err := url.Parse(s.endpoint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		u = &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: endpoint.Path + "/" + bucket + "/" + key}
+	}
+
+	u.RawPath = awsv4.Escape(u.Path, true)
+	u.RawQuery = awsv4.Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.creds.AccessKey != "" {
+		req.Header.Set("X-Amz-Content-Sha256", awsv4.EmptyPayloadHash)
+		awsv4.Sign(req, awsv4.EmptyPayloadHash, s.creds, "s3", s.region, time.Now())
+	}
+
+	return req, nil
+}
+
+func (s *s3Store) get(ctx context.Context, bucket, key string, query url.Values) (*http.Response, error) {
+	req, err := s.newRequest(ctx, bucket, key, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		return nil, responseError("s3://"+bucket+"/"+key, resp.Status, resp.Body)
+	}
+
+	return resp, nil
+}
+
+// listBucketResult is the response of ListObjectsV2
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *s3Store) list(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+
+	for {
+		resp, err := s.get(ctx, bucket, "", query)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+
+		if !result.IsTruncated {
+			return keys, nil
+		}
+
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (s *s3Store) open(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	resp, err := s.get(ctx, bucket, key, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
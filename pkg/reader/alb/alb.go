@@ -0,0 +1,137 @@
+package alb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// Positions of the fields in the ALB access log entry, see
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html
+const (
+	albTimeField                = 1
+	albClientField              = 3
+	albRequestProcessingField   = 5
+	albResponseProcessingField  = 7
+	albStatusField              = 8
+	albRequestField             = 12
+	albUserAgentField           = 13
+	albRequestCreationTimeField = 21
+)
+
+// ALBReader implements reader.LogReader interface
+type ALBReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+}
+
+func parseALBTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// ALB logs absolute URLs, only path and query are replayed against the prefix,
+// scheme and host (without the default port) are kept in the entry
+func parseALBURL(rawURL string, entry *reader.LogEntry) error {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return err
+	}
+
+	entry.URL = u.RequestURI()
+	entry.Scheme = u.Scheme
+	entry.Host = u.Host
+
+	if port := u.Port(); (port == "80" && u.Scheme == "http") || (port == "443" && u.Scheme == "https") {
+		entry.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	return nil
+}
+
+func parseALBInto(s string, entry *reader.LogEntry) error {
+	fields := reader.SplitQuoted(s)
+
+	if len(fields) <= albUserAgentField {
+		return fmt.Errorf("Not enough fields in ALB log line: %s", s)
+	}
+
+	// request_creation_time is when the request was received, time is when the response was sent
+	timeString := fields[albTimeField]
+
+	if len(fields) > albRequestCreationTimeField && fields[albRequestCreationTimeField] != "-" {
+		timeString = fields[albRequestCreationTimeField]
+	}
+
+	t, err := parseALBTime(timeString)
+
+	if err != nil {
+		return err
+	}
+
+	parsedRequest, err := reader.ParseRequest(fields[albRequestField])
+
+	if err != nil {
+		return err
+	}
+
+	if err := parseALBURL(parsedRequest[1], entry); err != nil {
+		return err
+	}
+
+	entry.Method = parsedRequest[0]
+	entry.Time = t
+	entry.Status = reader.ParseStatus(fields[albStatusField])
+	entry.ClientIP = reader.ParseClientIP(fields[albClientField])
+
+	// request, target and response processing times add up to the request duration, -1 when not known
+	for i := albRequestProcessingField; i <= albResponseProcessingField; i++ {
+		entry.Duration += reader.ParseDuration(fields[i], time.Second)
+	}
+
+	if ua := fields[albUserAgentField]; ua != "-" {
+		entry.UA = ua
+	}
+
+	return nil
+}
+
+// NewReader creates new reader for an AWS application load balancer log format using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader ALBReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+
+	return &reader
+}
+
+func (r *ALBReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	inputAvailable := r.InputScanner.Scan()
+
+	if !inputAvailable {
+		err := reader.NewInputError(r.InputScanner.Err())
+
+		if err != nil {
+			return &entry, err
+		}
+
+		return &entry, io.EOF
+	}
+
+	err := parseALBInto(r.InputScanner.Text(), &entry)
+
+	return &entry, err
+}
+
+// ParseLine implements reader.LineParser
+func (r *ALBReader) ParseLine(line string, entry *reader.LogEntry) error {
+	return parseALBInto(line, entry)
+}
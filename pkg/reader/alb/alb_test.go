@@ -0,0 +1,73 @@
+package alb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseALBInto(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		entry reader.LogEntry
+		err   bool
+	}{
+		{
+			name: "http with request creation time",
+			line: `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2018, time.July, 2, 22, 22, 48, 364000000, time.UTC),
+				Method:   "GET",
+				URL:      "/",
+				Scheme:   "http",
+				Host:     "www.example.com",
+				ClientIP: "192.168.131.39",
+				Status:   200,
+				Duration: time.Millisecond,
+				UA:       "curl/7.46.0",
+			},
+		},
+		{
+			name: "https with query on other port and unknown processing times",
+			line: `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 [2001:db8::1]:2817 - -1 -1 -1 502 - 34 366 "POST https://www.example.com:8443/api?q=a%20b HTTP/1.1" "-" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2018, time.July, 2, 22, 23, 0, 186641000, time.UTC),
+				Method:   "POST",
+				URL:      "/api?q=a%20b",
+				Scheme:   "https",
+				Host:     "www.example.com:8443",
+				ClientIP: "2001:db8::1",
+				Status:   502,
+			},
+		},
+		{
+			name: "not enough fields",
+			line: `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817`,
+			err:  true,
+		},
+		{
+			name: "invalid time",
+			line: `http 02/Jul/2018:22:23:00 app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0"`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			err := parseALBInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !tt.err && !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
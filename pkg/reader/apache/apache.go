@@ -0,0 +1,103 @@
+package apache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	apacheTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// Matches both common (%h %l %u %t "%r" %>s %b) and combined
+// (common + "%{Referer}i" "%{User-agent}i") log formats
+var apacheLineRegexp = regexp.MustCompile(`^(\S+) (\S+) (.+?) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}|-) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+var apacheUnescaper = strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+
+// ApacheReader implements reader.LogReader interface
+type ApacheReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+}
+
+func parseApacheTime(timeLocal string) (time.Time, error) {
+	return time.Parse(apacheTimeLayout, timeLocal)
+}
+
+func parseApacheInto(s string, entry *reader.LogEntry) error {
+	matches := apacheLineRegexp.FindStringSubmatch(s)
+
+	if matches == nil {
+		return fmt.Errorf("Line does not match apache common or combined log format: %s", s)
+	}
+
+	t, err := parseApacheTime(matches[4])
+
+	if err != nil {
+		return err
+	}
+
+	parsedRequest, err := reader.ParseRequest(apacheUnescaper.Replace(matches[5]))
+
+	if err != nil {
+		return err
+	}
+
+	entry.Method = parsedRequest[0]
+	entry.URL = parsedRequest[1]
+	entry.Time = t
+	entry.Status = reader.ParseStatus(matches[6])
+	entry.ClientIP = reader.ParseClientIP(matches[1])
+
+	if referer := matches[8]; referer != "-" {
+		entry.Referer = apacheUnescaper.Replace(referer)
+	}
+
+	if ua := matches[9]; ua != "-" {
+		entry.UA = apacheUnescaper.Replace(ua)
+	}
+
+	return nil
+}
+
+// NewReader creates new reader for apache common and combined log formats using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader ApacheReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+
+	return &reader
+}
+
+func (r *ApacheReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	inputAvailable := r.InputScanner.Scan()
+
+	if !inputAvailable {
+		err := reader.NewInputError(r.InputScanner.Err())
+
+		if err != nil {
+			return &entry, err
+		}
+
+		return &entry, io.EOF
+	}
+
+	err := parseApacheInto(r.InputScanner.Text(), &entry)
+
+	return &entry, err
+}
+
+// ParseLine implements reader.LineParser
+func (r *ApacheReader) ParseLine(line string, entry *reader.LogEntry) error {
+	return parseApacheInto(line, entry)
+}
@@ -0,0 +1,134 @@
+package apache
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseApacheInto(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		entry reader.LogEntry
+		err   bool
+	}{
+		{
+			name: "common",
+			line: `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2000, time.October, 10, 20, 55, 36, 0, time.UTC),
+				Method:   "GET",
+				URL:      "/apache_pb.gif",
+				ClientIP: "127.0.0.1",
+				Status:   200,
+			},
+		},
+		{
+			name: "combined",
+			line: `10.0.0.1 - - [01/Jan/2024:10:00:00 +0000] "POST /search?q=a HTTP/1.1" 201 - "http://example.com/start.html" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:   "POST",
+				URL:      "/search?q=a",
+				ClientIP: "10.0.0.1",
+				Status:   201,
+				Referer:  "http://example.com/start.html",
+				UA:       "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+		},
+		{
+			name: "escaped quotes and user with spaces",
+			line: `2001:db8::1 - John Doe [01/Jan/2024:10:00:00 +0000] "GET /a\"b HTTP/1.1" - 12 "-" "say \"hi\""`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:   "GET",
+				URL:      `/a"b`,
+				ClientIP: "2001:db8::1",
+				UA:       `say "hi"`,
+			},
+		},
+		{
+			name: "not an apache line",
+			line: `2024-01-01 10:00:00 GET /a`,
+			err:  true,
+		},
+		{
+			name: "invalid time",
+			line: `10.0.0.1 - - [01/Foo/2024:10:00:00 +0000] "GET / HTTP/1.1" 200 12`,
+			err:  true,
+		},
+		{
+			name: "request without protocol",
+			line: `10.0.0.1 - - [01/Jan/2024:10:00:00 +0000] "GET /" 400 12`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			err := parseApacheInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if tt.err {
+				return
+			}
+
+			if !entry.Time.Equal(tt.entry.Time) {
+				t.Errorf("time %s, expected %s", entry.Time, tt.entry.Time)
+			}
+
+			entry.Time = tt.entry.Time
+
+			if !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	input := strings.Join([]string{
+		`10.0.0.1 - - [01/Jan/2024:10:00:00 +0000] "GET /a HTTP/1.1" 200 12`,
+		`not an apache line`,
+		`10.0.0.1 - - [01/Jan/2024:10:00:01 +0000] "GET /b HTTP/1.1" 200 12`,
+	}, "\n")
+
+	r := NewReader(strings.NewReader(input))
+
+	var urls []string
+	var errors int
+
+	for {
+		entry, err := r.Read()
+
+		if err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+
+			// Lines which do not match can be skipped, the next one is read
+			if reader.IsInputError(err) {
+				t.Fatalf("unexpected input error %v", err)
+			}
+
+			errors++
+
+			continue
+		}
+
+		urls = append(urls, entry.URL)
+	}
+
+	if !reflect.DeepEqual(urls, []string{"/a", "/b"}) || errors != 1 {
+		t.Errorf("read %q with %d errors", urls, errors)
+	}
+}
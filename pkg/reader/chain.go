@@ -0,0 +1,34 @@
+package reader
+
+import (
+	"context"
+	"io"
+)
+
+// ChainReader reads entries of the readers one after another, like io.MultiReader
+type ChainReader struct {
+	Readers []LogReader
+}
+
+// NewChainReader creates reader returning all entries of the first reader, then of the second one and so on
+func NewChainReader(readers ...LogReader) LogReader {
+	return &ChainReader{Readers: readers}
+}
+
+func (r *ChainReader) Read() (*LogEntry, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *ChainReader) ReadContext(ctx context.Context) (*LogEntry, error) {
+	for len(r.Readers) > 0 {
+		entry, err := ReadContext(ctx, r.Readers[0])
+
+		if err != io.EOF {
+			return entry, err
+		}
+
+		r.Readers = r.Readers[1:]
+	}
+
+	return &LogEntry{}, io.EOF
+}
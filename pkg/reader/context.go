@@ -0,0 +1,26 @@
+package reader
+
+import (
+	"context"
+)
+
+// ContextLogReader is optionally implemented by readers which can stop waiting
+// for the next entry when the context is cancelled
+type ContextLogReader interface {
+	LogReader
+	ReadContext(ctx context.Context) (*LogEntry, error)
+}
+
+// ReadContext reads next entry using ReadContext of the reader if it is implemented,
+// other readers are only checked for cancellation before Read is called
+func ReadContext(ctx context.Context, r LogReader) (*LogEntry, error) {
+	if cr, ok := r.(ContextLogReader); ok {
+		return cr.ReadContext(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &LogEntry{}, err
+	}
+
+	return r.Read()
+}
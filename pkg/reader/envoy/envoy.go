@@ -0,0 +1,149 @@
+package envoy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+	"github.com/Gonzih/log-replay/pkg/reader/nginxjson"
+)
+
+// Matches [%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" at the start of
+// the default envoy (and istio) access log format
+var envoyLineRegexp = regexp.MustCompile(`^\[([^\]]+)\] "([^"]*)"`)
+
+// Quoted fields after the request line, counted from the end since istio inserts
+// extra ones in front: ... "%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%"
+var envoyQuotedRegexp = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+const (
+	envoyDurationField    = 4
+	envoyUserAgentFromEnd = 4
+	envoyForwardedFromEnd = 5
+	envoyAuthorityFromEnd = 2
+)
+
+// DefaultJSONFields matches keys used by istio's JSON access log encoding
+var DefaultJSONFields = nginxjson.Fields{
+	Time:   "start_time",
+	Method: "method",
+	URL:    "path",
+	Status: "response_code",
+	// %DURATION% is in milliseconds
+	Duration:     "duration",
+	DurationUnit: time.Millisecond,
+	UA:           "user_agent",
+	Host:         "authority",
+	Client:       "downstream_remote_address",
+}
+
+// EnvoyReader implements reader.LogReader interface
+type EnvoyReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+}
+
+func parseEnvoyTime(startTime string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, startTime)
+}
+
+func quotedFromEnd(quoted [][]string, n int) string {
+	if len(quoted) < n {
+		return ""
+	}
+
+	value := strings.Replace(quoted[len(quoted)-n][1], `\"`, `"`, -1)
+
+	if value == "-" {
+		return ""
+	}
+
+	return value
+}
+
+func parseEnvoyInto(s string, entry *reader.LogEntry) error {
+	matches := envoyLineRegexp.FindStringSubmatch(s)
+
+	if matches == nil {
+		return fmt.Errorf("Line does not match envoy access log format: %s", s)
+	}
+
+	t, err := parseEnvoyTime(matches[1])
+
+	if err != nil {
+		return err
+	}
+
+	parsedRequest, err := reader.ParseRequest(matches[2])
+
+	if err != nil {
+		return err
+	}
+
+	// Skip the request line itself, only the trailing quoted fields are positional
+	rest := s[len(matches[0]):]
+	quoted := envoyQuotedRegexp.FindAllStringSubmatch(rest, -1)
+
+	// %RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% follow the request line
+	if fields := strings.Fields(rest); len(fields) > 0 {
+		entry.Status = reader.ParseStatus(fields[0])
+
+		if len(fields) > envoyDurationField {
+			entry.Duration = reader.ParseDuration(fields[envoyDurationField], time.Millisecond)
+		}
+	}
+
+	entry.Method = parsedRequest[0]
+	entry.URL = parsedRequest[1]
+	entry.Time = t
+	entry.UA = quotedFromEnd(quoted, envoyUserAgentFromEnd)
+	entry.Host = quotedFromEnd(quoted, envoyAuthorityFromEnd)
+	// The default format has no downstream address, X-Forwarded-For is logged instead
+	entry.ClientIP = reader.ParseClientIP(quotedFromEnd(quoted, envoyForwardedFromEnd))
+
+	return nil
+}
+
+// NewReader creates new reader for the default envoy access log format using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader EnvoyReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+
+	return &reader
+}
+
+// NewJSONReader creates new reader for envoy JSON access logs using provided io.Reader and key mapping
+func NewJSONReader(inputReader io.Reader, fields nginxjson.Fields, timeLayout string) reader.LogReader {
+	return nginxjson.NewReader(inputReader, fields, timeLayout)
+}
+
+func (r *EnvoyReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	inputAvailable := r.InputScanner.Scan()
+
+	if !inputAvailable {
+		err := reader.NewInputError(r.InputScanner.Err())
+
+		if err != nil {
+			return &entry, err
+		}
+
+		return &entry, io.EOF
+	}
+
+	err := parseEnvoyInto(r.InputScanner.Text(), &entry)
+
+	return &entry, err
+}
+
+// ParseLine implements reader.LineParser
+func (r *EnvoyReader) ParseLine(line string, entry *reader.LogEntry) error {
+	return parseEnvoyInto(line, entry)
+}
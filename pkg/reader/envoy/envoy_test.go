@@ -0,0 +1,104 @@
+package envoy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseEnvoyInto(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		entry reader.LogEntry
+		err   bool
+	}{
+		{
+			name: "default format",
+			line: `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2016, time.April, 15, 20, 17, 0, 310000000, time.UTC),
+				Method:   "POST",
+				URL:      "/api/v1/locations",
+				Status:   204,
+				Duration: 226 * time.Millisecond,
+				UA:       "nsq2http",
+				Host:     "locations",
+				ClientIP: "10.0.35.28",
+			},
+		},
+		{
+			name: "istio format with extra fields in front",
+			line: `[2024-01-01T10:00:00.000Z] "GET /productpage?u=1 HTTP/1.1" 503 UF upstream_reset_before_response_started{connection_failure} - "-" 0 91 3 - "10.0.0.1, 10.0.0.2" "Mozilla/5.0 \"x\"" "c4d3" "productpage:9080" "-" outbound|9080||productpage - 10.0.0.3:9080 10.0.0.4:50000 - default`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:   "GET",
+				URL:      "/productpage?u=1",
+				Status:   503,
+				UA:       `Mozilla/5.0 "x"`,
+				Host:     "productpage:9080",
+				ClientIP: "10.0.0.1",
+			},
+		},
+		{
+			name: "not an envoy line",
+			line: `2016-04-15T20:17:00.310Z POST /api/v1/locations`,
+			err:  true,
+		},
+		{
+			name: "invalid time",
+			line: `[15/Apr/2016:20:17:00 +0000] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "-" "-" "-" "-" "-"`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			err := parseEnvoyInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !tt.err && !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	line := `{"start_time":"2016-04-15T20:17:00.310Z","method":"GET","path":"/api/v1/locations","protocol":"HTTP/1.1","response_code":200,"duration":12,"user_agent":"nsq2http","authority":"locations","downstream_remote_address":"10.0.35.28:5123"}`
+
+	entry, err := NewJSONReader(strings.NewReader(line), DefaultJSONFields, "").Read()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := reader.LogEntry{
+		Time:     time.Date(2016, time.April, 15, 20, 17, 0, 310000000, time.UTC),
+		Method:   "GET",
+		URL:      "/api/v1/locations",
+		Status:   200,
+		Duration: 12 * time.Millisecond,
+		UA:       "nsq2http",
+		Host:     "locations",
+		ClientIP: "10.0.35.28",
+	}
+
+	if !entry.Time.Equal(expected.Time) {
+		t.Errorf("time %s, expected %s", entry.Time, expected.Time)
+	}
+
+	entry.Time = expected.Time
+
+	if !reflect.DeepEqual(*entry, expected) {
+		t.Errorf("entry %+v, expected %+v", *entry, expected)
+	}
+}
@@ -0,0 +1,238 @@
+package esslowlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	maxLineSize = 16 * 1024 * 1024
+	// Timestamps have a comma before milliseconds, it is replaced with a dot before parsing
+	plainTimeLayout = "2006-01-02T15:04:05.000"
+	jsonTimeLayout  = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// plainRegexp matches [time][level][logger] [node] [index][shard] and the rest of plain text slow log lines (6.x and older)
+var plainRegexp = regexp.MustCompile(`^\[([^\]]+)\]\[\s*(\w+)\s*\]\[([^\]]+)\]\s*\[[^\]]*\]\s*(\[[^\]]+\]\[\d+\])\s*(.*)$`)
+
+// shardRegexp matches index and shard of the message ([index][0])
+var shardRegexp = regexp.MustCompile(`^\[([^\]]+)\]\[\d+\]`)
+
+var tookRegexp = regexp.MustCompile(`took_millis\[(\d+)\]`)
+var searchTypeRegexp = regexp.MustCompile(`search_type\[(\w+)\]`)
+var idRegexp = regexp.MustCompile(`, id\[([^\]]*)\]`)
+
+// fields of the JSON layouts of 7.x and ECS layout of 8.x, the first one present is used
+var (
+	timeFields       = []string{"@timestamp", "timestamp"}
+	loggerFields     = []string{"log.logger", "component", "type"}
+	messageFields    = []string{"elasticsearch.slowlog.message", "message"}
+	tookFields       = []string{"elasticsearch.slowlog.took_millis", "took_millis"}
+	searchTypeFields = []string{"elasticsearch.slowlog.search_type", "search_type"}
+	sourceFields     = []string{"elasticsearch.slowlog.source", "source"}
+	idFields         = []string{"elasticsearch.slowlog.id", "id"}
+)
+
+// line is the part of slow log line the entry is made of
+type line struct {
+	time       string
+	logger     string
+	message    string
+	took       string
+	searchType string
+	source     string
+	id         string
+}
+
+// ESSlowLogReader implements reader.LogReader interface
+type ESSlowLogReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+}
+
+// NewReader creates new reader of Elasticsearch search slow logs using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader ESSlowLogReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.InputScanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	return &reader
+}
+
+// parsePlain parses plain text line, the source is everything between source[ and ], id[ (or the last ])
+func parsePlain(s string) (*line, error) {
+	matches := plainRegexp.FindStringSubmatch(s)
+
+	if matches == nil {
+		return nil, fmt.Errorf("ERROR while parsing slow log line: %s", s)
+	}
+
+	l := &line{time: matches[1], logger: matches[3], message: matches[4]}
+	rest := matches[5]
+
+	if m := tookRegexp.FindStringSubmatch(rest); m != nil {
+		l.took = m[1]
+	}
+
+	if m := searchTypeRegexp.FindStringSubmatch(rest); m != nil {
+		l.searchType = m[1]
+	}
+
+	if m := idRegexp.FindStringSubmatch(rest); m != nil {
+		l.id = m[1]
+	}
+
+	if start := strings.Index(rest, "source["); start >= 0 {
+		source := rest[start+len("source["):]
+		end := strings.LastIndex(source, "], id[")
+
+		if end < 0 {
+			end = strings.LastIndex(source, "]")
+		}
+
+		if end >= 0 {
+			l.source = source[:end]
+		}
+	}
+
+	return l, nil
+}
+
+// parseJSON parses line of JSON (7.x) or ECS JSON (8.x) layout
+func parseJSON(s string) (*line, error) {
+	var fields map[string]interface{}
+
+	if err := json.Unmarshal([]byte(s), &fields); err != nil {
+		return nil, fmt.Errorf("ERROR while parsing slow log line: %s", err)
+	}
+
+	return &line{
+		time:       field(fields, timeFields),
+		logger:     field(fields, loggerFields),
+		message:    field(fields, messageFields),
+		took:       field(fields, tookFields),
+		searchType: field(fields, searchTypeFields),
+		source:     field(fields, sourceFields),
+		id:         field(fields, idFields),
+	}, nil
+}
+
+// field returns the first of the fields present, numbers are formatted
+func field(fields map[string]interface{}, names []string) string {
+	for _, name := range names {
+		switch v := fields[name].(type) {
+		case string:
+			return v
+		case float64:
+			return fmt.Sprint(v)
+		}
+	}
+
+	return ""
+}
+
+func parseTime(value string) (time.Time, error) {
+	value = strings.Replace(value, ",", ".", 1)
+
+	if t, err := time.Parse(jsonTimeLayout, value); err == nil {
+		return t, nil
+	}
+
+	// 7.x writes +0000 zones
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z0700", value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(plainTimeLayout, value)
+}
+
+// parseInto makes POST /index/_search entry of query phase lines, lines of fetch phase (the query was logged
+// already) and indexing slow logs are skipped
+func parseInto(s string, entry *reader.LogEntry) error {
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return reader.ErrSkipLine
+	}
+
+	var l *line
+	var err error
+
+	if strings.HasPrefix(s, "{") {
+		l, err = parseJSON(s)
+	} else {
+		l, err = parsePlain(s)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if l.logger != "" && !strings.HasSuffix(l.logger, ".query") && l.logger != "index_search_slowlog" {
+		return reader.ErrSkipLine
+	}
+
+	shard := shardRegexp.FindStringSubmatch(l.message)
+
+	if shard == nil {
+		return fmt.Errorf("slow log line has no [index][shard] message: %s", s)
+	}
+
+	if entry.Time, err = parseTime(l.time); err != nil {
+		return fmt.Errorf("ERROR while parsing slow log time: %s", err)
+	}
+
+	entry.Method = "POST"
+	entry.URL = "/" + shard[1] + "/_search"
+	entry.Payload = l.source
+	entry.ContentType = "application/json"
+	entry.Duration = reader.ParseDuration(l.took, time.Millisecond)
+
+	if strings.EqualFold(l.searchType, "DFS_QUERY_THEN_FETCH") {
+		entry.URL += "?search_type=dfs_query_then_fetch"
+	}
+
+	// id is X-Opaque-Id of the search request
+	if l.id != "null" {
+		entry.SetHeader("X-Opaque-Id", l.id)
+	}
+
+	return nil
+}
+
+// Read skips lines which are not query phase ones
+func (r *ESSlowLogReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	for r.InputScanner.Scan() {
+		err := parseInto(r.InputScanner.Text(), &entry)
+
+		if err == reader.ErrSkipLine {
+			continue
+		}
+
+		return &entry, err
+	}
+
+	err := reader.NewInputError(r.InputScanner.Err())
+
+	if err != nil {
+		return &entry, err
+	}
+
+	return &entry, io.EOF
+}
+
+// ParseLine implements reader.LineParser
+func (r *ESSlowLogReader) ParseLine(line string, entry *reader.LogEntry) error {
+	return parseInto(line, entry)
+}
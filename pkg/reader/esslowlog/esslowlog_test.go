@@ -0,0 +1,114 @@
+package esslowlog
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseInto(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		entry reader.LogEntry
+		err   error
+	}{
+		{
+			name: "plain text query phase",
+			line: `[2018-01-01T10:00:00,123][WARN ][index.search.slowlog.query] [node-1] [products][0] took[78.4ms], took_millis[78], total_hits[10], types[], stats[], search_type[QUERY_THEN_FETCH], total_shards[5], source[{"query":{"match":{"name":{"query":"foo [bar]"}}}}], id[app-1],`,
+			entry: reader.LogEntry{
+				Time:        time.Date(2018, time.January, 1, 10, 0, 0, 123000000, time.UTC),
+				Method:      "POST",
+				URL:         "/products/_search",
+				Payload:     `{"query":{"match":{"name":{"query":"foo [bar]"}}}}`,
+				ContentType: "application/json",
+				Duration:    78 * time.Millisecond,
+				Headers:     http.Header{"X-Opaque-Id": []string{"app-1"}},
+			},
+		},
+		{
+			name: "plain text fetch phase",
+			line: `[2018-01-01T10:00:00,200][WARN ][index.search.slowlog.fetch] [node-1] [products][0] took[10ms], took_millis[10], total_hits[10], types[], stats[], search_type[QUERY_THEN_FETCH], total_shards[5], source[{"query":{"match_all":{}}}], id[],`,
+			err:  reader.ErrSkipLine,
+		},
+		{
+			name: "7.x JSON with DFS search type",
+			line: `{"type": "index_search_slowlog", "timestamp": "2020-01-01T10:00:01,123Z", "level": "WARN", "component": "i.s.s.query", "cluster.name": "c", "node.name": "n", "message": "[logs-1][2]", "took": "5ms", "took_millis": "5", "total_hits": "0 hits", "stats": "[]", "search_type": "DFS_QUERY_THEN_FETCH", "total_shards": "1", "source": "{\"size\":0}", "id": "null"}`,
+			entry: reader.LogEntry{
+				Time:        time.Date(2020, time.January, 1, 10, 0, 1, 123000000, time.UTC),
+				Method:      "POST",
+				URL:         "/logs-1/_search?search_type=dfs_query_then_fetch",
+				Payload:     `{"size":0}`,
+				ContentType: "application/json",
+				Duration:    5 * time.Millisecond,
+			},
+		},
+		{
+			name: "8.x ECS JSON",
+			line: `{"@timestamp":"2024-01-01T10:00:02.500Z","log.level":"WARN","log.logger":"index.search.slowlog.query","elasticsearch.slowlog.id":"kibana","elasticsearch.slowlog.message":"[orders][1]","elasticsearch.slowlog.search_type":"QUERY_THEN_FETCH","elasticsearch.slowlog.source":"{\"query\":{\"term\":{\"id\":1}}}","elasticsearch.slowlog.took_millis":12,"elasticsearch.slowlog.total_shards":3}`,
+			entry: reader.LogEntry{
+				Time:        time.Date(2024, time.January, 1, 10, 0, 2, 500000000, time.UTC),
+				Method:      "POST",
+				URL:         "/orders/_search",
+				Payload:     `{"query":{"term":{"id":1}}}`,
+				ContentType: "application/json",
+				Duration:    12 * time.Millisecond,
+				Headers:     http.Header{"X-Opaque-Id": []string{"kibana"}},
+			},
+		},
+		{
+			name: "indexing slow log",
+			line: `{"@timestamp":"2024-01-01T10:00:03.500Z","log.level":"WARN","log.logger":"index.indexing.slowlog.index","elasticsearch.slowlog.message":"[orders/abc]","elasticsearch.slowlog.took_millis":12}`,
+			err:  reader.ErrSkipLine,
+		},
+		{
+			name: "empty line",
+			line: "  ",
+			err:  reader.ErrSkipLine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			if err := parseInto(tt.line, &entry); err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if !entry.Time.Equal(tt.entry.Time) {
+				t.Errorf("time %s, expected %s", entry.Time, tt.entry.Time)
+			}
+
+			entry.Time = tt.entry.Time
+
+			if !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
+
+func TestParseIntoErrors(t *testing.T) {
+	lines := []string{
+		`2018-01-01 10:00:00 query took 78ms`,
+		`{"@timestamp":`,
+		`{"@timestamp":"2024-01-01T10:00:02.500Z","log.logger":"index.search.slowlog.query","elasticsearch.slowlog.message":"no shard"}`,
+		`{"@timestamp":"yesterday","log.logger":"index.search.slowlog.query","elasticsearch.slowlog.message":"[orders][1]"}`,
+	}
+
+	for _, line := range lines {
+		var entry reader.LogEntry
+
+		if err := parseInto(line, &entry); err == nil || err == reader.ErrSkipLine {
+			t.Errorf("expected error of %q, got %v", line, err)
+		}
+	}
+}
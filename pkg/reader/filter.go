@@ -0,0 +1,30 @@
+package reader
+
+import (
+	"context"
+)
+
+// FilterReader skips entries of the underlying reader which are not accepted by the Accept function
+type FilterReader struct {
+	Reader LogReader
+	Accept func(*LogEntry) bool
+}
+
+// NewFilterReader creates reader returning only entries of provided reader accepted by the function
+func NewFilterReader(r LogReader, accept func(*LogEntry) bool) LogReader {
+	return &FilterReader{Reader: r, Accept: accept}
+}
+
+func (r *FilterReader) Read() (*LogEntry, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *FilterReader) ReadContext(ctx context.Context) (*LogEntry, error) {
+	for {
+		entry, err := ReadContext(ctx, r.Reader)
+
+		if err != nil || r.Accept(entry) {
+			return entry, err
+		}
+	}
+}
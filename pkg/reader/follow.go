@@ -0,0 +1,106 @@
+package reader
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// FollowReader reads a growing file like tail -F: at the end of the file it waits for more
+// data instead of returning io.EOF, reopens the path when the file was rotated
+// and starts over when it was truncated
+type FollowReader struct {
+	Path     string
+	Interval time.Duration
+	file     *os.File
+	offset   int64
+}
+
+// NewFollowReader opens the file positioned at its end, new data is polled for every interval
+func NewFollowReader(path string, interval time.Duration) (*FollowReader, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := file.Seek(0, io.SeekEnd)
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FollowReader{Path: path, Interval: interval, file: file, offset: offset}, nil
+}
+
+func (r *FollowReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		r.offset += int64(n)
+
+		if n > 0 {
+			return n, nil
+		}
+
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+
+		time.Sleep(r.Interval)
+	}
+}
+
+// reopen switches to the new file when the path was rotated, the old one is fully read at this point,
+// and rewinds the current file when it was truncated. Missing path is waited for
+func (r *FollowReader) reopen() error {
+	current, err := r.file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	latest, err := os.Stat(r.Path)
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !os.SameFile(current, latest) {
+		file, err := os.Open(r.Path)
+
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		r.file.Close()
+		r.file = file
+		r.offset = 0
+
+		return nil
+	}
+
+	if current.Size() < r.offset {
+		offset, err := r.file.Seek(0, io.SeekStart)
+
+		if err != nil {
+			return err
+		}
+
+		r.offset = offset
+	}
+
+	return nil
+}
+
+func (r *FollowReader) Close() error {
+	return r.file.Close()
+}
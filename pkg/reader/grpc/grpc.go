@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	maxLineSize = 16 * 1024 * 1024
+)
+
+// record is a single gRPC call, one JSON object per line
+type record struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// Request is base64 encoded protobuf message (string) or the message in protobuf JSON mapping (object)
+	Request   json.RawMessage   `json:"request"`
+	Authority string            `json:"authority"`
+	Peer      string            `json:"peer"`
+	Metadata  map[string]string `json:"metadata"`
+	// Code is status code number or name, Duration is in seconds
+	Code     json.RawMessage `json:"code"`
+	Duration float64         `json:"duration"`
+}
+
+// GRPCReader implements reader.LogReader interface
+type GRPCReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+}
+
+// NewReader creates new reader of gRPC call records using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader GRPCReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.InputScanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	return &reader
+}
+
+func parseInto(line []byte, entry *reader.LogEntry) error {
+	var rec record
+
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return fmt.Errorf("ERROR while parsing gRPC call record: %s", err)
+	}
+
+	if rec.Method == "" || rec.Method[0] != '/' {
+		return fmt.Errorf("gRPC call record has to have /package.Service/Method method, not '%s'", rec.Method)
+	}
+
+	// Calls are unary HTTP/2 POST requests to the method path
+	entry.Time = rec.Time
+	entry.Method = "POST"
+	entry.URL = rec.Method
+	entry.Host = rec.Authority
+	entry.ClientIP = reader.ParseClientIP(rec.Peer)
+	entry.Duration = time.Duration(rec.Duration * float64(time.Second))
+	entry.Scheme = "http"
+	entry.ContentType = "application/grpc"
+
+	if len(rec.Request) > 0 && rec.Request[0] == '"' {
+		if err := json.Unmarshal(rec.Request, &entry.Payload); err != nil {
+			return fmt.Errorf("ERROR while parsing gRPC request: %s", err)
+		}
+	} else if len(rec.Request) > 0 && !bytes.Equal(rec.Request, []byte("null")) {
+		var compact bytes.Buffer
+
+		if err := json.Compact(&compact, rec.Request); err != nil {
+			return fmt.Errorf("ERROR while parsing gRPC request: %s", err)
+		}
+
+		entry.Payload = compact.String()
+	}
+
+	if len(rec.Code) > 0 {
+		code := string(rec.Code)
+
+		if unquoted, err := strconv.Unquote(code); err == nil {
+			code = unquoted
+		}
+
+		entry.Status = reader.GRPCStatus(code)
+	}
+
+	for name, value := range rec.Metadata {
+		entry.SetHeader(name, value)
+	}
+
+	return nil
+}
+
+func (r *GRPCReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	for r.InputScanner.Scan() {
+		line := r.InputScanner.Bytes()
+
+		if len(line) == 0 {
+			continue
+		}
+
+		err := parseInto(line, &entry)
+
+		return &entry, err
+	}
+
+	err := reader.NewInputError(r.InputScanner.Err())
+
+	if err != nil {
+		return &entry, err
+	}
+
+	return &entry, io.EOF
+}
+
+// ParseLine implements reader.LineParser
+func (r *GRPCReader) ParseLine(line string, entry *reader.LogEntry) error {
+	if line == "" {
+		return reader.ErrSkipLine
+	}
+
+	return parseInto([]byte(line), entry)
+}
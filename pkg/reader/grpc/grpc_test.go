@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseInto(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		entry reader.LogEntry
+		err   bool
+	}{
+		{
+			name: "base64 request with code name",
+			line: `{"time":"2024-01-01T10:00:00Z","method":"/helloworld.Greeter/SayHello","request":"CgV3b3JsZA==","authority":"greeter:50051","peer":"10.0.0.1:5123","metadata":{"x-request-id":"abc"},"code":"NOT_FOUND","duration":0.012}`,
+			entry: reader.LogEntry{
+				Time:        time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:      "POST",
+				URL:         "/helloworld.Greeter/SayHello",
+				Payload:     "CgV3b3JsZA==",
+				Host:        "greeter:50051",
+				ClientIP:    "10.0.0.1",
+				Status:      404,
+				Duration:    12 * time.Millisecond,
+				Scheme:      "http",
+				ContentType: "application/grpc",
+				Headers:     http.Header{"X-Request-Id": []string{"abc"}},
+			},
+		},
+		{
+			name: "JSON request with code number",
+			line: `{"time":"2024-01-01T10:00:00Z","method":"/helloworld.Greeter/SayHello","request":{ "name": "world" },"code":14}`,
+			entry: reader.LogEntry{
+				Time:        time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:      "POST",
+				URL:         "/helloworld.Greeter/SayHello",
+				Payload:     `{"name":"world"}`,
+				Status:      503,
+				Scheme:      "http",
+				ContentType: "application/grpc",
+			},
+		},
+		{
+			name: "method without leading slash",
+			line: `{"time":"2024-01-01T10:00:00Z","method":"helloworld.Greeter/SayHello"}`,
+			err:  true,
+		},
+		{
+			name: "not JSON",
+			line: `/helloworld.Greeter/SayHello OK`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			err := parseInto([]byte(tt.line), &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !tt.err && !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
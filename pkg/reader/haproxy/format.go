@@ -0,0 +1,239 @@
+package haproxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	haProxyZonedTsLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// Optional syslog header in front of the log-format output, e.g. "<142>Sep 27 00:15:57 haproxy[28513]: "
+const syslogHeaderPattern = `^(?:<\d+>)?(?:[A-Z][a-z]{2} +\d+ \d\d:\d\d:\d\d (?:\S+ )?\S+?(?:\[\d+\])?: )?`
+
+// Matches log-format variables: %var, %{flags}var and %[sample fetch]
+var logFormatVarRegexp = regexp.MustCompile(`%(?:\{([^}]*)\})?([a-zA-Z]+|\[[^\]]*\])`)
+
+type formatField int
+
+const (
+	fieldIgnored formatField = iota
+	fieldTime
+	fieldZonedTime
+	fieldUnixTime
+	fieldRequest
+	fieldMethod
+	fieldURI
+	fieldPath
+	fieldQuery
+	fieldStatus
+	fieldDuration
+	fieldClientIP
+)
+
+// Variables we can extract a replay record from, everything else is matched and ignored
+var formatFields = map[string]formatField{
+	"t":   fieldTime,
+	"tr":  fieldTime,
+	"T":   fieldZonedTime,
+	"Tl":  fieldZonedTime,
+	"trg": fieldZonedTime,
+	"trl": fieldZonedTime,
+	"Ts":  fieldUnixTime,
+	"r":   fieldRequest,
+	"HM":  fieldMethod,
+	"HU":  fieldURI,
+	"HP":  fieldPath,
+	"HQ":  fieldQuery,
+	"ST":  fieldStatus,
+	"Ta":  fieldDuration,
+	"Tt":  fieldDuration,
+	"ci":  fieldClientIP,
+}
+
+// FormatReader implements reader.LogReader interface for custom HAProxy log-format strings
+type FormatReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+	Regexp       *regexp.Regexp
+	fields       []formatField
+}
+
+func hasFields(fields []formatField, wanted ...formatField) bool {
+	for _, field := range fields {
+		for _, w := range wanted {
+			if field == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compileLogFormat turns HAProxy log-format string into a regular expression
+// with one capture group per variable
+func compileLogFormat(format string) (*regexp.Regexp, []formatField, error) {
+	var pattern strings.Builder
+	var fields []formatField
+
+	pattern.WriteString(syslogHeaderPattern)
+
+	// %% is a literal percent sign, hide it from the variables regexp
+	escaped := strings.Replace(format, "%%", "\x00", -1)
+	literal := func(s string) {
+		pattern.WriteString(regexp.QuoteMeta(strings.Replace(s, "\x00", "%", -1)))
+	}
+
+	last := 0
+
+	for _, loc := range logFormatVarRegexp.FindAllStringSubmatchIndex(escaped, -1) {
+		literal(escaped[last:loc[0]])
+		last = loc[1]
+
+		var flags string
+
+		if loc[2] >= 0 {
+			flags = escaped[loc[2]:loc[3]]
+		}
+
+		name := escaped[loc[4]:loc[5]]
+		field := formatFields[name]
+		quoted := strings.Contains(flags, "+Q")
+
+		switch {
+		case quoted:
+			pattern.WriteString(`"([^"]*)"`)
+		case field == fieldRequest:
+			pattern.WriteString(`(\S+ \S+(?: \S+)?)`)
+		case field == fieldZonedTime:
+			pattern.WriteString(`(\S+ [+-]\d{4})`)
+		default:
+			pattern.WriteString(`(\S*)`)
+		}
+
+		fields = append(fields, field)
+	}
+
+	literal(escaped[last:])
+
+	if !hasFields(fields, fieldTime, fieldZonedTime, fieldUnixTime) ||
+		!(hasFields(fields, fieldRequest) || hasFields(fields, fieldMethod) && hasFields(fields, fieldURI, fieldPath)) {
+		return nil, nil, fmt.Errorf("haproxy log-format '%s' must provide date (%%t, %%tr, %%Ts...) and request (%%r or %%HM with %%HU/%%HP)", format)
+	}
+
+	re, err := regexp.Compile(pattern.String())
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("ERROR while compiling haproxy log-format '%s': %s", format, err)
+	}
+
+	return re, fields, nil
+}
+
+func (r *FormatReader) parseInto(s string, entry *reader.LogEntry) error {
+	matches := r.Regexp.FindStringSubmatch(s)
+
+	if matches == nil {
+		return fmt.Errorf("Line does not match haproxy log-format: %s", s)
+	}
+
+	var path, query string
+	var err error
+
+	for i, field := range r.fields {
+		value := matches[i+1]
+
+		switch field {
+		case fieldTime:
+			entry.Time, err = time.Parse(haProxyTsLayout, value)
+		case fieldZonedTime:
+			entry.Time, err = time.Parse(haProxyZonedTsLayout, value)
+		case fieldUnixTime:
+			var ts int64
+			ts, err = strconv.ParseInt(value, 10, 64)
+			entry.Time = time.Unix(ts, 0)
+		case fieldRequest:
+			var parsedRequest []string
+			parsedRequest, err = reader.ParseRequest(value)
+
+			if err == nil {
+				entry.Method = parsedRequest[0]
+				entry.URL = parsedRequest[1]
+			}
+		case fieldMethod:
+			entry.Method = value
+		case fieldURI:
+			entry.URL = value
+		case fieldPath:
+			path = value
+		case fieldQuery:
+			query = value
+		case fieldStatus:
+			entry.Status = reader.ParseStatus(value)
+		case fieldDuration:
+			entry.Duration = reader.ParseDuration(value, time.Millisecond)
+		case fieldClientIP:
+			entry.ClientIP = reader.ParseClientIP(value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if entry.URL == "" {
+		entry.URL = path + query
+	}
+
+	return nil
+}
+
+// NewFormatReader creates new reader for a custom haproxy log-format using provided io.Reader
+func NewFormatReader(inputReader io.Reader, format string) reader.LogReader {
+	re, fields, err := compileLogFormat(format)
+
+	reader.Must(err)
+
+	var reader FormatReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.Regexp = re
+	reader.fields = fields
+
+	return &reader
+}
+
+func (r *FormatReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	inputAvailable := r.InputScanner.Scan()
+
+	if !inputAvailable {
+		err := reader.NewInputError(r.InputScanner.Err())
+
+		if err != nil {
+			return &entry, err
+		}
+
+		return &entry, io.EOF
+	}
+
+	err := r.parseInto(r.InputScanner.Text(), &entry)
+
+	return &entry, err
+}
+
+// ParseLine implements reader.LineParser
+func (r *FormatReader) ParseLine(line string, entry *reader.LogEntry) error {
+	return r.parseInto(line, entry)
+}
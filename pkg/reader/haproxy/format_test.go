@@ -0,0 +1,164 @@
+package haproxy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestCompileLogFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		fields []formatField
+		err    string
+	}{
+		{
+			name:   "httplog",
+			format: `%ci:%cp [%tr] %ft %b/%s %TR/%Tw/%Tc/%Tr/%Ta %ST %B %CC %CS %tsc %ac/%fc/%bc/%sc/%rc %sq/%bq %{+Q}r`,
+			fields: []formatField{fieldClientIP, fieldIgnored, fieldTime, fieldIgnored, fieldIgnored, fieldIgnored,
+				fieldIgnored, fieldIgnored, fieldIgnored, fieldIgnored, fieldDuration, fieldStatus, fieldIgnored, fieldIgnored,
+				fieldIgnored, fieldIgnored, fieldIgnored, fieldIgnored, fieldIgnored, fieldIgnored, fieldIgnored,
+				fieldIgnored, fieldIgnored, fieldRequest},
+		},
+		{
+			name:   "method and path with sample fetch",
+			format: `%Ts %HM %HP%HQ %[capture.req.hdr(0)] %%`,
+			fields: []formatField{fieldUnixTime, fieldMethod, fieldPath, fieldQuery, fieldIgnored},
+		},
+		{
+			name:   "no date",
+			format: `%ci %{+Q}r`,
+			err:    "must provide date",
+		},
+		{
+			name:   "method without path",
+			format: `%t %HM %ST`,
+			err:    "must provide date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, fields, err := compileLogFormat(tt.format)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields %v, expected %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestFormatParseInto(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		entry  reader.LogEntry
+		err    bool
+	}{
+		{
+			name:   "httplog with syslog header",
+			format: `%ci:%cp [%tr] %ft %b/%s %TR/%Tw/%Tc/%Tr/%Ta %ST %B %CC %CS %tsc %ac/%fc/%bc/%sc/%rc %sq/%bq %{+Q}r`,
+			line:   `<134>Jan  1 10:00:00 lb1 haproxy[123]: 10.0.0.1:5123 [01/Jan/2024:10:00:00.250] fe be/srv1 0/0/1/2/25 200 512 - - ---- 1/1/0/0/0 0/0 "GET /a?b=1 HTTP/1.1"`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 250000000, time.UTC),
+				Method:   "GET",
+				URL:      "/a?b=1",
+				ClientIP: "10.0.0.1",
+				Status:   200,
+				Duration: 25 * time.Millisecond,
+			},
+		},
+		{
+			name:   "zoned time, method and path",
+			format: `%ci [%trl] %HM %HP%HQ %ST %Tt 100%%`,
+			line:   `[2001:db8::1]:443 [01/Jan/2024:12:00:00 +0200] DELETE /items/1?force=1 204 7 100%`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:   "DELETE",
+				URL:      "/items/1?force=1",
+				ClientIP: "2001:db8::1",
+				Status:   204,
+				Duration: 7 * time.Millisecond,
+			},
+		},
+		{
+			name:   "GMT zoned time",
+			format: `%ci [%trg] %HM %HP %ST`,
+			line:   `10.0.0.2:5000 [01/Jan/2024:10:00:00 +0000] GET /health 200`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:   "GET",
+				URL:      "/health",
+				ClientIP: "10.0.0.2",
+				Status:   200,
+			},
+		},
+		{
+			name:   "unix time and URI",
+			format: `%Ts %HM %HU %ST`,
+			line:   `1704103200 PUT /upload -`,
+			entry: reader.LogEntry{
+				Time:   time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method: "PUT",
+				URL:    "/upload",
+			},
+		},
+		{
+			name:   "line of other format",
+			format: `%Ts %HM %HU %ST`,
+			line:   `GET /upload 200`,
+			err:    true,
+		},
+		{
+			name:   "invalid unix time",
+			format: `%Ts %HM %HU %ST`,
+			line:   `now PUT /upload 200`,
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewFormatReader(strings.NewReader(""), tt.format).(*FormatReader)
+
+			var entry reader.LogEntry
+
+			err := r.parseInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if tt.err {
+				return
+			}
+
+			if !entry.Time.Equal(tt.entry.Time) {
+				t.Errorf("time %s, expected %s", entry.Time, tt.entry.Time)
+			}
+
+			entry.Time = tt.entry.Time
+
+			if !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
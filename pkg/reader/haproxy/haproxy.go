@@ -56,6 +56,19 @@ func parseStringInto(s string, entry *reader.LogEntry) error {
 	entry.URL = parsedRequest[1]
 	entry.Time = parseHaproxyTime(dateString)
 
+	// httplog: client_ip:port [date], the client is the last field before the date
+	if fields := strings.Fields(strings.TrimSuffix(s[:dateStartI], "[")); len(fields) > 0 {
+		entry.ClientIP = reader.ParseClientIP(fields[len(fields)-1])
+	}
+
+	// httplog: [date] frontend backend/server timers status ..., the last timer is the total time (Ta or Tt)
+	if fields := strings.Fields(s[dateEndI+1:]); len(fields) > 3 {
+		entry.Status = reader.ParseStatus(fields[3])
+
+		timers := strings.Split(fields[2], "/")
+		entry.Duration = reader.ParseDuration(timers[len(timers)-1], time.Millisecond)
+	}
+
 	return nil
 }
 
@@ -80,7 +93,7 @@ func (r *HaproxyReader) Read() (*reader.LogEntry, error) {
 		return &entry, io.EOF
 	}
 
-	err := r.InputScanner.Err()
+	err := reader.NewInputError(r.InputScanner.Err())
 
 	if err != nil {
 		return &entry, err
@@ -88,3 +101,10 @@ func (r *HaproxyReader) Read() (*reader.LogEntry, error) {
 
 	return &entry, nil
 }
+
+// ParseLine implements reader.LineParser, lines which can not be parsed are not reported like in Read
+func (r *HaproxyReader) ParseLine(line string, entry *reader.LogEntry) error {
+	parseStringInto(line, entry)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package haproxy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseStringInto(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		entry reader.LogEntry
+		err   bool
+	}{
+		{
+			name: "httplog with syslog header",
+			line: `<142>Sep 27 00:15:57 haproxy[28513]: 67.188.214.167:64531 [27/Sep/2013:00:15:43.494] frontend~ test/10.127.57.177-10000 449/0/0/13531/13980 200 13824 - - ---- 6/6/0/1/0 0/0 "GET /index.html?a=1 HTTP/1.1"`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2013, time.September, 27, 0, 15, 43, 494000000, time.UTC),
+				Method:   "GET",
+				URL:      "/index.html?a=1",
+				ClientIP: "67.188.214.167",
+				Status:   200,
+				Duration: 13980 * time.Millisecond,
+			},
+		},
+		{
+			name: "httplog without syslog header",
+			line: `10.0.0.1:5123 [01/Jan/2024:10:00:00.000] fe be/srv1 0/0/1/2/3 503 212 - - sC-- 1/1/0/0/0 0/0 "POST /api HTTP/1.1"`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:   "POST",
+				URL:      "/api",
+				ClientIP: "10.0.0.1",
+				Status:   503,
+				Duration: 3 * time.Millisecond,
+			},
+		},
+		{
+			name: "no date",
+			line: `10.0.0.1:5123 fe be/srv1 0/0/1/2/3 200 212 "GET / HTTP/1.1"`,
+			err:  true,
+		},
+		{
+			name: "incomplete request",
+			line: `10.0.0.1:5123 [01/Jan/2024:10:00:00.000] fe be/srv1 0/0/1/2/3 400 0 - - CR-- 1/1/0/0/0 0/0 "<BADREQ>"`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			err := parseStringInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !tt.err && !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
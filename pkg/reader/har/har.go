@@ -0,0 +1,196 @@
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// document is the part of HTTP Archive the entries are read from
+type document struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total time of the request in milliseconds
+	Time    float64 `json:"time"`
+	Request struct {
+		Method   string       `json:"method"`
+		URL      string       `json:"url"`
+		Headers  []harHeader  `json:"headers"`
+		PostData *harPostData `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status int `json:"status"`
+	} `json:"response"`
+	ResourceType string `json:"_resourceType"`
+	// WebSocketMessages are recorded by Chrome DevTools
+	WebSocketMessages []harMessage `json:"_webSocketMessages"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harMessage struct {
+	Type string `json:"type"`
+	// Time is in unix seconds
+	Time float64 `json:"time"`
+	// Opcode 1 is a text message, 2 a base64 encoded binary one
+	Opcode int    `json:"opcode"`
+	Data   string `json:"data"`
+}
+
+// HARReader implements reader.LogReader interface, the whole archive is read on the first Read
+type HARReader struct {
+	InputReader io.Reader
+	entries     []harEntry
+	loaded      bool
+}
+
+// NewReader creates new reader of HTTP Archive (HAR) using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	return &HARReader{InputReader: inputReader}
+}
+
+func (r *HARReader) load() error {
+	r.loaded = true
+
+	var doc document
+
+	if err := json.NewDecoder(r.InputReader).Decode(&doc); err != nil {
+		return fmt.Errorf("ERROR while parsing HAR: %s", err)
+	}
+
+	// Browsers write entries in the order they were started, not always strictly
+	sort.SliceStable(doc.Log.Entries, func(i, j int) bool {
+		return doc.Log.Entries[i].StartedDateTime.Before(doc.Log.Entries[j].StartedDateTime)
+	})
+
+	r.entries = doc.Log.Entries
+
+	return nil
+}
+
+func (r *HARReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	if !r.loaded {
+		if err := r.load(); err != nil {
+			return &entry, reader.NewInputError(err)
+		}
+	}
+
+	for len(r.entries) > 0 {
+		har := r.entries[0]
+		r.entries = r.entries[1:]
+
+		// data:, blob: and similar URLs were not requested over network
+		if !strings.Contains(har.Request.URL, "://") || strings.HasPrefix(har.Request.URL, "chrome-extension:") {
+			continue
+		}
+
+		err := parseInto(&har, &entry)
+
+		return &entry, err
+	}
+
+	return &entry, io.EOF
+}
+
+func parseInto(har *harEntry, entry *reader.LogEntry) error {
+	u, err := url.Parse(har.Request.URL)
+
+	if err != nil {
+		return err
+	}
+
+	entry.Time = har.StartedDateTime
+	entry.Method = har.Request.Method
+	entry.URL = u.RequestURI()
+	entry.Host = u.Host
+	entry.Status = har.Response.Status
+
+	switch u.Scheme {
+	case "ws":
+		entry.Scheme = "http"
+	case "wss":
+		entry.Scheme = "https"
+	default:
+		entry.Scheme = u.Scheme
+	}
+
+	if har.Time > 0 {
+		entry.Duration = time.Duration(har.Time * float64(time.Millisecond))
+	}
+
+	header := make(http.Header)
+
+	for _, h := range har.Request.Headers {
+		switch {
+		case strings.EqualFold(h.Name, "host"), h.Name == ":authority":
+			entry.Host = h.Value
+		case strings.HasPrefix(h.Name, ":"):
+			// Other HTTP/2 pseudo headers are in the URL already
+		default:
+			header.Add(h.Name, h.Value)
+		}
+	}
+
+	entry.SetRequestHeaders(header)
+
+	if har.Request.PostData != nil {
+		entry.Payload = har.Request.PostData.Text
+
+		if har.Request.PostData.MimeType != "" {
+			entry.ContentType = har.Request.PostData.MimeType
+		}
+	}
+
+	entry.WebSocket = har.Response.Status == http.StatusSwitchingProtocols || har.ResourceType == "websocket" || len(har.WebSocketMessages) > 0
+
+	for _, m := range har.WebSocketMessages {
+		sec, frac := math.Modf(m.Time)
+		// Times are floats, rounding drops their noise
+		offset := time.Unix(int64(sec), int64(frac*1e9)).Sub(har.StartedDateTime).Round(time.Microsecond)
+
+		if offset < 0 {
+			offset = 0
+		}
+
+		msg := reader.WebSocketMessage{Offset: offset, Sent: m.Type == "send", Data: m.Data}
+
+		if m.Opcode == 2 {
+			data, err := base64.StdEncoding.DecodeString(m.Data)
+
+			if err != nil {
+				return fmt.Errorf("ERROR while decoding binary WebSocket message: %s", err)
+			}
+
+			msg.Binary = true
+			msg.Data = string(data)
+		}
+
+		entry.Messages = append(entry.Messages, msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package har
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const archive = `{"log":{"entries":[
+{"startedDateTime":"2024-01-01T10:00:01.000Z","time":120.5,"request":{"method":"POST","url":"https://example.com/api?a=1","headers":[{"name":":authority","value":"api.example.com"},{"name":":path","value":"/api?a=1"},{"name":"User-Agent","value":"Mozilla/5.0"},{"name":"X-Request-Id","value":"abc"}],"postData":{"mimeType":"application/json","text":"{\"a\":1}"}},"response":{"status":201}},
+{"startedDateTime":"2024-01-01T10:00:00.000Z","time":0,"request":{"method":"GET","url":"data:image/png;base64,AAAA","headers":[]},"response":{"status":200}},
+{"startedDateTime":"2024-01-01T10:00:00.500Z","time":-1,"request":{"method":"GET","url":"wss://example.com/ws","headers":[{"name":"Host","value":"example.com"}]},"response":{"status":101},"_webSocketMessages":[{"type":"send","time":1704103200.75,"opcode":1,"data":"hi"},{"type":"receive","time":1704103201.5,"opcode":2,"data":"AAE="}]}
+]}}`
+
+func TestRead(t *testing.T) {
+	expected := []reader.LogEntry{
+		// Entries are sorted by start, data: URLs are skipped
+		{
+			Time:      time.Date(2024, time.January, 1, 10, 0, 0, 500000000, time.UTC),
+			Method:    "GET",
+			URL:       "/ws",
+			Host:      "example.com",
+			Scheme:    "https",
+			Status:    101,
+			WebSocket: true,
+			Messages: []reader.WebSocketMessage{
+				{Offset: 250 * time.Millisecond, Sent: true, Data: "hi"},
+				{Offset: time.Second, Binary: true, Data: "\x00\x01"},
+			},
+		},
+		{
+			Time:        time.Date(2024, time.January, 1, 10, 0, 1, 0, time.UTC),
+			Method:      "POST",
+			URL:         "/api?a=1",
+			Host:        "api.example.com",
+			Scheme:      "https",
+			Status:      201,
+			Duration:    120500 * time.Microsecond,
+			UA:          "Mozilla/5.0",
+			Headers:     http.Header{"X-Request-Id": []string{"abc"}},
+			Payload:     `{"a":1}`,
+			ContentType: "application/json",
+		},
+	}
+
+	r := NewReader(strings.NewReader(archive))
+
+	for i, want := range expected {
+		entry, err := r.Read()
+
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(*entry, want) {
+			t.Errorf("entry %d: %+v, expected %+v", i, *entry, want)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestReadInvalidArchive(t *testing.T) {
+	_, err := NewReader(strings.NewReader(`{"log":`)).Read()
+
+	if !reader.IsInputError(err) {
+		t.Errorf("expected input error, got %v", err)
+	}
+}
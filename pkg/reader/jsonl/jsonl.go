@@ -0,0 +1,207 @@
+package jsonl
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	maxLineSize = 16 * 1024 * 1024
+)
+
+// record is a single line of the normalized log, written by the convert command
+type record struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	URL     string    `json:"url"`
+	Payload string    `json:"payload,omitempty"`
+	UA      string    `json:"ua,omitempty"`
+	Referer string    `json:"referer,omitempty"`
+	Host    string    `json:"host,omitempty"`
+	Status  int       `json:"status,omitempty"`
+	// Duration is in seconds
+	Duration float64 `json:"duration,omitempty"`
+	Client   string  `json:"client,omitempty"`
+	// Headers are other request headers, values of repeated ones are joined with commas
+	Headers     map[string]string `json:"headers,omitempty"`
+	Scheme      string            `json:"scheme,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	// WebSocket is set for entries opening WebSocket sessions
+	WebSocket bool      `json:"websocket,omitempty"`
+	Messages  []message `json:"messages,omitempty"`
+}
+
+// message is a WebSocket message, sent by the client or received, binary data is base64 encoded
+type message struct {
+	// Offset is in seconds since the session was opened
+	Offset float64 `json:"offset"`
+	Type   string  `json:"type"`
+	Binary bool    `json:"binary,omitempty"`
+	Data   string  `json:"data"`
+}
+
+// JSONLReader implements reader.LogReader interface
+type JSONLReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+}
+
+// NewReader creates new reader of the normalized JSON lines log using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader JSONLReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.InputScanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	return &reader
+}
+
+func parseInto(line []byte, entry *reader.LogEntry) error {
+	var rec record
+
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return fmt.Errorf("ERROR while parsing json line: %s", err)
+	}
+
+	entry.Time = rec.Time
+	entry.Method = rec.Method
+	entry.URL = rec.URL
+	entry.Payload = rec.Payload
+	entry.UA = rec.UA
+	entry.Referer = rec.Referer
+	entry.Host = rec.Host
+	entry.Status = rec.Status
+	entry.Duration = time.Duration(rec.Duration * float64(time.Second))
+	entry.ClientIP = rec.Client
+	entry.Scheme = rec.Scheme
+	entry.ContentType = rec.ContentType
+
+	for name, value := range rec.Headers {
+		entry.SetHeader(name, value)
+	}
+
+	entry.WebSocket = rec.WebSocket
+
+	for _, m := range rec.Messages {
+		msg := reader.WebSocketMessage{
+			Offset: time.Duration(m.Offset * float64(time.Second)),
+			Sent:   m.Type == "send",
+			Binary: m.Binary,
+			Data:   m.Data,
+		}
+
+		if m.Binary {
+			data, err := base64.StdEncoding.DecodeString(m.Data)
+
+			if err != nil {
+				return fmt.Errorf("ERROR while decoding binary WebSocket message: %s", err)
+			}
+
+			msg.Data = string(data)
+		}
+
+		entry.Messages = append(entry.Messages, msg)
+	}
+
+	return nil
+}
+
+func (r *JSONLReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	for r.InputScanner.Scan() {
+		line := r.InputScanner.Bytes()
+
+		if len(line) == 0 {
+			continue
+		}
+
+		err := parseInto(line, &entry)
+
+		return &entry, err
+	}
+
+	err := reader.NewInputError(r.InputScanner.Err())
+
+	if err != nil {
+		return &entry, err
+	}
+
+	return &entry, io.EOF
+}
+
+// ParseLine implements reader.LineParser
+func (r *JSONLReader) ParseLine(line string, entry *reader.LogEntry) error {
+	if line == "" {
+		return reader.ErrSkipLine
+	}
+
+	return parseInto([]byte(line), entry)
+}
+
+// Writer writes entries in the normalized JSON lines format
+type Writer struct {
+	encoder *json.Encoder
+}
+
+// NewWriter creates writer of the normalized JSON lines log
+func NewWriter(w io.Writer) *Writer {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &Writer{encoder: encoder}
+}
+
+func (w *Writer) Write(entry *reader.LogEntry) error {
+	var headers map[string]string
+
+	if len(entry.Headers) > 0 {
+		headers = make(map[string]string, len(entry.Headers))
+
+		for name, values := range entry.Headers {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	var messages []message
+
+	for _, msg := range entry.Messages {
+		m := message{Offset: msg.Offset.Seconds(), Type: "receive", Binary: msg.Binary, Data: msg.Data}
+
+		if msg.Sent {
+			m.Type = "send"
+		}
+
+		if msg.Binary {
+			m.Data = base64.StdEncoding.EncodeToString([]byte(msg.Data))
+		}
+
+		messages = append(messages, m)
+	}
+
+	return w.encoder.Encode(record{
+		Time:        entry.Time,
+		Method:      entry.Method,
+		URL:         entry.URL,
+		Payload:     entry.Payload,
+		UA:          entry.UA,
+		Referer:     entry.Referer,
+		Host:        entry.Host,
+		Status:      entry.Status,
+		Duration:    entry.Duration.Seconds(),
+		Client:      entry.ClientIP,
+		Headers:     headers,
+		Scheme:      entry.Scheme,
+		ContentType: entry.ContentType,
+		WebSocket:   entry.WebSocket,
+		Messages:    messages,
+	})
+}
@@ -0,0 +1,116 @@
+package jsonl
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestRead(t *testing.T) {
+	input := strings.Join([]string{
+		`{"time":"2024-01-01T10:00:00.5Z","method":"POST","url":"/api","payload":"a=1","ua":"curl/8.0","referer":"http://example.com/","host":"example.com","status":201,"duration":0.25,"client":"10.0.0.1","headers":{"X-Request-Id":"abc","X-Empty":""},"scheme":"https","content_type":"application/x-www-form-urlencoded"}`,
+		``,
+		`{"time":"2024-01-01T10:00:01Z","method":"GET","url":"/b"}`,
+		`{"time":"2024-01-01T10:00:02Z","method":"GET","url":"/ws","websocket":true,"messages":[{"offset":0.5,"type":"send","data":"hi"},{"offset":1,"type":"receive","binary":true,"data":"AAE="}]}`,
+		`{"time":"2024-01-01T10:00:03Z","method":"GET","url":"/ws","messages":[{"type":"send","binary":true,"data":"%%"}]}`,
+		`GET /api`,
+	}, "\n")
+
+	expected := []reader.LogEntry{
+		{
+			Time:        time.Date(2024, time.January, 1, 10, 0, 0, 500000000, time.UTC),
+			Method:      "POST",
+			URL:         "/api",
+			Payload:     "a=1",
+			UA:          "curl/8.0",
+			Referer:     "http://example.com/",
+			Host:        "example.com",
+			Status:      201,
+			Duration:    250 * time.Millisecond,
+			ClientIP:    "10.0.0.1",
+			Headers:     http.Header{"X-Request-Id": []string{"abc"}},
+			Scheme:      "https",
+			ContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			Time:   time.Date(2024, time.January, 1, 10, 0, 1, 0, time.UTC),
+			Method: "GET",
+			URL:    "/b",
+		},
+		{
+			Time:      time.Date(2024, time.January, 1, 10, 0, 2, 0, time.UTC),
+			Method:    "GET",
+			URL:       "/ws",
+			WebSocket: true,
+			Messages: []reader.WebSocketMessage{
+				{Offset: 500 * time.Millisecond, Sent: true, Data: "hi"},
+				{Offset: time.Second, Binary: true, Data: "\x00\x01"},
+			},
+		},
+	}
+
+	r := NewReader(strings.NewReader(input))
+
+	for i, want := range expected {
+		entry, err := r.Read()
+
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(*entry, want) {
+			t.Errorf("entry %d: %+v, expected %+v", i, *entry, want)
+		}
+	}
+
+	if _, err := r.Read(); err == nil || err == io.EOF {
+		t.Errorf("expected error of the invalid binary message, got %v", err)
+	}
+
+	if _, err := r.Read(); err == nil || err == io.EOF {
+		t.Errorf("expected error of the line which is not JSON, got %v", err)
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	entry := reader.LogEntry{
+		Time:    time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+		Method:  "POST",
+		URL:     "/api?q=<a>",
+		Payload: `{"a":1}`,
+		Host:    "example.com",
+		Status:  201,
+	}
+
+	var buf bytes.Buffer
+
+	if err := NewWriter(&buf).Write(&entry); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"time":"2024-01-01T10:00:00Z","method":"POST","url":"/api?q=<a>","payload":"{\"a\":1}","host":"example.com","status":201}` + "\n"
+
+	if buf.String() != expected {
+		t.Errorf("wrote %s, expected %s", buf.String(), expected)
+	}
+
+	read, err := NewReader(&buf).Read()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(*read, entry) {
+		t.Errorf("read back %+v, expected %+v", *read, entry)
+	}
+}
@@ -0,0 +1,80 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// LoopReader replays entries of the underlying reader multiple times.
+// Entries are buffered in memory during the first pass, timestamps of the
+// following passes are shifted so that time keeps moving forward, a pass starts
+// the mean gap between entries after the last entry of the previous one.
+type LoopReader struct {
+	Reader  LogReader
+	Times   int
+	entries []*LogEntry
+	pass    int
+	pos     int
+	shift   time.Duration
+}
+
+// NewLoopReader creates reader repeating entries of provided reader given number of times, 0 means forever
+func NewLoopReader(r LogReader, times int) LogReader {
+	return &LoopReader{Reader: r, Times: times}
+}
+
+func (r *LoopReader) Read() (*LogEntry, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *LoopReader) ReadContext(ctx context.Context) (*LogEntry, error) {
+	if r.pass == 0 {
+		entry, err := ReadContext(ctx, r.Reader)
+
+		if err != io.EOF {
+			if err == nil {
+				buffered := *entry
+				r.entries = append(r.entries, &buffered)
+			}
+
+			return entry, err
+		}
+
+		r.pass = 1
+		r.pos = len(r.entries)
+	}
+
+	// Buffered passes never block, but looping forever has to stop somewhere
+	if err := ctx.Err(); err != nil {
+		return &LogEntry{}, err
+	}
+
+	if r.pos >= len(r.entries) {
+		if len(r.entries) == 0 || (r.Times > 0 && r.pass >= r.Times) {
+			return &LogEntry{}, io.EOF
+		}
+
+		r.pass++
+		r.pos = 0
+		r.shift += r.passDuration()
+	}
+
+	entry := *r.entries[r.pos]
+	entry.Time = entry.Time.Add(r.shift)
+	r.pos++
+
+	return &entry, nil
+}
+
+// passDuration is the time span of the entries plus the mean gap between them, the first entry
+// of the next pass is not sent at the same moment as the last one
+func (r *LoopReader) passDuration() time.Duration {
+	span := r.entries[len(r.entries)-1].Time.Sub(r.entries[0].Time)
+
+	if len(r.entries) < 2 {
+		return span
+	}
+
+	return span + span/time.Duration(len(r.entries)-1)
+}
@@ -0,0 +1,76 @@
+package reader
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoopReader(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		offsets []time.Duration
+		times   int
+		// expected are offsets of the entries of all passes
+		expected []time.Duration
+	}{
+		{
+			name:     "mean gap between passes",
+			offsets:  []time.Duration{0, time.Second, 4 * time.Second},
+			times:    3,
+			expected: []time.Duration{0, time.Second, 4 * time.Second, 6 * time.Second, 7 * time.Second, 10 * time.Second, 12 * time.Second, 13 * time.Second, 16 * time.Second},
+		},
+		{
+			name:     "single entry",
+			offsets:  []time.Duration{0},
+			times:    2,
+			expected: []time.Duration{0, 0},
+		},
+		{
+			name:     "once",
+			offsets:  []time.Duration{0, time.Second},
+			times:    1,
+			expected: []time.Duration{0, time.Second},
+		},
+		{
+			name:    "empty input",
+			offsets: nil,
+			times:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []*LogEntry
+
+			for _, offset := range tt.offsets {
+				entries = append(entries, &LogEntry{Time: start.Add(offset), URL: "/"})
+			}
+
+			r := NewLoopReader(NewSliceReader(entries), tt.times)
+
+			var offsets []time.Duration
+
+			for {
+				entry, err := r.Read()
+
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				offsets = append(offsets, entry.Time.Sub(start))
+			}
+
+			if !reflect.DeepEqual(offsets, tt.expected) {
+				t.Errorf("offsets %v, expected %v", offsets, tt.expected)
+			}
+		})
+	}
+}
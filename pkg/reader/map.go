@@ -0,0 +1,30 @@
+package reader
+
+import (
+	"context"
+)
+
+// MapReader modifies entries of the underlying reader with the Map function before returning them
+type MapReader struct {
+	Reader LogReader
+	Map    func(*LogEntry)
+}
+
+// NewMapReader creates reader returning entries of provided reader modified by the function
+func NewMapReader(r LogReader, mapper func(*LogEntry)) LogReader {
+	return &MapReader{Reader: r, Map: mapper}
+}
+
+func (r *MapReader) Read() (*LogEntry, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *MapReader) ReadContext(ctx context.Context) (*LogEntry, error) {
+	entry, err := ReadContext(ctx, r.Reader)
+
+	if entry != nil && err == nil {
+		r.Map(entry)
+	}
+
+	return entry, err
+}
@@ -0,0 +1,90 @@
+package reader
+
+import (
+	"container/heap"
+	"context"
+	"io"
+)
+
+// mergeHead is the next entry of one of the merged readers
+type mergeHead struct {
+	entry  *LogEntry
+	reader LogReader
+	index  int
+}
+
+// mergeHeap orders heads by time, entries with the same time keep the order of the readers
+type mergeHeap []*mergeHead
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].entry.Time.Equal(h[j].entry.Time) {
+		return h[i].index < h[j].index
+	}
+
+	return h[i].entry.Time.Before(h[j].entry.Time)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeHead)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return head
+}
+
+// MergeReader interleaves entries of several readers in timestamp order (k-way merge),
+// entries of each reader are expected to be ordered already as they are in log files
+type MergeReader struct {
+	heads   mergeHeap
+	pending []*mergeHead
+}
+
+// NewMergeReader creates reader returning entries of all readers ordered by time
+func NewMergeReader(readers ...LogReader) LogReader {
+	var r MergeReader
+
+	for i, rdr := range readers {
+		r.pending = append(r.pending, &mergeHead{reader: rdr, index: i})
+	}
+
+	return &r
+}
+
+func (r *MergeReader) Read() (*LogEntry, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *MergeReader) ReadContext(ctx context.Context) (*LogEntry, error) {
+	// Readers without a head have to be read before the earliest entry is known,
+	// a reader which failed to parse a line stays pending and is read again next time
+	for len(r.pending) > 0 {
+		head := r.pending[0]
+		entry, err := ReadContext(ctx, head.reader)
+
+		if err == io.EOF {
+			r.pending = r.pending[1:]
+			continue
+		} else if err != nil {
+			return entry, err
+		}
+
+		head.entry = entry
+		heap.Push(&r.heads, head)
+		r.pending = r.pending[1:]
+	}
+
+	if len(r.heads) == 0 {
+		return &LogEntry{}, io.EOF
+	}
+
+	head := heap.Pop(&r.heads).(*mergeHead)
+	r.pending = append(r.pending, head)
+
+	return head.entry, nil
+}
@@ -0,0 +1,182 @@
+package mysqlslow
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	maxLineSize = 16 * 1024 * 1024
+	// 5.6 and older write two digit year and do not pad the hour (140101  9:00:00)
+	oldTimeLayout = "060102 15:04:05"
+)
+
+var userHostRegexp = regexp.MustCompile(`^# User@Host: .*@\s*(\S*)\s*\[([^\]]*)\]`)
+var queryTimeRegexp = regexp.MustCompile(`Query_time: ([0-9.]+)`)
+
+// errnoRegexp matches errors of Percona Server (Last_errno) and of log_slow_extra of MySQL 8 (Errno)
+var errnoRegexp = regexp.MustCompile(`\b(?:Last_errno|Errno): (\d+)`)
+
+// MySQLSlowReader implements reader.LogReader interface, statements are entries with the verb (SELECT, UPDATE...)
+// as method, /database as URL and the statement as payload
+type MySQLSlowReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+	// database is the default database of the statements, set by use statements
+	database string
+	// next is the line read ahead, the header of the next entry
+	next    string
+	hasNext bool
+}
+
+// NewReader creates new reader of MySQL slow query log using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader MySQLSlowReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.InputScanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	return &reader
+}
+
+func (r *MySQLSlowReader) scan() (string, bool) {
+	if r.hasNext {
+		r.hasNext = false
+		return r.next, true
+	}
+
+	if !r.InputScanner.Scan() {
+		return "", false
+	}
+
+	return r.InputScanner.Text(), true
+}
+
+func (r *MySQLSlowReader) unscan(line string) {
+	r.next, r.hasNext = line, true
+}
+
+// serverHeader tells whether the line is of the header written when the server (re)opens the log
+func serverHeader(line string) bool {
+	return strings.Contains(line, ", Version: ") || strings.HasPrefix(line, "Tcp port: ") || strings.HasPrefix(line, "Time                 Id Command")
+}
+
+// Read returns the next statement, entries without one (e.g. administrator commands) are skipped
+func (r *MySQLSlowReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+	var logged, timestamp time.Time
+	var statement []string
+
+	for {
+		line, ok := r.scan()
+
+		if !ok {
+			break
+		}
+
+		if strings.HasPrefix(line, "#") {
+			// Header of the next entry
+			if len(statement) > 0 {
+				r.unscan(line)
+				break
+			}
+
+			r.parseHeader(line, &entry, &logged)
+
+			continue
+		}
+
+		if serverHeader(line) {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+
+		switch {
+		case len(statement) == 0 && strings.HasPrefix(lower, "use ") && strings.HasSuffix(trimmed, ";"):
+			r.database = strings.Trim(strings.TrimSuffix(trimmed[4:], ";"), " `")
+		case len(statement) == 0 && strings.HasPrefix(lower, "set timestamp="):
+			sec, err := strconv.ParseInt(strings.TrimSuffix(trimmed[len("set timestamp="):], ";"), 10, 64)
+
+			if err == nil {
+				timestamp = time.Unix(sec, 0).UTC()
+			}
+		case len(statement) == 0 && trimmed == "":
+		default:
+			statement = append(statement, line)
+		}
+	}
+
+	if err := reader.NewInputError(r.InputScanner.Err()); err != nil {
+		return &entry, err
+	}
+
+	if len(statement) == 0 {
+		return &entry, io.EOF
+	}
+
+	// The time of the header is when the statement ended, SET timestamp is when it started (in seconds)
+	if !logged.IsZero() {
+		entry.Time = logged.Add(-entry.Duration)
+	} else {
+		entry.Time = timestamp
+	}
+
+	entry.Payload = strings.TrimSuffix(strings.TrimSpace(strings.Join(statement, "\n")), ";")
+	entry.Method = reader.StatementVerb(entry.Payload)
+	entry.URL = "/" + r.database
+
+	return &entry, nil
+}
+
+// parseHeader picks time, client, duration and error of the entry up from # header lines
+func (r *MySQLSlowReader) parseHeader(line string, entry *reader.LogEntry, logged *time.Time) {
+	if value, ok := strings.CutPrefix(line, "# Time: "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value)); err == nil {
+			*logged = t
+			return
+		}
+
+		if fields := strings.Fields(value); len(fields) == 2 {
+			if len(fields[1]) == len("9:00:00") {
+				fields[1] = "0" + fields[1]
+			}
+
+			if t, err := time.Parse(oldTimeLayout, fields[0]+" "+fields[1]); err == nil {
+				*logged = t
+			}
+		}
+
+		return
+	}
+
+	if m := userHostRegexp.FindStringSubmatch(line); m != nil {
+		entry.ClientIP = m[2]
+
+		if entry.ClientIP == "" {
+			entry.ClientIP = m[1]
+		}
+
+		return
+	}
+
+	if m := queryTimeRegexp.FindStringSubmatch(line); m != nil {
+		entry.Duration = reader.ParseDuration(m[1], time.Second)
+	}
+
+	if m := errnoRegexp.FindStringSubmatch(line); m != nil {
+		entry.Status = 200
+
+		if m[1] != "0" {
+			entry.Status = 500
+		}
+	}
+}
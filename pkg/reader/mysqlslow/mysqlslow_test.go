@@ -0,0 +1,109 @@
+package mysqlslow
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const slowLog = `/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:
+Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock
+Time                 Id Command    Argument
+# Time: 2024-01-01T10:00:00.500000Z
+# User@Host: app[app] @ web1 [10.0.0.5]  Id:    12
+# Query_time: 0.200000  Lock_time: 0.000010 Rows_sent: 2  Rows_examined: 100
+use shop;
+SET timestamp=1704103200;
+SELECT *
+  FROM users
+ WHERE id = 1;
+# Time: 2024-01-01T10:00:01.000000Z
+# User@Host: app[app] @  [10.0.0.6]  Id:    13
+# Query_time: 0.100000  Lock_time: 0.000010 Rows_sent: 0  Rows_examined: 1 Errno: 1062
+SET timestamp=1704103200;
+UPDATE users SET name = 'x' WHERE id = 1;
+# Time: 2024-01-01T10:00:01.200000Z
+# User@Host: app[app] @ web1 [10.0.0.5]  Id:    12
+# Query_time: 0.000100  Lock_time: 0.000000 Rows_sent: 0  Rows_examined: 0
+SET timestamp=1704103201;
+# administrator command: Quit;
+# Time: 240101  9:00:02
+# User@Host: rep[rep] @ localhost []
+# Query_time: 1.5  Lock_time: 0.0 Rows_sent: 0  Rows_examined: 0 Last_errno: 0
+use stats;
+SET timestamp=1704103202;
+/* report */ select count(*) from missing;
+# User@Host: rep[rep] @ localhost []
+# Query_time: 0.5  Lock_time: 0.0 Rows_sent: 0  Rows_examined: 0
+SET timestamp=1704103203;
+SELECT 1;
+`
+
+func TestRead(t *testing.T) {
+	expected := []reader.LogEntry{
+		{
+			Time:     time.Date(2024, time.January, 1, 10, 0, 0, 300000000, time.UTC),
+			Method:   "SELECT",
+			URL:      "/shop",
+			Payload:  "SELECT *\n  FROM users\n WHERE id = 1",
+			ClientIP: "10.0.0.5",
+			Duration: 200 * time.Millisecond,
+		},
+		{
+			Time:     time.Date(2024, time.January, 1, 10, 0, 0, 900000000, time.UTC),
+			Method:   "UPDATE",
+			URL:      "/shop",
+			Payload:  "UPDATE users SET name = 'x' WHERE id = 1",
+			ClientIP: "10.0.0.6",
+			Duration: 100 * time.Millisecond,
+			Status:   500,
+		},
+		// The administrator command has no statement, its header is overwritten by the next entry
+		{
+			Time:     time.Date(2024, time.January, 1, 9, 0, 0, 500000000, time.UTC),
+			Method:   "SELECT",
+			URL:      "/stats",
+			Payload:  "/* report */ select count(*) from missing",
+			ClientIP: "localhost",
+			Duration: 1500 * time.Millisecond,
+			Status:   200,
+		},
+		// Without # Time: the statement starts at SET timestamp
+		{
+			Time:     time.Unix(1704103203, 0).UTC(),
+			Method:   "SELECT",
+			URL:      "/stats",
+			Payload:  "SELECT 1",
+			ClientIP: "localhost",
+			Duration: 500 * time.Millisecond,
+		},
+	}
+
+	r := NewReader(strings.NewReader(slowLog))
+
+	for i, want := range expected {
+		entry, err := r.Read()
+
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+
+		if !entry.Time.Equal(want.Time) {
+			t.Errorf("entry %d: time %s, expected %s", i, entry.Time, want.Time)
+		}
+
+		entry.Time = want.Time
+
+		if !reflect.DeepEqual(*entry, want) {
+			t.Errorf("entry %d: %+v, expected %+v", i, *entry, want)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
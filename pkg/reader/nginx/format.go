@@ -0,0 +1,255 @@
+package nginx
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Fields of the entry looked up in the log line
+const (
+	fieldTimeLocal = iota
+	fieldRequest
+	fieldUserAgent
+	fieldStatus
+	fieldRequestTime
+	fieldRemoteAddr
+	fieldReferer
+	fieldScheme
+	fieldHost
+	fieldContentType
+	fieldRequestBody
+	fieldCount
+)
+
+var fieldNames = [fieldCount]string{"time_local", "request", "http_user_agent", "status", "request_time", "remote_addr",
+	"http_referer", "scheme", "host", "content_type", "request_body"}
+
+// fieldAliases are other variables with the same value as the field
+var fieldAliases = map[string]int{"http_host": fieldHost, "http_content_type": fieldContentType}
+
+// formatVar is a variable of the log format and the literal text following it
+type formatVar struct {
+	name    string
+	literal string
+	// field is index into fields of the entry, -1 for variables which are not used
+	field int
+	// header is index into headers of the format for other $http_ variables, -1 for the rest
+	header int
+}
+
+// logFormat is nginx log_format precompiled into literals and variables so that lines are matched
+// without regexp: a variable spans till the first character of the literal following it,
+// the last one till the end of line without spaces, the same way gonx patterns matched
+type logFormat struct {
+	prefix string
+	vars   []formatVar
+	// headers are names of request headers logged with $http_ variables
+	headers []string
+}
+
+func isVarChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// compileFormat splits the log format into the leading literal and variables, formats without
+// $time_local or $request are rejected
+func compileFormat(format string) (*logFormat, error) {
+	format = strings.Trim(format, " ")
+
+	var f logFormat
+
+	i := strings.IndexByte(format, '$')
+
+	if i < 0 {
+		return nil, fmt.Errorf("Log format '%s' has no variables", format)
+	}
+
+	f.prefix = format[:i]
+
+	for i < len(format) {
+		// format[i] is '$'
+		end := i + 1
+
+		for end < len(format) && isVarChar(format[end]) {
+			end++
+		}
+
+		if end == i+1 {
+			return nil, fmt.Errorf("Missing variable name at %d in log format '%s'", i, format)
+		}
+
+		next := strings.IndexByte(format[end:], '$')
+
+		if next < 0 {
+			next = len(format)
+		} else if next == 0 {
+			return nil, fmt.Errorf("Variables have to be separated in log format '%s'", format)
+		} else {
+			next += end
+		}
+
+		v := formatVar{name: format[i+1 : end], literal: format[end:next], field: -1, header: -1}
+
+		for field, name := range fieldNames {
+			if v.name == name {
+				v.field = field
+			}
+		}
+
+		if field, ok := fieldAliases[v.name]; ok {
+			v.field = field
+		} else if v.field < 0 && strings.HasPrefix(v.name, "http_") {
+			v.header = len(f.headers)
+			f.headers = append(f.headers, http.CanonicalHeaderKey(strings.ReplaceAll(v.name[len("http_"):], "_", "-")))
+		}
+
+		f.vars = append(f.vars, v)
+		i = next
+	}
+
+	var missing []string
+
+	for _, name := range fieldNames[:fieldUserAgent] {
+		if !f.has(name) {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Log format has no $%s", strings.Join(missing, ", $"))
+	}
+
+	return &f, nil
+}
+
+func (f *logFormat) has(name string) bool {
+	for _, v := range f.vars {
+		if v.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match looks up fields and headers of the line, values are substrings of the line
+func (f *logFormat) match(line string, fields *[fieldCount]string, headers []string) bool {
+	if !strings.HasPrefix(line, f.prefix) {
+		return false
+	}
+
+	pos := len(f.prefix)
+
+	for _, v := range f.vars {
+		var value string
+
+		if v.literal == "" {
+			value = line[pos:]
+			pos = len(line)
+
+			if strings.IndexByte(value, ' ') >= 0 {
+				return false
+			}
+		} else {
+			stop := strings.IndexByte(line[pos:], v.literal[0])
+
+			if stop < 0 {
+				return false
+			}
+
+			value = line[pos : pos+stop]
+			pos += stop
+
+			if !strings.HasPrefix(line[pos:], v.literal) {
+				return false
+			}
+
+			pos += len(v.literal)
+		}
+
+		if v.field >= 0 {
+			fields[v.field] = value
+		} else if v.header >= 0 {
+			headers[v.header] = value
+		}
+	}
+
+	return pos == len(line)
+}
+
+var months = map[string]time.Month{
+	"Jan": time.January, "Feb": time.February, "Mar": time.March, "Apr": time.April,
+	"May": time.May, "Jun": time.June, "Jul": time.July, "Aug": time.August,
+	"Sep": time.September, "Oct": time.October, "Nov": time.November, "Dec": time.December,
+}
+
+// zones caches locations of offsets in whole quarter hours between -14h and +14h
+var zones [113]atomic.Pointer[time.Location]
+
+func zone(offset int) *time.Location {
+	if offset%900 != 0 || offset < -14*3600 || offset > 14*3600 {
+		return time.FixedZone("", offset)
+	}
+
+	i := offset/900 + 56
+
+	if loc := zones[i].Load(); loc != nil {
+		return loc
+	}
+
+	loc := time.FixedZone("", offset)
+	zones[i].Store(loc)
+
+	return loc
+}
+
+// digits parses unsigned decimal number, -1 is returned for anything else
+func digits(s string) int {
+	n := 0
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return -1
+		}
+
+		n = n*10 + int(s[i]-'0')
+	}
+
+	return n
+}
+
+// parseTimeLocal parses $time_local (02/Jan/2006:15:04:05 -0700) without allocations,
+// values of other shape are left to time.Parse
+func parseTimeLocal(value string) (time.Time, error) {
+	if len(value) != 26 || value[2] != '/' || value[6] != '/' || value[11] != ':' || value[14] != ':' || value[17] != ':' || value[20] != ' ' {
+		return time.Parse(nginxTimeLayout, value)
+	}
+
+	month, ok := months[value[3:6]]
+	day, year := digits(value[0:2]), digits(value[7:11])
+	hour, min, sec := digits(value[12:14]), digits(value[15:17]), digits(value[18:20])
+	offHour, offMin := digits(value[22:24]), digits(value[24:26])
+
+	if !ok || day < 1 || day > 31 || year < 0 || hour < 0 || hour > 23 || min < 0 || min > 59 || sec < 0 || sec > 59 ||
+		offHour < 0 || offMin < 0 || offMin > 59 || (value[21] != '+' && value[21] != '-') {
+		return time.Parse(nginxTimeLayout, value)
+	}
+
+	offset := offHour*3600 + offMin*60
+
+	if value[21] == '-' {
+		offset = -offset
+	}
+
+	t := time.Date(year, month, day, hour, min, sec, 0, zone(offset))
+
+	if t.Day() != day {
+		// Date normalizes days past the end of month, time.Parse reports them
+		return time.Parse(nginxTimeLayout, value)
+	}
+
+	return t, nil
+}
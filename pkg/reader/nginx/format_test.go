@@ -0,0 +1,328 @@
+package nginx
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const combinedFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+const combinedLine = `10.0.0.1 - - [08/Nov/2013:13:39:18 +0000] "GET /t/100x100/foo/bar.jpeg?a=1 HTTP/1.1" 200 1027 "http://example.com/" "curl/7.29.0"`
+
+func TestCompileFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		prefix   string
+		vars     []string
+		literals []string
+		headers  []string
+		err      string
+	}{
+		{
+			name:     "combined",
+			format:   combinedFormat,
+			vars:     []string{"remote_addr", "remote_user", "time_local", "request", "status", "body_bytes_sent", "http_referer", "http_user_agent"},
+			literals: []string{" - ", " [", `] "`, `" `, " ", ` "`, `" "`, `"`},
+		},
+		{
+			name:     "leading literal and surrounding spaces",
+			format:   `  [$time_local] "$request" $request_time  `,
+			prefix:   "[",
+			vars:     []string{"time_local", "request", "request_time"},
+			literals: []string{`] "`, `" `, ""},
+		},
+		{
+			name:     "other http variables are headers",
+			format:   `[$time_local] "$request" $http_x_request_id $http_host`,
+			prefix:   "[",
+			vars:     []string{"time_local", "request", "http_x_request_id", "http_host"},
+			literals: []string{`] "`, `" `, " ", ""},
+			headers:  []string{"X-Request-Id"},
+		},
+		{
+			name:   "no variables",
+			format: `plain text`,
+			err:    "has no variables",
+		},
+		{
+			name:   "missing variable name",
+			format: `[$time_local] "$request" $ $status`,
+			err:    "Missing variable name",
+		},
+		{
+			name:   "adjacent variables",
+			format: `[$time_local] "$request" $status$request_time`,
+			err:    "have to be separated",
+		},
+		{
+			name:   "missing required variables",
+			format: `$remote_addr $status`,
+			err:    "Log format has no $time_local, $request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := compileFormat(tt.format)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var vars, literals []string
+
+			for _, v := range f.vars {
+				vars = append(vars, v.name)
+				literals = append(literals, v.literal)
+			}
+
+			if f.prefix != tt.prefix {
+				t.Errorf("prefix %q, expected %q", f.prefix, tt.prefix)
+			}
+
+			if !reflect.DeepEqual(vars, tt.vars) {
+				t.Errorf("vars %q, expected %q", vars, tt.vars)
+			}
+
+			if !reflect.DeepEqual(literals, tt.literals) {
+				t.Errorf("literals %q, expected %q", literals, tt.literals)
+			}
+
+			if !reflect.DeepEqual(f.headers, tt.headers) {
+				t.Errorf("headers %q, expected %q", f.headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		line    string
+		ok      bool
+		fields  map[int]string
+		headers []string
+	}{
+		{
+			name:   "combined",
+			format: combinedFormat,
+			line:   combinedLine,
+			ok:     true,
+			fields: map[int]string{
+				fieldRemoteAddr: "10.0.0.1",
+				fieldTimeLocal:  "08/Nov/2013:13:39:18 +0000",
+				fieldRequest:    "GET /t/100x100/foo/bar.jpeg?a=1 HTTP/1.1",
+				fieldStatus:     "200",
+				fieldReferer:    "http://example.com/",
+				fieldUserAgent:  "curl/7.29.0",
+			},
+		},
+		{
+			name:    "last variable up to the end of line",
+			format:  `[$time_local] "$request" $http_x_request_id`,
+			line:    `[08/Nov/2013:13:39:18 +0000] "GET / HTTP/1.1" abc-123`,
+			ok:      true,
+			fields:  map[int]string{fieldTimeLocal: "08/Nov/2013:13:39:18 +0000", fieldRequest: "GET / HTTP/1.1"},
+			headers: []string{"abc-123"},
+		},
+		{
+			// gonx allowed spaces in the last variable, the scanner does not
+			name:   "last variable with spaces",
+			format: `[$time_local] "$request" $request_time`,
+			line:   `[08/Nov/2013:13:39:18 +0000] "GET / HTTP/1.1" 0.014 extra`,
+		},
+		{
+			name:   "different prefix",
+			format: `[$time_local] "$request"`,
+			line:   `08/Nov/2013:13:39:18 +0000] "GET / HTTP/1.1"`,
+		},
+		{
+			name:   "missing literal",
+			format: combinedFormat,
+			line:   `10.0.0.1 - - [08/Nov/2013:13:39:18 +0000] "GET / HTTP/1.1" 200 1027`,
+		},
+		{
+			name:   "text after the last literal",
+			format: `[$time_local] "$request"`,
+			line:   `[08/Nov/2013:13:39:18 +0000] "GET / HTTP/1.1" trailing`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := compileFormat(tt.format)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var fields [fieldCount]string
+			headers := make([]string, len(f.headers))
+
+			if ok := f.match(tt.line, &fields, headers); ok != tt.ok {
+				t.Fatalf("match returned %t, expected %t", ok, tt.ok)
+			}
+
+			if !tt.ok {
+				return
+			}
+
+			for field, expected := range tt.fields {
+				if fields[field] != expected {
+					t.Errorf("$%s is %q, expected %q", fieldNames[field], fields[field], expected)
+				}
+			}
+
+			if len(tt.headers) > 0 && !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("headers %q, expected %q", headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestParseTimeLocal(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+		err      bool
+	}{
+		{value: "08/Nov/2013:13:39:18 +0000", expected: time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC)},
+		{value: "31/Dec/2023:23:59:59 -0700", expected: time.Date(2024, time.January, 1, 6, 59, 59, 0, time.UTC)},
+		{value: "01/Jan/2024:10:00:00 +0530", expected: time.Date(2024, time.January, 1, 4, 30, 0, 0, time.UTC)},
+		// Offsets which are not whole quarter hours are not cached
+		{value: "01/Jan/2024:10:00:00 +0001", expected: time.Date(2024, time.January, 1, 9, 59, 0, 0, time.UTC)},
+		// Other shapes are left to time.Parse
+		{value: "8/Nov/2013:13:39:18 +0000", expected: time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC)},
+		{value: "30/Feb/2024:10:00:00 +0000", err: true},
+		{value: "08/Foo/2013:13:39:18 +0000", err: true},
+		{value: "08/Nov/2013:25:39:18 +0000", err: true},
+		{value: "08/Nov/2013:13:39:18 *0000", err: true},
+		{value: "-", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			parsed, err := parseTimeLocal(tt.value)
+
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", parsed)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !parsed.Equal(tt.expected) {
+				t.Errorf("parsed %s, expected %s", parsed, tt.expected)
+			}
+
+			// The offset is kept like time.Parse keeps it
+			reference, _ := time.Parse(nginxTimeLayout, tt.value)
+
+			if _, offset := parsed.Zone(); offset != zoneOffset(reference) {
+				t.Errorf("offset %d, expected %d", offset, zoneOffset(reference))
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	format := `$remote_addr [$time_local] "$request" $status $request_time "$http_user_agent" "$http_x_request_id" "$request_body"`
+	r := NewReader(strings.NewReader(""), format).(*NginxReader)
+
+	var entry reader.LogEntry
+
+	line := `10.0.0.1:5123 [08/Nov/2013:13:39:18 +0000] "POST /api HTTP/1.1" 201 0.014 "-" "abc" "a=\x22b\x22"`
+
+	if err := r.ParseLine(line, &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := reader.LogEntry{
+		Time:     time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC),
+		Method:   "POST",
+		URL:      "/api",
+		Payload:  `a="b"`,
+		ClientIP: "10.0.0.1",
+		Headers:  http.Header{"X-Request-Id": []string{"abc"}},
+		Status:   201,
+		Duration: 14 * time.Millisecond,
+	}
+
+	if !entry.Time.Equal(expected.Time) {
+		t.Errorf("time %s, expected %s", entry.Time, expected.Time)
+	}
+
+	entry.Time = expected.Time
+
+	if !reflect.DeepEqual(entry, expected) {
+		t.Errorf("entry %+v, expected %+v", entry, expected)
+	}
+
+	// Lines not matching the format are skipped instead of failing the replay
+	if err := r.ParseLine(`garbage`, &entry); err != reader.ErrSkipLine {
+		t.Errorf("expected ErrSkipLine, got %v", err)
+	}
+
+	if err := r.ParseLine(`10.0.0.1 [08/Nov/2013:13:39:18 +0000] "GET" 200 0.014 "-" "-" "-"`, &entry); err == nil || err == reader.ErrSkipLine {
+		t.Errorf("expected error of the request, got %v", err)
+	}
+}
+
+func TestReadSkipsLines(t *testing.T) {
+	input := strings.Join([]string{"garbage", combinedLine, "", combinedLine + " extra"}, "\n")
+	r := NewReader(strings.NewReader(input), combinedFormat)
+
+	entry, err := r.Read()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.URL != "/t/100x100/foo/bar.jpeg?a=1" || entry.UA != "curl/7.29.0" || entry.Referer != "http://example.com/" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	if _, err := r.Read(); err == nil || err.Error() != "EOF" {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func BenchmarkParseLine(b *testing.B) {
+	r := NewReader(strings.NewReader(""), combinedFormat).(*NginxReader)
+
+	var entry reader.LogEntry
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := r.ParseLine(combinedLine, &entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func zoneOffset(t time.Time) int {
+	_, offset := t.Zone()
+
+	return offset
+}
@@ -1,75 +1,157 @@
 package nginx
 
 import (
+	"bufio"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Gonzih/log-replay/pkg/reader"
-	"github.com/satyrius/gonx"
 )
 
 const (
 	nginxTimeLayout = "2/Jan/2006:15:04:05 -0700"
+	maxLineSize     = 16 * 1024 * 1024
 )
 
 // NginxReader implements reader.LogReader intefrace
 type NginxReader struct {
-	GonxReader *gonx.Reader
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+	format       *logFormat
 }
 
-func parseNginxTime(timeLocal string) time.Time {
-	t, err := time.Parse(nginxTimeLayout, timeLocal)
+// NewReader creates new reader for a nginx log format using provided io.Reader
+func NewReader(inputReader io.Reader, format string) reader.LogReader {
+	logFormat, err := compileFormat(format)
 
 	reader.Must(err)
 
-	return t
-}
-
-// NewReader creates new reader for a haproxy log format using provided io.Reader
-func NewReader(inputReader io.Reader, format string) reader.LogReader {
 	var reader NginxReader
-	reader.GonxReader = gonx.NewReader(inputReader, format)
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.InputScanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	reader.format = logFormat
 
 	return &reader
 }
 
+// Read skips lines not matching the format
 func (r *NginxReader) Read() (*reader.LogEntry, error) {
 	var entry reader.LogEntry
 
-	rec, err := r.GonxReader.Read()
+	for r.InputScanner.Scan() {
+		err := r.ParseLine(r.InputScanner.Text(), &entry)
+
+		if err == reader.ErrSkipLine {
+			continue
+		}
 
-	if err != nil {
 		return &entry, err
 	}
 
-	timeLocal, err := rec.Field("time_local")
+	err := reader.NewInputError(r.InputScanner.Err())
 
 	if err != nil {
 		return &entry, err
 	}
 
-	requestString, err := rec.Field("request")
+	return &entry, io.EOF
+}
 
-	if err != nil {
-		return &entry, err
+// value returns the value of optional variable, nginx logs empty ones as "-"
+func value(v string) string {
+	if v == "-" {
+		return ""
 	}
 
-	ua, err := rec.Field("http_user_agent")
+	return v
+}
 
-	if err != nil {
-		return &entry, err
+// unescape decodes \xHH sequences nginx writes for quotes, backslashes and non printable characters
+func unescape(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if n, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
 	}
 
-	parsedRequest, err := reader.ParseRequest(requestString)
+	return b.String()
+}
+
+// ParseLine implements reader.LineParser, lines not matching the format are skipped like in Read.
+// Values of the entry are substrings of the line, so the line is the only allocation
+func (r *NginxReader) ParseLine(line string, entry *reader.LogEntry) error {
+	var fields [fieldCount]string
+	var headers []string
+
+	if len(r.format.headers) > 0 {
+		headers = make([]string, len(r.format.headers))
+	}
+
+	if !r.format.match(line, &fields, headers) {
+		return reader.ErrSkipLine
+	}
+
+	// Same split as reader.ParseRequest, without allocating the parts
+	request := fields[fieldRequest]
+	method, rest, ok := strings.Cut(request, " ")
+
+	if !ok || !strings.Contains(rest, " ") {
+		return fmt.Errorf("ERROR while parsing string: %s", request)
+	}
+
+	url, _, _ := strings.Cut(rest, " ")
+
+	t, err := parseTimeLocal(fields[fieldTimeLocal])
 
 	if err != nil {
-		return &entry, err
+		return err
 	}
 
-	entry.Method = parsedRequest[0]
-	entry.URL = parsedRequest[1]
-	entry.UA = ua
-	entry.Time = parseNginxTime(timeLocal)
+	entry.Method = method
+	entry.URL = url
+	entry.UA = value(fields[fieldUserAgent])
+	entry.Time = t
+
+	// $status is optional in the log format
+	if fields[fieldStatus] != "" {
+		entry.Status = reader.ParseStatus(fields[fieldStatus])
+	}
+
+	if fields[fieldRequestTime] != "" {
+		entry.Duration = reader.ParseDuration(fields[fieldRequestTime], time.Second)
+	}
+
+	if fields[fieldRemoteAddr] != "" {
+		entry.ClientIP = reader.ParseClientIP(fields[fieldRemoteAddr])
+	}
+
+	entry.Referer = value(fields[fieldReferer])
+	entry.Scheme = value(fields[fieldScheme])
+	entry.Host = value(fields[fieldHost])
+	entry.ContentType = value(fields[fieldContentType])
+	entry.Payload = unescape(value(fields[fieldRequestBody]))
+
+	for i, header := range headers {
+		entry.SetHeader(r.format.headers[i], header)
+	}
 
-	return &entry, nil
+	return nil
 }
@@ -0,0 +1,334 @@
+package nginxjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	maxLineSize = 1024 * 1024
+)
+
+// Fields maps replay record fields to the keys of a JSON log line,
+// nested keys can be addressed with dots (e.g. "request.uri")
+type Fields struct {
+	Time    string
+	Method  string
+	URL     string
+	Request string
+	Status  string
+	Payload string
+	UA      string
+	Referer string
+	Host    string
+	// Client is the key of the original client address
+	Client      string
+	Scheme      string
+	ContentType string
+	// Headers is the key of an object with request headers
+	Headers string
+	// Duration is the key of the original request duration given in DurationUnit
+	Duration     string
+	DurationUnit time.Duration
+}
+
+// DefaultFields matches key names of the nginx variables usually used in json log_format
+var DefaultFields = Fields{
+	Time:    "time_local",
+	Method:  "request_method",
+	URL:     "request_uri",
+	Request: "request",
+	Status:  "status",
+	Payload: "request_body",
+	UA:      "http_user_agent",
+	Referer: "http_referer",
+	Host:    "host",
+	Client:  "remote_addr",
+	// $request_time is in seconds
+	Duration:     "request_time",
+	DurationUnit: time.Second,
+	Scheme:       "scheme",
+	ContentType:  "content_type",
+}
+
+// ParseFields parses comma separated list of field=key pairs on top of defaults,
+// known fields are time, method, url, request, status, payload, ua, referer, host, client, scheme, content_type,
+// headers (object of request headers) and duration
+func ParseFields(defaults Fields, spec string) (Fields, error) {
+	fields := defaults
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+
+		if len(kv) != 2 {
+			return fields, fmt.Errorf("Invalid field mapping '%s', expected field=key", pair)
+		}
+
+		key := strings.TrimSpace(kv[1])
+
+		switch strings.TrimSpace(kv[0]) {
+		case "time":
+			fields.Time = key
+		case "method":
+			fields.Method = key
+		case "url":
+			fields.URL = key
+		case "request":
+			fields.Request = key
+		case "status":
+			fields.Status = key
+		case "payload":
+			fields.Payload = key
+		case "ua":
+			fields.UA = key
+		case "referer":
+			fields.Referer = key
+		case "host":
+			fields.Host = key
+		case "client":
+			fields.Client = key
+		case "scheme":
+			fields.Scheme = key
+		case "content_type":
+			fields.ContentType = key
+		case "headers":
+			fields.Headers = key
+		case "duration":
+			fields.Duration = key
+		default:
+			return fields, fmt.Errorf("Unknown field '%s' in mapping '%s'", kv[0], pair)
+		}
+	}
+
+	return fields, nil
+}
+
+// NginxJSONReader implements reader.LogReader interface
+type NginxJSONReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+	Fields       Fields
+	TimeLayout   string
+}
+
+// lookupValue returns value of the dot separated key
+func lookupValue(doc map[string]interface{}, key string) (interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	var value interface{} = doc
+
+	for _, part := range strings.Split(key, ".") {
+		obj, ok := value.(map[string]interface{})
+
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = obj[part]
+
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func lookup(doc map[string]interface{}, key string) (string, bool) {
+	value, ok := lookupValue(doc, key)
+
+	if !ok {
+		return "", false
+	}
+
+	return stringValue(value)
+}
+
+// stringValue formats JSON value, null is reported as missing
+func stringValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+func (r *NginxJSONReader) parseInto(line string, entry *reader.LogEntry) error {
+	var doc map[string]interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&doc); err != nil {
+		return fmt.Errorf("ERROR while parsing json line: %s", err)
+	}
+
+	timeString, ok := lookup(doc, r.Fields.Time)
+
+	if !ok {
+		return fmt.Errorf("Time key '%s' not found in line: %s", r.Fields.Time, line)
+	}
+
+	t, err := reader.ParseTime(r.TimeLayout, timeString)
+
+	if err != nil {
+		return err
+	}
+
+	method, hasMethod := lookup(doc, r.Fields.Method)
+	url, hasURL := lookup(doc, r.Fields.URL)
+
+	if !hasMethod || !hasURL {
+		requestString, ok := lookup(doc, r.Fields.Request)
+
+		if !ok {
+			return fmt.Errorf("Neither method/url keys nor request key '%s' found in line: %s", r.Fields.Request, line)
+		}
+
+		parsedRequest, err := reader.ParseRequest(requestString)
+
+		if err != nil {
+			return err
+		}
+
+		method = parsedRequest[0]
+		url = parsedRequest[1]
+	}
+
+	entry.Time = t
+	entry.Method = method
+	entry.URL = url
+	entry.Payload, _ = lookup(doc, r.Fields.Payload)
+
+	if status, ok := lookup(doc, r.Fields.Status); ok {
+		entry.Status = reader.ParseStatus(status)
+	}
+
+	if duration, ok := lookup(doc, r.Fields.Duration); ok {
+		entry.Duration = reader.ParseDuration(duration, r.Fields.DurationUnit)
+	}
+
+	entry.UA, _ = lookup(doc, r.Fields.UA)
+	entry.Referer, _ = lookup(doc, r.Fields.Referer)
+	entry.Host, _ = lookup(doc, r.Fields.Host)
+
+	if client, ok := lookup(doc, r.Fields.Client); ok {
+		entry.ClientIP = reader.ParseClientIP(client)
+	}
+
+	// nginx writes "-" for empty variables
+	if entry.Payload == "-" {
+		entry.Payload = ""
+	}
+
+	if entry.UA == "-" {
+		entry.UA = ""
+	}
+
+	if entry.Referer == "-" {
+		entry.Referer = ""
+	}
+
+	if entry.Host == "-" {
+		entry.Host = ""
+	}
+
+	if scheme, ok := lookup(doc, r.Fields.Scheme); ok && scheme != "-" {
+		entry.Scheme = scheme
+	}
+
+	if contentType, ok := lookup(doc, r.Fields.ContentType); ok && contentType != "-" {
+		entry.ContentType = contentType
+	}
+
+	if headers, ok := lookupValue(doc, r.Fields.Headers); ok {
+		obj, _ := headers.(map[string]interface{})
+
+		for name, value := range obj {
+			// Arrays of values are joined as in a single header line
+			if values, ok := value.([]interface{}); ok {
+				var parts []string
+
+				for _, v := range values {
+					if s, ok := stringValue(v); ok {
+						parts = append(parts, s)
+					}
+				}
+
+				entry.SetHeader(name, strings.Join(parts, ", "))
+			} else if s, ok := stringValue(value); ok {
+				entry.SetHeader(name, s)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewReader creates new reader for a json nginx log format using provided io.Reader,
+// empty timeLayout means nginx time_local, RFC3339 and unix timestamps are tried in order
+func NewReader(inputReader io.Reader, fields Fields, timeLayout string) reader.LogReader {
+	var reader NginxJSONReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.InputScanner.Buffer(make([]byte, bufio.MaxScanTokenSize), maxLineSize)
+	reader.Fields = fields
+	reader.TimeLayout = timeLayout
+
+	return &reader
+}
+
+func (r *NginxJSONReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	for r.InputScanner.Scan() {
+		line := strings.TrimSpace(r.InputScanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		err := r.parseInto(line, &entry)
+
+		return &entry, err
+	}
+
+	err := reader.NewInputError(r.InputScanner.Err())
+
+	if err != nil {
+		return &entry, err
+	}
+
+	return &entry, io.EOF
+}
+
+// ParseLine implements reader.LineParser
+func (r *NginxJSONReader) ParseLine(line string, entry *reader.LogEntry) error {
+	line = strings.TrimSpace(line)
+
+	if line == "" {
+		return reader.ErrSkipLine
+	}
+
+	return r.parseInto(line, entry)
+}
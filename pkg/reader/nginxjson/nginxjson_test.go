@@ -0,0 +1,149 @@
+package nginxjson
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		spec   string
+		change func(f *Fields)
+		err    bool
+	}{
+		{spec: "", change: func(f *Fields) {}},
+		{spec: "time=ts, url=req.uri,,headers=req.headers", change: func(f *Fields) {
+			f.Time = "ts"
+			f.URL = "req.uri"
+			f.Headers = "req.headers"
+		}},
+		{spec: "duration=upstream_time", change: func(f *Fields) { f.Duration = "upstream_time" }},
+		{spec: "time", err: true},
+		{spec: "cookie=c", err: true},
+	}
+
+	for _, tt := range tests {
+		fields, err := ParseFields(DefaultFields, tt.spec)
+
+		if (err != nil) != tt.err {
+			t.Errorf("ParseFields(%q) returned error %v", tt.spec, err)
+			continue
+		}
+
+		if tt.err {
+			continue
+		}
+
+		expected := DefaultFields
+		tt.change(&expected)
+
+		if fields != expected {
+			t.Errorf("ParseFields(%q) = %+v, expected %+v", tt.spec, fields, expected)
+		}
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		layout string
+		line   string
+		entry  reader.LogEntry
+		err    bool
+	}{
+		{
+			name: "default fields",
+			line: `{"time_local":"08/Nov/2013:13:39:18 +0000","request_method":"POST","request_uri":"/api?a=1","status":"201","request_body":"a=1","http_user_agent":"curl/7.29.0","http_referer":"-","host":"example.com","remote_addr":"10.0.0.1","request_time":"0.014","scheme":"https","content_type":"application/x-www-form-urlencoded"}`,
+			entry: reader.LogEntry{
+				Time:        time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC),
+				Method:      "POST",
+				URL:         "/api?a=1",
+				Payload:     "a=1",
+				UA:          "curl/7.29.0",
+				Host:        "example.com",
+				ClientIP:    "10.0.0.1",
+				Status:      201,
+				Duration:    14 * time.Millisecond,
+				Scheme:      "https",
+				ContentType: "application/x-www-form-urlencoded",
+			},
+		},
+		{
+			name: "request instead of method and URL, numbers and unix time",
+			line: `{"time_local":1383917958,"request":"GET /a HTTP/1.1","status":404,"request_body":"-"}`,
+			entry: reader.LogEntry{
+				Time:   time.Unix(1383917958, 0),
+				Method: "GET",
+				URL:    "/a",
+				Status: 404,
+			},
+		},
+		{
+			name:   "nested keys and headers",
+			fields: "time=ts,method=req.method,url=req.uri,headers=req.headers",
+			layout: time.RFC3339,
+			line:   `{"ts":"2024-01-01T10:00:00Z","req":{"method":"GET","uri":"/b","headers":{"X-Request-Id":"abc","Accept":["text/html","*/*"],"X-Empty":null}}}`,
+			entry: reader.LogEntry{
+				Time:    time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:  "GET",
+				URL:     "/b",
+				Headers: http.Header{"X-Request-Id": []string{"abc"}, "Accept": []string{"text/html, */*"}},
+			},
+		},
+		{
+			name: "missing time",
+			line: `{"request":"GET /a HTTP/1.1"}`,
+			err:  true,
+		},
+		{
+			name: "missing request",
+			line: `{"time_local":"08/Nov/2013:13:39:18 +0000","request_uri":"/a"}`,
+			err:  true,
+		},
+		{
+			name: "not JSON",
+			line: `08/Nov/2013:13:39:18 GET /a`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseFields(DefaultFields, tt.fields)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := NewReader(strings.NewReader(""), fields, tt.layout).(*NginxJSONReader)
+
+			var entry reader.LogEntry
+
+			err = r.parseInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if tt.err {
+				return
+			}
+
+			if !entry.Time.Equal(tt.entry.Time) {
+				t.Errorf("time %s, expected %s", entry.Time, tt.entry.Time)
+			}
+
+			entry.Time = tt.entry.Time
+
+			if !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package reader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// parallelChunkLines is how many lines are handed to a parse worker at once
+	parallelChunkLines  = 1024
+	parallelMaxLineSize = 16 * 1024 * 1024
+)
+
+// ErrSkipLine is returned by LineParser for lines without an entry, like empty ones
+var ErrSkipLine = errors.New("line has no entry")
+
+// LineParser is implemented by readers of line based formats, parsing is independent
+// of other lines so that lines can be parsed concurrently
+type LineParser interface {
+	ParseLine(line string, entry *LogEntry) error
+}
+
+// parallelChunk is a run of consecutive lines, done is closed once all of them are parsed
+type parallelChunk struct {
+	lines   []string
+	entries []LogEntry
+	errs    []error
+	done    chan struct{}
+}
+
+// ParallelReader splits input into chunks of lines parsed by several workers,
+// entries are returned in the order of the lines
+type ParallelReader struct {
+	chunks  chan *parallelChunk
+	current *parallelChunk
+	next    int
+	err     error
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewParallelReader creates reader parsing lines of the input with the parser in workers goroutines,
+// at most twice as many chunks as there are workers are read ahead
+func NewParallelReader(input io.Reader, parser LineParser, workers int) *ParallelReader {
+	r := &ParallelReader{
+		chunks: make(chan *parallelChunk, 2*workers),
+		stop:   make(chan struct{}),
+	}
+
+	work := make(chan *parallelChunk, 2*workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for chunk := range work {
+				chunk.entries = make([]LogEntry, len(chunk.lines))
+				chunk.errs = make([]error, len(chunk.lines))
+
+				for j, line := range chunk.lines {
+					chunk.errs[j] = parser.ParseLine(line, &chunk.entries[j])
+				}
+
+				close(chunk.done)
+			}
+		}()
+	}
+
+	go r.scan(input, work)
+
+	return r
+}
+
+// scan splits the input into chunks, each of them is queued both for parsing and for Read
+func (r *ParallelReader) scan(input io.Reader, work chan *parallelChunk) {
+	defer close(r.chunks)
+	defer close(work)
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), parallelMaxLineSize)
+
+	var lines []string
+
+	send := func() bool {
+		chunk := &parallelChunk{lines: lines, done: make(chan struct{})}
+		lines = nil
+
+		select {
+		case work <- chunk:
+		case <-r.stop:
+			return false
+		}
+
+		select {
+		case r.chunks <- chunk:
+			return true
+		case <-r.stop:
+			return false
+		}
+	}
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+
+		if len(lines) == parallelChunkLines && !send() {
+			return
+		}
+	}
+
+	if len(lines) > 0 && !send() {
+		return
+	}
+
+	// Chunks channel is closed after err is set, Read sees it once all the chunks are consumed
+	r.err = NewInputError(scanner.Err())
+}
+
+func (r *ParallelReader) Read() (*LogEntry, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *ParallelReader) ReadContext(ctx context.Context) (*LogEntry, error) {
+	for {
+		if r.current != nil {
+			select {
+			case <-r.current.done:
+			case <-ctx.Done():
+				return &LogEntry{}, ctx.Err()
+			}
+
+			if r.next < len(r.current.lines) {
+				entry := &r.current.entries[r.next]
+				err := r.current.errs[r.next]
+				r.next++
+
+				if err == ErrSkipLine {
+					continue
+				}
+
+				return entry, err
+			}
+		}
+
+		var ok bool
+
+		select {
+		case r.current, ok = <-r.chunks:
+		case <-ctx.Done():
+			return &LogEntry{}, ctx.Err()
+		}
+
+		if !ok {
+			if r.err != nil {
+				return &LogEntry{}, r.err
+			}
+
+			return &LogEntry{}, io.EOF
+		}
+
+		r.next = 0
+	}
+}
+
+// Close stops reading ahead, it has to be called when the reader is not read till io.EOF
+func (r *ParallelReader) Close() error {
+	r.once.Do(func() { close(r.stop) })
+
+	return nil
+}
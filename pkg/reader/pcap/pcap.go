@@ -0,0 +1,340 @@
+// Package pcap reads HTTP/1.x requests out of pcap and pcapng captures (e.g. tcpdump -w), TCP streams
+// are reassembled and responses seen in the capture give statuses and durations of the requests
+package pcap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/google/gopacket/tcpassembly"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// Magic number pcapng files start with, classic pcap ones are told apart by the reader
+const pcapngMagic = 0x0a0d0d0a
+
+// PcapReader implements reader.LogReader interface, the whole capture is read on the first Read
+// and requests are returned in the order they were sent
+type PcapReader struct {
+	InputReader io.Reader
+	entries     []*reader.LogEntry
+	loaded      bool
+}
+
+// NewReader creates new reader of the capture using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader PcapReader
+
+	reader.InputReader = inputReader
+
+	return &reader
+}
+
+func (r *PcapReader) Read() (*reader.LogEntry, error) {
+	if !r.loaded {
+		r.loaded = true
+
+		entries, err := readCapture(r.InputReader)
+
+		if err != nil {
+			return &reader.LogEntry{}, reader.NewInputError(err)
+		}
+
+		r.entries = entries
+	}
+
+	if len(r.entries) == 0 {
+		return &reader.LogEntry{}, io.EOF
+	}
+
+	entry := r.entries[0]
+	r.entries = r.entries[1:]
+
+	return entry, nil
+}
+
+// packetSource is implemented by both pcap and pcapng readers
+type packetSource interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	LinkType() layers.LinkType
+}
+
+func newPacketSource(input io.Reader) (packetSource, error) {
+	buffered := bufio.NewReader(input)
+	magic, err := buffered.Peek(4)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if binary.LittleEndian.Uint32(magic) == pcapngMagic {
+		return pcapgo.NewNgReader(buffered, pcapgo.DefaultNgReaderOptions)
+	}
+
+	return pcapgo.NewReader(buffered)
+}
+
+// readCapture reassembles TCP streams of the capture and returns requests found in them sorted by time
+func readCapture(input io.Reader) ([]*reader.LogEntry, error) {
+	source, err := newPacketSource(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	factory := &streamFactory{streams: make(map[flowKey][]*stream)}
+	assembler := tcpassembly.NewAssembler(tcpassembly.NewStreamPool(factory))
+
+	for {
+		data, ci, err := source.ReadPacketData()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		packet := gopacket.NewPacket(data, source.LinkType(), gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+		network := packet.NetworkLayer()
+
+		if network == nil {
+			continue
+		}
+
+		if tcp, ok := packet.TransportLayer().(*layers.TCP); ok {
+			assembler.AssembleWithTimestamp(network.NetworkFlow(), tcp, ci.Timestamp)
+		}
+	}
+
+	assembler.FlushAll()
+
+	var entries []*reader.LogEntry
+
+	for key, streams := range factory.streams {
+		responses := factory.streams[flowKey{key.net.Reverse(), key.tcp.Reverse()}]
+
+		for i, s := range streams {
+			var response *stream
+
+			if i < len(responses) {
+				response = responses[i]
+			}
+
+			requests := s.requests(response)
+
+			// Requests are sent by the source of the connection
+			for _, entry := range requests {
+				entry.ClientIP = key.net.Src().String()
+			}
+
+			entries = append(entries, requests...)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	return entries, nil
+}
+
+type flowKey struct {
+	net gopacket.Flow
+	tcp gopacket.Flow
+}
+
+// streamFactory keeps streams of every direction of every connection, connections reusing
+// the same addresses and ports are kept in order
+type streamFactory struct {
+	streams map[flowKey][]*stream
+}
+
+func (f *streamFactory) New(net, tcp gopacket.Flow) tcpassembly.Stream {
+	s := &stream{}
+	key := flowKey{net, tcp}
+	f.streams[key] = append(f.streams[key], s)
+
+	return s
+}
+
+// stream is one direction of a connection split into segments of contiguous data, HTTP messages
+// are parsed from the start of every segment, a segment after missing data usually starts mid message
+type stream struct {
+	segments []*segment
+}
+
+// segment is contiguous data of the stream with times the chunks starting at offsets were seen
+type segment struct {
+	data    []byte
+	offsets []int
+	times   []time.Time
+}
+
+func (s *stream) Reassembled(chunks []tcpassembly.Reassembly) {
+	for _, chunk := range chunks {
+		if len(chunk.Bytes) == 0 {
+			continue
+		}
+
+		if chunk.Skip != 0 || len(s.segments) == 0 {
+			s.segments = append(s.segments, &segment{})
+		}
+
+		seg := s.segments[len(s.segments)-1]
+		seg.offsets = append(seg.offsets, len(seg.data))
+		seg.times = append(seg.times, chunk.Seen)
+		seg.data = append(seg.data, chunk.Bytes...)
+	}
+}
+
+func (s *stream) ReassemblyComplete() {}
+
+// timeAt returns the time the byte at the offset was seen
+func (seg *segment) timeAt(offset int) time.Time {
+	i := sort.SearchInts(seg.offsets, offset+1) - 1
+
+	if i < 0 {
+		i = 0
+	}
+
+	return seg.times[i]
+}
+
+// message is a request or response parsed from the segment with time of its first and last byte
+type message struct {
+	start time.Time
+	end   time.Time
+}
+
+// parse calls read for HTTP messages at the start of the segment until it fails, read has
+// to consume the whole message
+func (seg *segment) parse(read func(buf *bufio.Reader) error) []message {
+	var messages []message
+
+	data := bytes.NewReader(seg.data)
+	buf := bufio.NewReader(data)
+
+	consumed := func() int {
+		return len(seg.data) - data.Len() - buf.Buffered()
+	}
+
+	for {
+		start := consumed()
+
+		if start >= len(seg.data) || read(buf) != nil {
+			return messages
+		}
+
+		messages = append(messages, message{start: seg.timeAt(start), end: seg.timeAt(consumed() - 1)})
+	}
+}
+
+// requests returns requests of the stream, statuses and durations are taken from the responses stream
+// of the same connection when the responses are complete
+func (s *stream) requests(responses *stream) []*reader.LogEntry {
+	var entries []*reader.LogEntry
+	var requests []*http.Request
+
+	for _, seg := range s.segments {
+		var parsed []*http.Request
+		var payloads []string
+
+		messages := seg.parse(func(buf *bufio.Reader) error {
+			req, err := http.ReadRequest(buf)
+
+			if err != nil {
+				return err
+			}
+
+			payload, err := io.ReadAll(req.Body)
+
+			if err != nil {
+				return err
+			}
+
+			parsed = append(parsed, req)
+			payloads = append(payloads, string(payload))
+
+			return nil
+		})
+
+		for i, msg := range messages {
+			req := parsed[i]
+
+			entry := &reader.LogEntry{
+				Time:    msg.start,
+				Method:  req.Method,
+				URL:     req.RequestURI,
+				Payload: payloads[i],
+				Host:    req.Host,
+				// TLS traffic can not be read
+				Scheme: "http",
+			}
+
+			entry.SetRequestHeaders(req.Header)
+			entries = append(entries, entry)
+		}
+
+		requests = append(requests, parsed...)
+	}
+
+	// Responses can be matched with requests only when no data is missing
+	if responses == nil || len(responses.segments) != 1 || len(s.segments) != 1 {
+		return entries
+	}
+
+	i := 0
+	var final []bool
+
+	messages := responses.segments[0].parse(func(buf *bufio.Reader) error {
+		if i >= len(requests) {
+			return io.EOF
+		}
+
+		resp, err := http.ReadResponse(buf, requests[i])
+
+		if err != nil {
+			return err
+		}
+
+		defer resp.Body.Close()
+
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			return err
+		}
+
+		// Interim responses like 100 Continue are followed by the final one
+		interim := resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != http.StatusSwitchingProtocols
+		final = append(final, !interim)
+
+		if !interim {
+			entries[i].Status = resp.StatusCode
+			i++
+		}
+
+		return nil
+	})
+
+	// Durations are from the first byte of the request to the last byte of its final response
+	i = 0
+
+	for k, msg := range messages {
+		if final[k] {
+			entries[i].Duration = msg.end.Sub(entries[i].Time)
+			i++
+		}
+	}
+
+	return entries
+}
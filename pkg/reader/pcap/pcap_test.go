@@ -0,0 +1,96 @@
+package pcap
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// testdata/http.pcap has two connections to 10.0.0.2:80, the first one sends a GET and
+// a keep-alive POST with the body in a separate packet, the second one a GET answered with 404
+func TestRead(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	expected := []reader.LogEntry{
+		{
+			Time:     start.Add(time.Millisecond),
+			Method:   "GET",
+			URL:      "/a?x=1",
+			UA:       "curl/8.0",
+			Host:     "api.example.com",
+			ClientIP: "10.0.0.1",
+			Scheme:   "http",
+			Status:   200,
+			Duration: 10 * time.Millisecond,
+		},
+		{
+			Time:     start.Add(3 * time.Millisecond),
+			Method:   "GET",
+			URL:      "/missing",
+			Host:     "api.example.com",
+			ClientIP: "10.0.0.3",
+			Scheme:   "http",
+			Status:   404,
+			Duration: 5 * time.Millisecond,
+		},
+		{
+			Time:        start.Add(20 * time.Millisecond),
+			Method:      "POST",
+			URL:         "/b",
+			Payload:     "q=1&r=2",
+			Host:        "api.example.com",
+			ClientIP:    "10.0.0.1",
+			Scheme:      "http",
+			ContentType: "application/x-www-form-urlencoded",
+			Headers:     http.Header{"Content-Length": []string{"7"}},
+			Status:      201,
+			Duration:    25 * time.Millisecond,
+		},
+	}
+
+	file, err := os.Open("testdata/http.pcap")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	r := NewReader(file)
+
+	for i, want := range expected {
+		entry, err := r.Read()
+
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+
+		if !entry.Time.Equal(want.Time) {
+			t.Errorf("entry %d: time %s, expected %s", i, entry.Time, want.Time)
+		}
+
+		entry.Time = want.Time
+
+		if !reflect.DeepEqual(*entry, want) {
+			t.Errorf("entry %d: %+v, expected %+v", i, *entry, want)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestReadInvalidCapture(t *testing.T) {
+	_, err := NewReader(strings.NewReader("not a capture")).Read()
+
+	if !reader.IsInputError(err) {
+		t.Errorf("expected input error, got %v", err)
+	}
+}
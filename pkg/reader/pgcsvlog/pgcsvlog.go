@@ -0,0 +1,151 @@
+package pgcsvlog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	pgTimeLayout = "2006-01-02 15:04:05.999 MST"
+)
+
+// columns of csvlog, later versions add columns after application_name
+const (
+	colLogTime        = 0
+	colDatabase       = 2
+	colConnectionFrom = 4
+	colSeverity       = 11
+	colMessage        = 13
+	colDetail         = 14
+	minColumns        = 23
+)
+
+// statementRegexp matches messages of log_statement and log_min_duration_statement with the statement, the duration
+// (in milliseconds) and whether it was executed with extended protocol (parameters are in the detail then)
+var statementRegexp = regexp.MustCompile(`(?s)^(?:duration: ([0-9.]+) ms\s+)?(statement|execute [^:]*): (.*)$`)
+
+// parameterRegexp matches parameters of the detail ($1 = '42', $2 = NULL), quotes in values are doubled
+var parameterRegexp = regexp.MustCompile(`\$(\d+) = (NULL|'(?:[^']|'')*')`)
+
+var placeholderRegexp = regexp.MustCompile(`\$\d+`)
+
+// PGCSVLogReader implements reader.LogReader interface, statements are entries with the verb (SELECT, UPDATE...)
+// as method, /database as URL and the statement as payload
+type PGCSVLogReader struct {
+	InputReader io.Reader
+	CSVReader   *csv.Reader
+}
+
+// NewReader creates new reader of PostgreSQL csvlog using provided io.Reader
+func NewReader(inputReader io.Reader) reader.LogReader {
+	var reader PGCSVLogReader
+
+	reader.InputReader = inputReader
+	reader.CSVReader = csv.NewReader(inputReader)
+	reader.CSVReader.FieldsPerRecord = -1
+	reader.CSVReader.ReuseRecord = true
+
+	return &reader
+}
+
+// bindParameters replaces $N placeholders of the statement with values of the parameters detail
+func bindParameters(statement, detail string) string {
+	values := make(map[string]string)
+
+	for _, m := range parameterRegexp.FindAllStringSubmatch(detail, -1) {
+		values["$"+m[1]] = m[2]
+	}
+
+	if len(values) == 0 {
+		return statement
+	}
+
+	return placeholderRegexp.ReplaceAllStringFunc(statement, func(placeholder string) string {
+		if value, ok := values[placeholder]; ok {
+			return value
+		}
+
+		return placeholder
+	})
+}
+
+// parseInto makes entry of statement records, other messages (errors, parse and bind steps of extended
+// protocol, durations logged on their own) are skipped
+func parseInto(record []string, entry *reader.LogEntry) error {
+	if len(record) < minColumns {
+		return fmt.Errorf("csvlog record has %d columns, expected at least %d", len(record), minColumns)
+	}
+
+	if record[colSeverity] != "LOG" {
+		return reader.ErrSkipLine
+	}
+
+	m := statementRegexp.FindStringSubmatch(record[colMessage])
+
+	if m == nil {
+		return reader.ErrSkipLine
+	}
+
+	t, err := time.Parse(pgTimeLayout, record[colLogTime])
+
+	if err != nil {
+		return fmt.Errorf("ERROR while parsing csvlog time: %s", err)
+	}
+
+	statement := strings.TrimSpace(m[3])
+
+	if strings.HasPrefix(m[2], "execute") {
+		statement = bindParameters(statement, record[colDetail])
+	}
+
+	entry.Payload = strings.TrimSuffix(statement, ";")
+	entry.Method = reader.StatementVerb(entry.Payload)
+	entry.URL = "/" + record[colDatabase]
+	entry.Time = t
+
+	// Unix socket connections are [local]
+	if record[colConnectionFrom] != "[local]" {
+		entry.ClientIP = reader.ParseClientIP(record[colConnectionFrom])
+	}
+
+	// Statements with duration are logged when they ended
+	if m[1] != "" {
+		ms, _ := strconv.ParseFloat(m[1], 64)
+		entry.Duration = time.Duration(ms * float64(time.Millisecond))
+		entry.Time = t.Add(-entry.Duration)
+	}
+
+	return nil
+}
+
+// Read skips records which are not statements
+func (r *PGCSVLogReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	for {
+		record, err := r.CSVReader.Read()
+
+		if err != nil {
+			var parseErr *csv.ParseError
+
+			// The CSV reader continues after malformed records, other errors end the input
+			if err != io.EOF && !errors.As(err, &parseErr) {
+				err = reader.NewInputError(err)
+			}
+
+			return &entry, err
+		}
+
+		if err := parseInto(record, &entry); err != reader.ErrSkipLine {
+			return &entry, err
+		}
+	}
+}
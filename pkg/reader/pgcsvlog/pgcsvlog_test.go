@@ -0,0 +1,89 @@
+package pgcsvlog
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const csvLog = `2024-01-01 10:00:00.100 UTC,"app","shop",1234,"10.0.0.5:51234",65a2b1c0.4d2,1,"SELECT",2024-01-01 09:59:00 UTC,3/12,0,LOG,00000,"statement: SELECT * FROM users
+WHERE name = 'o''brien';",,,,,,,,,"psql","client backend",,0
+2024-01-01 10:00:00.600 UTC,"app","shop",1234,"10.0.0.5:51234",65a2b1c0.4d2,2,"SELECT",2024-01-01 09:59:00 UTC,3/13,0,LOG,00000,"duration: 500.000 ms  execute <unnamed>: SELECT * FROM orders WHERE user_id = $1 AND status = $2 AND note = $12","parameters: $1 = '42', $2 = 'it''s'",,,,,,,,"app","client backend",,0
+2024-01-01 10:00:00.800 UTC,"app","shop",1234,"10.0.0.5:51234",65a2b1c0.4d2,4,"SELECT",2024-01-01 09:59:00 UTC,3/15,0,ERROR,42P01,"relation ""missing"" does not exist",,,,,,"SELECT 1 FROM missing",15,,"app","client backend",,0
+2024-01-01 10:00:01.000 UTC,"rep","stats",1300,"[local]",65a2b1c0.514,1,"SELECT",2024-01-01 09:59:00 UTC,4/1,0,LOG,00000,"statement: select count(*) from missing",,,,,,,,,"psql","client backend",,0
+2024-01-01 10:00:01.100 UTC,"rep","stats",1300,"[local]",65a2b1c0.514,2,"SELECT",2024-01-01 09:59:00 UTC,4/2,0,LOG,00000,"duration: 0.100 ms",,,,,,,,,"psql","client backend",,0
+`
+
+func TestRead(t *testing.T) {
+	expected := []reader.LogEntry{
+		{
+			Time:     time.Date(2024, time.January, 1, 10, 0, 0, 100000000, time.UTC),
+			Method:   "SELECT",
+			URL:      "/shop",
+			Payload:  "SELECT * FROM users\nWHERE name = 'o''brien'",
+			ClientIP: "10.0.0.5",
+		},
+		// Parameters of the extended protocol are bound, unknown placeholders are kept
+		{
+			Time:     time.Date(2024, time.January, 1, 10, 0, 0, 100000000, time.UTC),
+			Method:   "SELECT",
+			URL:      "/shop",
+			Payload:  "SELECT * FROM orders WHERE user_id = '42' AND status = 'it''s' AND note = $12",
+			ClientIP: "10.0.0.5",
+			Duration: 500 * time.Millisecond,
+		},
+		// Errors and durations logged on their own are skipped
+		{
+			Time:    time.Date(2024, time.January, 1, 10, 0, 1, 0, time.UTC),
+			Method:  "SELECT",
+			URL:     "/stats",
+			Payload: "select count(*) from missing",
+		},
+	}
+
+	r := NewReader(strings.NewReader(csvLog))
+
+	for i, want := range expected {
+		entry, err := r.Read()
+
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+
+		if !entry.Time.Equal(want.Time) {
+			t.Errorf("entry %d: time %s, expected %s", i, entry.Time, want.Time)
+		}
+
+		entry.Time = want.Time
+
+		if !reflect.DeepEqual(*entry, want) {
+			t.Errorf("entry %d: %+v, expected %+v", i, *entry, want)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestParseIntoErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+	}{
+		{name: "too few columns", record: []string{"2024-01-01 10:00:00.100 UTC", "app", "shop"}},
+		{name: "invalid time", record: append([]string{"yesterday", "app", "shop", "1", "", "", "", "", "", "", "", "LOG", "", "statement: SELECT 1"}, make([]string, 9)...)},
+	}
+
+	for _, tt := range tests {
+		var entry reader.LogEntry
+
+		if err := parseInto(tt.record, &entry); err == nil || err == reader.ErrSkipLine {
+			t.Errorf("%s: expected error, got %v", tt.name, err)
+		}
+	}
+}
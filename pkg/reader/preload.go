@@ -0,0 +1,144 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unsafe"
+)
+
+// SliceReader returns entries held in memory
+type SliceReader struct {
+	Entries []*LogEntry
+	pos     int
+}
+
+// NewSliceReader creates reader returning the entries in order
+func NewSliceReader(entries []*LogEntry) LogReader {
+	return &SliceReader{Entries: entries}
+}
+
+func (r *SliceReader) Read() (*LogEntry, error) {
+	if r.pos >= len(r.Entries) {
+		return &LogEntry{}, io.EOF
+	}
+
+	entry := r.Entries[r.pos]
+	r.pos++
+
+	return entry, nil
+}
+
+// entrySize estimates memory taken by the entry
+func entrySize(entry *LogEntry) int64 {
+	size := int64(unsafe.Sizeof(*entry))
+
+	for _, field := range stringFields(entry) {
+		size += int64(len(*field))
+	}
+
+	// Map buckets and slices of headers are counted roughly
+	for name, values := range entry.Headers {
+		size += int64(len(name)) + 64
+
+		for _, value := range values {
+			size += int64(len(value)) + int64(unsafe.Sizeof(value))
+		}
+	}
+
+	for _, message := range entry.Messages {
+		size += int64(len(message.Data)) + int64(unsafe.Sizeof(message))
+	}
+
+	return size
+}
+
+// stringFields returns pointers to string fields of the entry
+func stringFields(entry *LogEntry) []*string {
+	return []*string{&entry.Method, &entry.URL, &entry.Payload, &entry.UA, &entry.Referer, &entry.Host, &entry.ClientIP, &entry.Scheme, &entry.ContentType}
+}
+
+// ErrPreloadLimit is returned by Preload when entries do not fit into the memory limit
+var ErrPreloadLimit = errors.New("preloaded entries exceed the memory limit")
+
+// compact copies the entry with its strings in a single buffer, so that entries do not keep
+// whole log lines (or chunks of entries) they were parsed from in memory
+func compact(entry *LogEntry) *LogEntry {
+	copied := *entry
+	fields := stringFields(&copied)
+
+	if entry.Headers != nil {
+		copied.Headers = make(http.Header, len(entry.Headers))
+
+		for name, values := range entry.Headers {
+			copied.Headers[name] = append([]string(nil), values...)
+		}
+
+		for _, values := range copied.Headers {
+			for i := range values {
+				fields = append(fields, &values[i])
+			}
+		}
+	}
+
+	if entry.Messages != nil {
+		copied.Messages = append([]WebSocketMessage(nil), entry.Messages...)
+
+		for i := range copied.Messages {
+			fields = append(fields, &copied.Messages[i].Data)
+		}
+	}
+
+	var n int
+
+	for _, field := range fields {
+		n += len(*field)
+	}
+
+	var b strings.Builder
+
+	b.Grow(n)
+
+	for _, field := range fields {
+		b.WriteString(*field)
+	}
+
+	buf := b.String()
+
+	for _, field := range fields {
+		*field, buf = buf[:len(*field)], buf[len(*field):]
+	}
+
+	return &copied
+}
+
+// Preload reads all entries of the reader into memory and returns their estimated size,
+// reading fails once the size exceeds maxBytes, 0 means no limit
+func Preload(ctx context.Context, r LogReader, maxBytes int64) ([]*LogEntry, int64, error) {
+	var entries []*LogEntry
+	var size int64
+
+	for {
+		entry, err := ReadContext(ctx, r)
+
+		if err == io.EOF {
+			return entries, size, nil
+		}
+
+		if err != nil {
+			return nil, size, err
+		}
+
+		entry = compact(entry)
+		entries = append(entries, entry)
+		// Pointers of the slice are counted too
+		size += entrySize(entry) + int64(unsafe.Sizeof(entry))
+
+		if maxBytes > 0 && size > maxBytes {
+			return nil, size, fmt.Errorf("%w of %d bytes after %d entries", ErrPreloadLimit, maxBytes, len(entries))
+		}
+	}
+}
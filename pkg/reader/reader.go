@@ -1,19 +1,51 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	nginxTimeLayout = "2/Jan/2006:15:04:05 -0700"
+)
+
 // LogEntry is single parsed entry from the log file
 type LogEntry struct {
 	Time    time.Time
 	Method  string
 	URL     string
 	Payload string
-	UA string
+	UA      string
+	Referer string
+	Host    string
+	// ClientIP is the address of the original client, empty when unknown
+	ClientIP string
+	// Scheme (http or https) and ContentType of the original request, empty when unknown
+	Scheme      string
+	ContentType string
+	// Headers are other logged request headers, nil when there are none
+	Headers http.Header
+	// Status is the response status originally logged, 0 when unknown
+	Status int
+	// Duration is how long the request originally took, 0 when unknown
+	Duration time.Duration
+	// WebSocket entries opened WebSocket sessions the Messages were exchanged in
+	WebSocket bool
+	Messages  []WebSocketMessage
+}
+
+// WebSocketMessage is a message of WebSocket session, Offset is the time since the session was opened
+type WebSocketMessage struct {
+	Offset time.Duration
+	// Sent by the client, otherwise received from the server
+	Sent   bool
+	Binary bool
+	Data   string
 }
 
 // LogReader provides generic log parser interface
@@ -21,6 +53,36 @@ type LogReader interface {
 	Read() (*LogEntry, error)
 }
 
+// InputError is an error of reading the input (e.g. too long line or I/O error) rather than of parsing a line,
+// reading can not continue after it
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// NewInputError wraps error of reading the input, nil stays nil
+func NewInputError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &InputError{Err: err}
+}
+
+// IsInputError tells whether the error ends the input, other errors are of single lines which can be skipped
+func IsInputError(err error) bool {
+	var inputErr *InputError
+
+	return errors.As(err, &inputErr)
+}
+
 func ParseRequest(requestString string) ([]string, error) {
 	parsedRequest := strings.SplitN(requestString, " ", 3)
 
@@ -31,6 +93,198 @@ func ParseRequest(requestString string) ([]string, error) {
 	return parsedRequest, nil
 }
 
+// ParseStatus parses logged response status, "-" and other non numeric values are reported as unknown (0)
+func ParseStatus(value string) int {
+	status, err := strconv.Atoi(value)
+
+	if err != nil {
+		return 0
+	}
+
+	return status
+}
+
+// grpcStatuses are HTTP statuses equivalent to gRPC status codes, the same as grpc-gateway uses
+var grpcStatuses = []int{
+	200, // OK
+	499, // CANCELLED
+	500, // UNKNOWN
+	400, // INVALID_ARGUMENT
+	504, // DEADLINE_EXCEEDED
+	404, // NOT_FOUND
+	409, // ALREADY_EXISTS
+	403, // PERMISSION_DENIED
+	429, // RESOURCE_EXHAUSTED
+	400, // FAILED_PRECONDITION
+	409, // ABORTED
+	400, // OUT_OF_RANGE
+	501, // UNIMPLEMENTED
+	500, // INTERNAL
+	503, // UNAVAILABLE
+	500, // DATA_LOSS
+	401, // UNAUTHENTICATED
+}
+
+// grpcCodes are upper case names of gRPC status codes without underscores
+var grpcCodes = map[string]int{
+	"OK": 0, "CANCELLED": 1, "CANCELED": 1, "UNKNOWN": 2, "INVALIDARGUMENT": 3, "DEADLINEEXCEEDED": 4, "NOTFOUND": 5,
+	"ALREADYEXISTS": 6, "PERMISSIONDENIED": 7, "RESOURCEEXHAUSTED": 8, "FAILEDPRECONDITION": 9, "ABORTED": 10,
+	"OUTOFRANGE": 11, "UNIMPLEMENTED": 12, "INTERNAL": 13, "UNAVAILABLE": 14, "DATALOSS": 15, "UNAUTHENTICATED": 16,
+}
+
+// GRPCStatus parses gRPC status code given by number or name (NOT_FOUND, NotFound) into the equivalent HTTP status,
+// unknown codes are reported as unknown (0)
+func GRPCStatus(value string) int {
+	code, err := strconv.Atoi(value)
+
+	if err != nil {
+		var ok bool
+
+		if code, ok = grpcCodes[strings.ToUpper(strings.Replace(value, "_", "", -1))]; !ok {
+			return 0
+		}
+	}
+
+	if code < 0 || code >= len(grpcStatuses) {
+		return 0
+	}
+
+	return grpcStatuses[code]
+}
+
+// ParseDuration parses logged duration given in the unit (e.g. time.Second for nginx $request_time),
+// "-", negative and other non numeric values are reported as unknown (0)
+func ParseDuration(value string, unit time.Duration) time.Duration {
+	f, err := strconv.ParseFloat(strings.TrimPrefix(value, "+"), 64)
+
+	if err != nil || f < 0 {
+		return 0
+	}
+
+	return time.Duration(f * float64(unit))
+}
+
+// ParseClientIP parses logged client address, the port (ip:port, [ipv6]:port) is dropped and only
+// the first address of X-Forwarded-For like lists is kept, "-" is reported as unknown (empty)
+func ParseClientIP(value string) string {
+	value, _, _ = strings.Cut(value, ",")
+	value = strings.TrimSpace(value)
+
+	if value == "-" {
+		return ""
+	}
+
+	if strings.HasPrefix(value, "[") {
+		if end := strings.IndexByte(value, ']'); end > 0 {
+			return value[1:end]
+		}
+	}
+
+	// Bare IPv6 addresses have more than one colon
+	if host, _, ok := strings.Cut(value, ":"); ok && strings.Count(value, ":") == 1 {
+		return host
+	}
+
+	return value
+}
+
+// SetHeader adds the logged header to the entry, "-" and empty values are skipped
+func (e *LogEntry) SetHeader(name, value string) {
+	if value == "" || value == "-" {
+		return
+	}
+
+	if e.Headers == nil {
+		e.Headers = make(http.Header)
+	}
+
+	e.Headers.Set(name, value)
+}
+
+// SetRequestHeaders takes UA, Referer, ContentType and the rest of Headers of the entry from headers
+// of a captured request
+func (e *LogEntry) SetRequestHeaders(header http.Header) {
+	for name, values := range header {
+		switch name {
+		case "User-Agent":
+			e.UA = values[0]
+		case "Referer":
+			e.Referer = values[0]
+		case "Content-Type":
+			e.ContentType = values[0]
+		default:
+			if e.Headers == nil {
+				e.Headers = make(http.Header)
+			}
+
+			e.Headers[name] = values
+		}
+	}
+}
+
+// ParseTime parses timestamp using provided layout, if layout is empty
+// nginx time_local, RFC3339 and unix timestamp (with fractional part) formats are tried in order
+func ParseTime(layout string, value string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, value)
+	}
+
+	if t, err := time.Parse(nginxTimeLayout, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+
+	// nginx $msec is unix time in seconds with milliseconds resolution
+	if epoch, err := strconv.ParseFloat(value, 64); err == nil {
+		sec := int64(epoch)
+		nsec := int64((epoch - float64(sec)) * float64(time.Second))
+		return time.Unix(sec, nsec), nil
+	}
+
+	return time.Time{}, fmt.Errorf("Unable to parse time '%s'", value)
+}
+
+// SplitQuoted splits space separated fields, keeping double quoted fields
+// (with backslash escaped quotes inside) together and without the quotes
+func SplitQuoted(s string) []string {
+	var fields []string
+	var field strings.Builder
+
+	inQuotes := false
+	inField := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(s):
+			i++
+			field.WriteByte(s[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			inField = true
+		case c == ' ' && !inQuotes:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteByte(c)
+			inField = true
+		}
+	}
+
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
 func Must(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,82 @@
+package reader
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitQuoted(t *testing.T) {
+	tests := []struct {
+		s      string
+		fields []string
+	}{
+		{s: "", fields: nil},
+		{s: "a b  c", fields: []string{"a", "b", "c"}},
+		{s: ` a "b c" d `, fields: []string{"a", "b c", "d"}},
+		{s: `"GET /a HTTP/1.1" "-" ""`, fields: []string{"GET /a HTTP/1.1", "-", ""}},
+		{s: `"say \"hi\""`, fields: []string{`say "hi"`}},
+		// Backslashes escape only inside quotes
+		{s: `C:\dir "a b"`, fields: []string{`C:\dir`, "a b"}},
+		{s: `"a\\" b`, fields: []string{`a\`, "b"}},
+		{s: `key="quoted value" next`, fields: []string{"key=quoted value", "next"}},
+		{s: `"unterminated quote`, fields: []string{"unterminated quote"}},
+	}
+
+	for _, tt := range tests {
+		if fields := SplitQuoted(tt.s); !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("SplitQuoted(%q) = %q, expected %q", tt.s, fields, tt.fields)
+		}
+	}
+}
+
+func TestParseClientIP(t *testing.T) {
+	tests := []struct {
+		value string
+		ip    string
+	}{
+		{value: "10.0.0.1", ip: "10.0.0.1"},
+		{value: "10.0.0.1:5123", ip: "10.0.0.1"},
+		{value: "10.0.0.1, 172.16.0.1", ip: "10.0.0.1"},
+		{value: "2001:db8::1", ip: "2001:db8::1"},
+		{value: "[2001:db8::1]:443", ip: "2001:db8::1"},
+		{value: "-", ip: ""},
+		{value: "", ip: ""},
+	}
+
+	for _, tt := range tests {
+		if ip := ParseClientIP(tt.value); ip != tt.ip {
+			t.Errorf("ParseClientIP(%q) = %q, expected %q", tt.value, ip, tt.ip)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		layout string
+		value  string
+		time   time.Time
+		err    bool
+	}{
+		{value: "08/Nov/2013:13:39:18 +0000", time: time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC)},
+		{value: "2013-11-08T13:39:18.5+01:00", time: time.Date(2013, time.November, 8, 12, 39, 18, 500000000, time.UTC)},
+		{value: "1383917958.250", time: time.Date(2013, time.November, 8, 13, 39, 18, 250000000, time.UTC)},
+		{layout: "2006-01-02 15:04:05", value: "2013-11-08 13:39:18", time: time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC)},
+		{layout: "2006-01-02 15:04:05", value: "08/Nov/2013:13:39:18 +0000", err: true},
+		{value: "yesterday", err: true},
+	}
+
+	for _, tt := range tests {
+		parsed, err := ParseTime(tt.layout, tt.value)
+
+		if (err != nil) != tt.err {
+			t.Errorf("ParseTime(%q, %q) returned error %v", tt.layout, tt.value, err)
+			continue
+		}
+
+		// Unix timestamps are parsed with float precision
+		if !tt.err && parsed.Sub(tt.time).Abs() > time.Microsecond {
+			t.Errorf("ParseTime(%q, %q) = %s, expected %s", tt.layout, tt.value, parsed, tt.time)
+		}
+	}
+}
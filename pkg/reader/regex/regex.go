@@ -0,0 +1,147 @@
+package regex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// RegexReader implements reader.LogReader interface using user provided regular expression,
+// recognized named groups are time, method, url, request, status, payload, ua, referer, host, client, scheme, content_type,
+// duration (seconds) and http_name for the Name request header (e.g. http_x_request_id)
+type RegexReader struct {
+	InputReader  io.Reader
+	InputScanner *bufio.Scanner
+	Regexp       *regexp.Regexp
+	TimeLayout   string
+}
+
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]bool)
+
+	for _, name := range re.SubexpNames() {
+		groups[name] = true
+	}
+
+	if !groups["time"] {
+		return nil, fmt.Errorf("Regex '%s' has to contain (?P<time>...) group", expr)
+	}
+
+	if !groups["request"] && !(groups["method"] && groups["url"]) {
+		return nil, fmt.Errorf("Regex '%s' has to contain (?P<method>...) and (?P<url>...) groups or (?P<request>...) group", expr)
+	}
+
+	return re, nil
+}
+
+func (r *RegexReader) parseInto(s string, entry *reader.LogEntry) error {
+	matches := r.Regexp.FindStringSubmatch(s)
+
+	if matches == nil {
+		return fmt.Errorf("Line does not match regex '%s': %s", r.Regexp, s)
+	}
+
+	var err error
+
+	for i, name := range r.Regexp.SubexpNames() {
+		value := matches[i]
+
+		switch name {
+		case "time":
+			entry.Time, err = reader.ParseTime(r.TimeLayout, value)
+		case "method":
+			entry.Method = value
+		case "url":
+			entry.URL = value
+		case "request":
+			var parsedRequest []string
+			parsedRequest, err = reader.ParseRequest(value)
+
+			if err == nil && entry.Method == "" {
+				entry.Method = parsedRequest[0]
+				entry.URL = parsedRequest[1]
+			}
+		case "status":
+			entry.Status = reader.ParseStatus(value)
+		case "duration":
+			entry.Duration = reader.ParseDuration(value, time.Second)
+		case "payload":
+			entry.Payload = value
+		case "ua":
+			entry.UA = value
+		case "referer":
+			entry.Referer = value
+		case "host":
+			entry.Host = value
+		case "client":
+			entry.ClientIP = reader.ParseClientIP(value)
+		case "scheme":
+			entry.Scheme = value
+		case "content_type":
+			entry.ContentType = value
+		default:
+			if strings.HasPrefix(name, "http_") {
+				entry.SetHeader(strings.ReplaceAll(name[len("http_"):], "_", "-"), value)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewReader creates new reader for the log format described by the regex using provided io.Reader,
+// empty timeLayout means nginx time_local, RFC3339 and unix timestamps are tried in order
+func NewReader(inputReader io.Reader, expr string, timeLayout string) reader.LogReader {
+	re, err := compileRegexp(expr)
+
+	reader.Must(err)
+
+	var reader RegexReader
+
+	reader.InputReader = inputReader
+	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.Regexp = re
+	reader.TimeLayout = timeLayout
+
+	return &reader
+}
+
+func (r *RegexReader) Read() (*reader.LogEntry, error) {
+	var entry reader.LogEntry
+
+	inputAvailable := r.InputScanner.Scan()
+
+	if !inputAvailable {
+		err := reader.NewInputError(r.InputScanner.Err())
+
+		if err != nil {
+			return &entry, err
+		}
+
+		return &entry, io.EOF
+	}
+
+	err := r.parseInto(r.InputScanner.Text(), &entry)
+
+	return &entry, err
+}
+
+// ParseLine implements reader.LineParser
+func (r *RegexReader) ParseLine(line string, entry *reader.LogEntry) error {
+	return r.parseInto(line, entry)
+}
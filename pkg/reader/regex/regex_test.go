@@ -0,0 +1,116 @@
+package regex
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestCompileRegexp(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: `^(?P<time>\S+) (?P<method>\S+) (?P<url>\S+)`},
+		{expr: `^(?P<time>\S+) "(?P<request>[^"]*)"`},
+		{expr: `^(?P<method>\S+) (?P<url>\S+)`, err: "(?P<time>...)"},
+		{expr: `^(?P<time>\S+) (?P<method>\S+)`, err: "(?P<request>...)"},
+		{expr: `^(?P<time>\S+`, err: "missing closing )"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileRegexp(tt.expr)
+
+		if tt.err == "" && err != nil {
+			t.Errorf("compileRegexp(%q): %v", tt.expr, err)
+		}
+
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("compileRegexp(%q): expected error containing %q, got %v", tt.expr, tt.err, err)
+		}
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		layout string
+		line   string
+		entry  reader.LogEntry
+		err    bool
+	}{
+		{
+			name: "method, url and other groups",
+			expr: `^(?P<client>\S+) \[(?P<time>[^\]]+)\] (?P<method>\S+) (?P<url>\S+) (?P<status>\d+) (?P<duration>[\d.]+) "(?P<ua>[^"]*)" (?P<http_x_request_id>\S+)$`,
+			line: `10.0.0.1:5123 [08/Nov/2013:13:39:18 +0000] GET /a?b=1 200 0.250 "curl/7.29.0" abc-123`,
+			entry: reader.LogEntry{
+				Time:     time.Date(2013, time.November, 8, 13, 39, 18, 0, time.UTC),
+				Method:   "GET",
+				URL:      "/a?b=1",
+				Status:   200,
+				Duration: 250 * time.Millisecond,
+				UA:       "curl/7.29.0",
+				ClientIP: "10.0.0.1",
+				Headers:  http.Header{"X-Request-Id": []string{"abc-123"}},
+			},
+		},
+		{
+			name:   "request and time layout",
+			expr:   `^(?P<time>\S+ \S+) "(?P<request>[^"]*)" (?P<payload>.*)$`,
+			layout: "2006-01-02 15:04:05",
+			line:   `2024-01-01 10:00:00 "POST /api HTTP/1.1" {"a":1}`,
+			entry: reader.LogEntry{
+				Time:    time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+				Method:  "POST",
+				URL:     "/api",
+				Payload: `{"a":1}`,
+			},
+		},
+		{
+			name: "line does not match",
+			expr: `^(?P<time>\S+) (?P<method>[A-Z]+) (?P<url>\S+)$`,
+			line: `2024-01-01T10:00:00Z get /a`,
+			err:  true,
+		},
+		{
+			name:   "invalid time",
+			expr:   `^(?P<time>\S+) (?P<method>[A-Z]+) (?P<url>\S+)$`,
+			layout: time.RFC3339,
+			line:   `1704103200 GET /a`,
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(""), tt.expr, tt.layout).(*RegexReader)
+
+			var entry reader.LogEntry
+
+			err := r.parseInto(tt.line, &entry)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if tt.err {
+				return
+			}
+
+			if !entry.Time.Equal(tt.entry.Time) {
+				t.Errorf("time %s, expected %s", entry.Time, tt.entry.Time)
+			}
+
+			entry.Time = tt.entry.Time
+
+			if !reflect.DeepEqual(entry, tt.entry) {
+				t.Errorf("entry %+v, expected %+v", entry, tt.entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Factory creates reader of a registered log format for the input
+type Factory func(input io.Reader) (LogReader, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{factories: make(map[string]Factory)}
+
+// Register makes the log format available by the name (-file-type of the command line tool),
+// it is meant to be called from init functions of packages or Go plugins with proprietary formats.
+// Registering the same name twice panics
+func Register(name string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("reader: Register factory of %s is nil", name))
+	}
+
+	if _, ok := registry.factories[name]; ok {
+		panic(fmt.Sprintf("reader: Register called twice for %s", name))
+	}
+
+	registry.factories[name] = factory
+}
+
+// Lookup returns factory of the registered log format
+func Lookup(name string) (Factory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	factory, ok := registry.factories[name]
+
+	return factory, ok
+}
+
+// Registered returns sorted names of the registered log formats
+func Registered() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.factories))
+
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -15,10 +15,35 @@ const (
 	solrProxyTsLayout = "2006-01-02 15:04:05.000"
 )
 
+// collectionRegexp finds collection of SolrCloud MDC ([c:name s:shard r:replica x:core]) and core of standalone Solr
+// ([core] before webapp=)
+var collectionRegexp = regexp.MustCompile(`\[c:([^\s\]]+)|\[([^\]\s:]+)\]\s+webapp=`)
+
+// internalParams are added by the node distributing a query to its sub-requests
+var internalParams = map[string]bool{
+	"isShard":        true,
+	"shard.url":      true,
+	"shards.purpose": true,
+	"distrib":        true,
+	"NOW":            true,
+	"fsv":            true,
+	"_stateVer_":     true,
+}
+
+// Options of the solr reader
+type Options struct {
+	// Collections routes requests to /{collection}{path}, the prefix is then the Solr base URL (e.g. http://solr:8983/solr)
+	Collections bool
+	// ShardRequests is what to do with distributed sub-requests (isShard=true): keep them as they are, strip their
+	// internal params or skip them
+	ShardRequests string
+}
+
 // SolrReader implements reader.LogReader intefrace
 type SolrReader struct {
 	InputReader  io.Reader
 	InputScanner *bufio.Scanner
+	Options      Options
 }
 
 func parseSolrTime(timeLocal string) time.Time {
@@ -38,7 +63,34 @@ func parseSolrPayload(params string) (string, error) {
 	return matches[1], nil
 }
 
-func parseSolrInto(s string, entry *reader.LogEntry) error {
+// stripInternalParams removes params of distributed sub-requests from the payload
+func stripInternalParams(payload string) string {
+	params := strings.Split(payload, "&")
+	kept := params[:0]
+
+	for _, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+
+		if !internalParams[name] {
+			kept = append(kept, param)
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// isShardRequest tells whether the payload is of a distributed sub-request
+func isShardRequest(payload string) bool {
+	for _, param := range strings.Split(payload, "&") {
+		if param == "isShard=true" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseSolrInto(s string, entry *reader.LogEntry, opts Options) error {
 	if len(s) < 23 {
 		return fmt.Errorf("This log line does not seem to contain a valid timestamp.")
 	}
@@ -51,6 +103,8 @@ func parseSolrInto(s string, entry *reader.LogEntry) error {
 			requestParts[0] = part
 		} else if strings.HasPrefix(part, "params") {
 			requestParts[1] = part
+		} else if strings.HasPrefix(part, "QTime=") {
+			entry.Duration = reader.ParseDuration(strings.TrimPrefix(part, "QTime="), time.Millisecond)
 		}
 	}
 	//Default solr requests to post to go around query length for GET requests.
@@ -61,39 +115,79 @@ func parseSolrInto(s string, entry *reader.LogEntry) error {
 
 	path := strings.SplitAfterN(requestParts[0], "=", 2)
 
+	if isShardRequest(payload) {
+		switch opts.ShardRequests {
+		case "skip":
+			return reader.ErrSkipLine
+		case "strip":
+			payload = stripInternalParams(payload)
+		}
+	}
+
 	entry.Method = "POST"
 	entry.URL = path[1]
+
+	if opts.Collections {
+		if match := collectionRegexp.FindStringSubmatch(s); match != nil {
+			entry.URL = "/" + match[1] + match[2] + path[1]
+		}
+	}
+
 	entry.Time = parseSolrTime(dateString)
 	entry.Payload = payload
+	entry.ContentType = "application/x-www-form-urlencoded"
 	return nil
 }
 
 // NewReader creates new reader for a solr log format using provided io.Reader
 func NewReader(inputReader io.Reader) reader.LogReader {
+	return NewOptionsReader(inputReader, Options{})
+}
+
+// NewOptionsReader creates new reader for a solr log format routing requests and handling sub-requests
+// of distributed queries as the options say
+func NewOptionsReader(inputReader io.Reader, opts Options) reader.LogReader {
+	switch opts.ShardRequests {
+	case "", "keep", "strip", "skip":
+	default:
+		reader.Must(fmt.Errorf("shard requests can be keep, strip or skip, not '%s'", opts.ShardRequests))
+	}
+
 	var reader SolrReader
 
 	reader.InputReader = inputReader
 	reader.InputScanner = bufio.NewScanner(reader.InputReader)
+	reader.Options = opts
 
 	return &reader
 }
 
+// Read skips sub-requests of distributed queries with ShardRequests skip
 func (r *SolrReader) Read() (*reader.LogEntry, error) {
 	var entry reader.LogEntry
 
-	inputAvailable := r.InputScanner.Scan()
+	for r.InputScanner.Scan() {
+		if parseSolrInto(r.InputScanner.Text(), &entry, r.Options) != reader.ErrSkipLine {
+			return &entry, nil
+		}
 
-	if inputAvailable {
-		parseSolrInto(r.InputScanner.Text(), &entry)
-	} else {
-		return &entry, io.EOF
+		entry = reader.LogEntry{}
 	}
 
-	err := r.InputScanner.Err()
+	err := reader.NewInputError(r.InputScanner.Err())
 
 	if err != nil {
 		return &entry, err
 	}
 
-	return &entry, nil
+	return &entry, io.EOF
+}
+
+// ParseLine implements reader.LineParser, lines which can not be parsed are not reported like in Read
+func (r *SolrReader) ParseLine(line string, entry *reader.LogEntry) error {
+	if parseSolrInto(line, entry, r.Options) == reader.ErrSkipLine {
+		return reader.ErrSkipLine
+	}
+
+	return nil
 }
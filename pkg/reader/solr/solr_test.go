@@ -0,0 +1,124 @@
+package solr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+const (
+	cloudLine      = `2024-01-01 10:00:00,123 INFO o.a.s.c.S.Request [qtp-1-12] [c:products s:shard1 r:core_node2 x:products_shard1_replica_n1] o.a.s.c.S.Request [products_shard1_replica_n1]  webapp=/solr path=/select params={q=name:foo&wt=json} hits=10 status=0 QTime=5`
+	shardLine      = `2024-01-01 10:00:00,124 INFO o.a.s.c.S.Request [qtp-1-13] [c:products s:shard2 r:core_node4 x:products_shard2_replica_n3] o.a.s.c.S.Request [products_shard2_replica_n3]  webapp=/solr path=/select params={df=_text_&distrib=false&shards.purpose=16388&q=name:foo&isShard=true&wt=javabin} hits=4 status=0 QTime=2`
+	collectionLine = `2024-01-01 10:00:00,125 INFO o.a.s.c.S.Request [qtp-1-14] [c:books] o.a.s.c.S.Request webapp=/solr path=/query params={q=*:*} hits=3 status=0 QTime=1`
+	coreLine       = `2024-01-01 10:00:01,000 INFO o.a.s.c.SolrCore [qtp-1-15] [books]  webapp=/solr path=/select params={q=*:*} hits=3 status=0 QTime=12`
+)
+
+func TestParseSolrInto(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		opts     Options
+		url      string
+		payload  string
+		time     time.Time
+		duration time.Duration
+		err      error
+	}{
+		{
+			name:     "path only",
+			line:     cloudLine,
+			url:      "/select",
+			payload:  "q=name:foo&wt=json",
+			time:     time.Date(2024, time.January, 1, 10, 0, 0, 123000000, time.UTC),
+			duration: 5 * time.Millisecond,
+		},
+		{
+			name:     "SolrCloud collection",
+			line:     cloudLine,
+			opts:     Options{Collections: true},
+			url:      "/products/select",
+			payload:  "q=name:foo&wt=json",
+			time:     time.Date(2024, time.January, 1, 10, 0, 0, 123000000, time.UTC),
+			duration: 5 * time.Millisecond,
+		},
+		{
+			name:     "collection without shard and replica",
+			line:     collectionLine,
+			opts:     Options{Collections: true},
+			url:      "/books/query",
+			payload:  "q=*:*",
+			time:     time.Date(2024, time.January, 1, 10, 0, 0, 125000000, time.UTC),
+			duration: time.Millisecond,
+		},
+		{
+			name:     "standalone core",
+			line:     coreLine,
+			opts:     Options{Collections: true},
+			url:      "/books/select",
+			payload:  "q=*:*",
+			time:     time.Date(2024, time.January, 1, 10, 0, 1, 0, time.UTC),
+			duration: 12 * time.Millisecond,
+		},
+		{
+			name:     "shard request kept",
+			line:     shardLine,
+			opts:     Options{ShardRequests: "keep"},
+			url:      "/select",
+			payload:  "df=_text_&distrib=false&shards.purpose=16388&q=name:foo&isShard=true&wt=javabin",
+			time:     time.Date(2024, time.January, 1, 10, 0, 0, 124000000, time.UTC),
+			duration: 2 * time.Millisecond,
+		},
+		{
+			name:     "shard request stripped",
+			line:     shardLine,
+			opts:     Options{ShardRequests: "strip"},
+			url:      "/select",
+			payload:  "df=_text_&q=name:foo&wt=javabin",
+			time:     time.Date(2024, time.January, 1, 10, 0, 0, 124000000, time.UTC),
+			duration: 2 * time.Millisecond,
+		},
+		{
+			name: "shard request skipped",
+			line: shardLine,
+			opts: Options{ShardRequests: "skip"},
+			err:  reader.ErrSkipLine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry reader.LogEntry
+
+			err := parseSolrInto(tt.line, &entry, tt.opts)
+
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if entry.Method != "POST" || entry.ContentType != "application/x-www-form-urlencoded" {
+				t.Errorf("unexpected method %q and content type %q", entry.Method, entry.ContentType)
+			}
+
+			if entry.URL != tt.url {
+				t.Errorf("URL %q, expected %q", entry.URL, tt.url)
+			}
+
+			if entry.Payload != tt.payload {
+				t.Errorf("payload %q, expected %q", entry.Payload, tt.payload)
+			}
+
+			if !entry.Time.Equal(tt.time) {
+				t.Errorf("time %s, expected %s", entry.Time, tt.time)
+			}
+
+			if entry.Duration != tt.duration {
+				t.Errorf("duration %s, expected %s", entry.Duration, tt.duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package reader
+
+import (
+	"strings"
+	"unicode"
+)
+
+// StatementVerb returns the first keyword of SQL statement in upper case (SELECT, INSERT...), leading
+// comments and parentheses are skipped
+func StatementVerb(statement string) string {
+	s := statement
+
+	for {
+		s = strings.TrimLeftFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '(' })
+
+		switch {
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+
+			if end < 0 {
+				return ""
+			}
+
+			s = s[end+2:]
+		case strings.HasPrefix(s, "--"), strings.HasPrefix(s, "#"):
+			end := strings.IndexByte(s, '\n')
+
+			if end < 0 {
+				return ""
+			}
+
+			s = s[end+1:]
+		default:
+			end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+
+			if end < 0 {
+				end = len(s)
+			}
+
+			return strings.ToUpper(s[:end])
+		}
+	}
+}
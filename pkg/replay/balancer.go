@@ -0,0 +1,54 @@
+package replay
+
+import (
+	"sync"
+)
+
+// Target is a URL prefix requests are replayed against
+type Target struct {
+	URL    string
+	Weight int
+}
+
+// balancer spreads requests across targets proportionally to their weights
+// with smooth weighted round-robin
+type balancer struct {
+	mu      sync.Mutex
+	targets []Target
+	current []int
+	total   int
+}
+
+func newBalancer(targets []Target) *balancer {
+	b := &balancer{targets: targets, current: make([]int, len(targets))}
+
+	for _, target := range targets {
+		b.total += target.Weight
+	}
+
+	return b
+}
+
+// Pick returns prefix for the next request
+func (b *balancer) Pick() string {
+	if len(b.targets) == 1 {
+		return b.targets[0].URL
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	best := 0
+
+	for i, target := range b.targets {
+		b.current[i] += target.Weight
+
+		if b.current[i] > b.current[best] {
+			best = i
+		}
+	}
+
+	b.current[best] -= b.total
+
+	return b.targets[best].URL
+}
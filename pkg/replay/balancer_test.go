@@ -0,0 +1,53 @@
+package replay
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBalancerPick(t *testing.T) {
+	tests := []struct {
+		name     string
+		targets  []Target
+		expected string
+	}{
+		{name: "single target", targets: []Target{{URL: "a", Weight: 3}}, expected: "aaa"},
+		{name: "equal weights", targets: []Target{{URL: "a", Weight: 1}, {URL: "b", Weight: 1}, {URL: "c", Weight: 1}}, expected: "abcabc"},
+		// Smooth weighted round-robin interleaves picks of the heavier target
+		{name: "weighted", targets: []Target{{URL: "a", Weight: 5}, {URL: "b", Weight: 1}, {URL: "c", Weight: 1}}, expected: "aabacaaaabacaa"},
+		{name: "two to one", targets: []Target{{URL: "a", Weight: 2}, {URL: "b", Weight: 1}}, expected: "abaaba"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBalancer(tt.targets)
+
+			var picks strings.Builder
+
+			for i := 0; i < len(tt.expected); i++ {
+				picks.WriteString(b.Pick())
+			}
+
+			if picks.String() != tt.expected {
+				t.Errorf("picked %q, expected %q", picks.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestBalancerDistribution(t *testing.T) {
+	targets := []Target{{URL: "a", Weight: 7}, {URL: "b", Weight: 2}, {URL: "c", Weight: 1}}
+	b := newBalancer(targets)
+	counts := make(map[string]int)
+
+	for i := 0; i < 1000; i++ {
+		counts[b.Pick()]++
+	}
+
+	expected := map[string]int{"a": 700, "b": 200, "c": 100}
+
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("picks %v, expected %v", counts, expected)
+	}
+}
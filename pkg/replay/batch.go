@@ -0,0 +1,98 @@
+package replay
+
+import (
+	"sync"
+	"time"
+)
+
+// batcher collects items of sinks sending them in batches, the batch is flushed when it has size items
+// and every interval. Errors of background flushes are returned by the next add
+type batcher struct {
+	mu    sync.Mutex
+	size  int
+	count int
+	err   error
+	// flush sends collected items, it is called with the lock held
+	flush func() error
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newBatcher(size int, interval time.Duration, flush func() error) *batcher {
+	b := &batcher{
+		size:  size,
+		flush: flush,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go b.loop(interval)
+
+	return b
+}
+
+func (b *batcher) loop(interval time.Duration) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+
+			if b.count > 0 {
+				b.count = 0
+
+				if err := b.flush(); err != nil && b.err == nil {
+					b.err = err
+				}
+			}
+
+			b.mu.Unlock()
+		}
+	}
+}
+
+// add calls collect with the lock held to add an item to the batch
+func (b *batcher) add(collect func()) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.err; err != nil {
+		b.err = nil
+		return err
+	}
+
+	collect()
+	b.count++
+
+	if b.count >= b.size {
+		b.count = 0
+		return b.flush()
+	}
+
+	return nil
+}
+
+// close stops the background flushes and sends the last batch
+func (b *batcher) close() error {
+	close(b.stop)
+	<-b.done
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.count > 0 {
+		b.count = 0
+
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+
+	return b.err
+}
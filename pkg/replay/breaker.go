@@ -0,0 +1,343 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trip describes the moment the window reached Options.ErrorRate or Options.MaxLatency
+type Trip struct {
+	Time time.Time
+	// Reason is either "error rate" or "latency"
+	Reason string
+	// ErrorRate is the percentage of failed requests in the window
+	ErrorRate float64
+	// Latency is the Options.LatencyPercentile of request durations in the window
+	Latency time.Duration
+	// Errors, Status5xx and Status4xx break the failures of the window down,
+	// 4xx responses are failures only with Options.Window4xx
+	Errors    int
+	Status5xx int
+	Status4xx int
+	// Cooldown is how long the replay is paused for, 0 means it is stopped
+	Cooldown time.Duration
+}
+
+// pauseGate holds dispatching of requests while the replay is paused by the window or manually,
+// it is open again when all of them resumed
+type pauseGate struct {
+	mu     sync.Mutex
+	holds  map[string]bool
+	resume chan struct{}
+}
+
+// pause closes the gate for the holder, it returns false when the holder already paused it
+func (g *pauseGate) pause(holder string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.holds[holder] {
+		return false
+	}
+
+	if g.holds == nil {
+		g.holds = make(map[string]bool)
+	}
+
+	g.holds[holder] = true
+
+	if g.resume == nil {
+		g.resume = make(chan struct{})
+	}
+
+	return true
+}
+
+// unpause releases the hold, it returns false when the holder did not pause
+func (g *pauseGate) unpause(holder string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.holds[holder] {
+		return false
+	}
+
+	delete(g.holds, holder)
+
+	if len(g.holds) == 0 {
+		close(g.resume)
+		g.resume = nil
+	}
+
+	return true
+}
+
+func (g *pauseGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.resume != nil
+}
+
+// wait blocks while the replay is paused and returns for how long
+func (g *pauseGate) wait(ctx context.Context) (time.Duration, error) {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+
+	if resume == nil {
+		return 0, nil
+	}
+
+	start := time.Now()
+
+	select {
+	case <-resume:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// windowSample is the outcome of a single request tracked by the window
+type windowSample struct {
+	failed   bool
+	status   int
+	duration time.Duration
+}
+
+// slidingWindow keeps the last size samples
+type slidingWindow struct {
+	samples  []windowSample
+	next     int
+	full     bool
+	failures int
+	// classes counts failures by status class, 0 is used for transport errors
+	classes map[int]int
+}
+
+func newSlidingWindow(size int) *slidingWindow {
+	return &slidingWindow{samples: make([]windowSample, size), classes: make(map[int]int)}
+}
+
+func (w *slidingWindow) add(sample windowSample) {
+	if old := w.samples[w.next]; w.full && old.failed {
+		w.failures--
+		w.classes[old.status/100]--
+	}
+
+	if sample.failed {
+		w.failures++
+		w.classes[sample.status/100]++
+	}
+
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % len(w.samples)
+
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// filled returns the samples added so far
+func (w *slidingWindow) filled() []windowSample {
+	if w.full {
+		return w.samples
+	}
+
+	return w.samples[:w.next]
+}
+
+// errorRate is the percentage of failed requests
+func (w *slidingWindow) errorRate() float64 {
+	if len(w.filled()) == 0 {
+		return 0
+	}
+
+	return float64(w.failures) / float64(len(w.filled())) * 100
+}
+
+// latency returns the percentile of request durations
+func (w *slidingWindow) latency(percentile float64) time.Duration {
+	samples := w.filled()
+
+	if len(samples) == 0 {
+		return 0
+	}
+
+	durations := make([]time.Duration, len(samples))
+
+	for i, sample := range samples {
+		durations[i] = sample.duration
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	i := int(math.Ceil(percentile/100*float64(len(durations)))) - 1
+
+	if i < 0 {
+		i = 0
+	}
+
+	return durations[i]
+}
+
+// windowFailure tells if the result counts as a failure in the window
+func (r *Replayer) windowFailure(res *Result) bool {
+	return res.Err != nil || res.Status >= 500 || (r.opts.Window4xx && res.Status >= 400)
+}
+
+// windowLoop tracks the last WindowSize requests and trips once the error rate or the latency
+// percentile is reached: the replay is paused for the cooldown and resumed with an empty window,
+// or stopped when there is no cooldown. OnTrip runs on its own as hooks can be slow, it is waited for
+// when the window is closed
+func (r *Replayer) windowLoop(stop func()) error {
+	window := newSlidingWindow(r.opts.WindowSize)
+	var err error
+	var hooks sync.WaitGroup
+
+	var tick <-chan time.Time
+
+	if r.opts.WindowInterval > 0 {
+		ticker := time.NewTicker(r.opts.WindowInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var resume <-chan time.Time
+	var trips int
+
+	notify := func() {
+		if len(r.windowObservers) == 0 {
+			return
+		}
+
+		state := WindowState{
+			Samples:   len(window.filled()),
+			Size:      r.opts.WindowSize,
+			ErrorRate: window.errorRate(),
+			Latency:   window.latency(r.opts.LatencyPercentile),
+			Paused:    resume != nil,
+			Trips:     trips,
+		}
+
+		for _, observer := range r.windowObservers {
+			observer.WindowUpdated(state)
+		}
+	}
+
+	evaluate := func() {
+		if err != nil || resume != nil {
+			return
+		}
+
+		defer notify()
+
+		if !window.full {
+			return
+		}
+
+		trip := Trip{
+			Time:      time.Now(),
+			ErrorRate: window.errorRate(),
+			Cooldown:  r.opts.WindowCooldown,
+			Errors:    window.classes[0],
+			Status5xx: window.classes[5],
+			Status4xx: window.classes[4],
+		}
+
+		// Sorting the window for the percentile on every sample is only worth it when it is used
+		latencyKnown := r.opts.MaxLatency > 0 || len(r.windowObservers) > 0
+
+		if latencyKnown {
+			trip.Latency = window.latency(r.opts.LatencyPercentile)
+		}
+
+		var message string
+
+		switch {
+		case trip.ErrorRate >= r.opts.ErrorRate:
+			trip.Reason = "error rate"
+			message = fmt.Sprintf("Error rate %.2f%% reached (%d errors, %d 5xx", trip.ErrorRate, trip.Errors, trip.Status5xx)
+
+			if r.opts.Window4xx {
+				message += fmt.Sprintf(", %d 4xx", trip.Status4xx)
+			}
+
+			message += ")"
+		case r.opts.MaxLatency > 0 && trip.Latency > r.opts.MaxLatency:
+			trip.Reason = "latency"
+			message = fmt.Sprintf("p%g latency %s exceeded %s", r.opts.LatencyPercentile, trip.Latency, r.opts.MaxLatency)
+		default:
+			return
+		}
+
+		trips++
+
+		if r.opts.OnTrip != nil {
+			if !latencyKnown {
+				trip.Latency = window.latency(r.opts.LatencyPercentile)
+			}
+
+			hooks.Add(1)
+
+			go func() {
+				defer hooks.Done()
+
+				r.opts.OnTrip(trip)
+			}()
+		}
+
+		if trip.Cooldown > 0 {
+			log.Printf("%s, pausing replay for %s", message, trip.Cooldown)
+			r.gate.pause("window")
+			resume = time.After(trip.Cooldown)
+		} else {
+			log.Println(message)
+
+			if trip.Reason == "latency" {
+				err = ErrLatencyExceeded
+			} else {
+				err = ErrErrorRateExceeded
+			}
+
+			stop()
+		}
+	}
+
+	for {
+		select {
+		case sample, ok := <-r.window:
+			if !ok {
+				r.gate.unpause("window")
+				hooks.Wait()
+
+				return err
+			}
+
+			// Results of requests sent before the pause do not count
+			if err != nil || resume != nil {
+				continue
+			}
+
+			window.add(sample)
+
+			if tick == nil {
+				evaluate()
+			}
+		case <-tick:
+			evaluate()
+		case <-resume:
+			log.Println("Resuming replay")
+			resume = nil
+			window = newSlidingWindow(r.opts.WindowSize)
+			r.gate.unpause("window")
+			notify()
+		}
+	}
+}
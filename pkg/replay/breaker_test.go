@@ -0,0 +1,91 @@
+package replay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewErrorRate(t *testing.T) {
+	tests := []struct {
+		errorRate float64
+		err       bool
+	}{
+		{errorRate: 0.5},
+		{errorRate: 40},
+		{errorRate: 100},
+		{errorRate: 0, err: true},
+		{errorRate: -1, err: true},
+		{errorRate: 100.1, err: true},
+	}
+
+	for _, tt := range tests {
+		_, err := New(Options{EnableWindow: true, WindowSize: 10, ErrorRate: tt.errorRate}, nil)
+
+		if (err != nil) != tt.err {
+			t.Errorf("error rate %g: got error %v, expected error %t", tt.errorRate, err, tt.err)
+		}
+	}
+}
+
+func TestWindowLoopTripHook(t *testing.T) {
+	release := make(chan struct{})
+	called := make(chan Trip, 1)
+
+	r := &Replayer{
+		opts: Options{
+			WindowSize:     2,
+			ErrorRate:      50,
+			WindowCooldown: time.Hour,
+			OnTrip: func(trip Trip) {
+				called <- trip
+				<-release
+			},
+		},
+		window: make(chan windowSample),
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- r.windowLoop(func() {})
+	}()
+
+	r.window <- windowSample{failed: true, status: 503}
+	r.window <- windowSample{failed: true, status: 503}
+
+	select {
+	case trip := <-called:
+		if trip.Reason != "error rate" || trip.Status5xx != 2 {
+			t.Errorf("unexpected trip %+v", trip)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("trip hook was not called")
+	}
+
+	// The loop keeps taking samples while the hook is running
+	select {
+	case r.window <- windowSample{}:
+	case <-time.After(5 * time.Second):
+		t.Fatal("window loop is blocked by the trip hook")
+	}
+
+	close(r.window)
+
+	// Shutdown waits for the hook
+	select {
+	case <-done:
+		t.Fatal("window loop returned before the trip hook finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("window loop did not return")
+	}
+}
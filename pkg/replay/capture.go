@@ -0,0 +1,71 @@
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BodyCapture is a sink storing captured response bodies as files of a directory, it has to come
+// before output sinks as it moves Result.Body to Result.BodyFile. index.tsv of the directory lists
+// file name, status, method, url and whether the body was truncated for every body
+type BodyCapture struct {
+	dir   string
+	index *os.File
+	w     *bufio.Writer
+	count int
+}
+
+// NewBodyCapture creates the directory when it does not exist yet
+func NewBodyCapture(dir string) (*BodyCapture, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	index, err := os.Create(filepath.Join(dir, "index.tsv"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &BodyCapture{dir: dir, index: index, w: bufio.NewWriter(index)}, nil
+}
+
+// Write stores the body of the result when there is one
+func (c *BodyCapture) Write(r *Result) error {
+	if r.Body == nil {
+		return nil
+	}
+
+	c.count++
+	name := fmt.Sprintf("%06d-%d.body", c.count, r.Status)
+
+	if err := ioutil.WriteFile(filepath.Join(c.dir, name), r.Body, 0644); err != nil {
+		return err
+	}
+
+	fields := []string{name, strconv.Itoa(r.Status), r.Method, r.URL, strconv.FormatBool(r.BodyTruncated)}
+
+	if _, err := c.w.WriteString(strings.Join(fields, "\t") + "\n"); err != nil {
+		return err
+	}
+
+	r.BodyFile = filepath.Join(c.dir, name)
+	r.Body = nil
+
+	return nil
+}
+
+// Close flushes and closes the index
+func (c *BodyCapture) Close() error {
+	if err := c.w.Flush(); err != nil {
+		c.index.Close()
+		return err
+	}
+
+	return c.index.Close()
+}
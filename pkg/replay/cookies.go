@@ -0,0 +1,47 @@
+package replay
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+)
+
+// cookieJars keeps cookies set by responses separately for every original client IP,
+// jars are kept for the whole replay
+type cookieJars struct {
+	mu   sync.Mutex
+	jars map[string]http.CookieJar
+}
+
+func newCookieJars() *cookieJars {
+	return &cookieJars{jars: make(map[string]http.CookieJar)}
+}
+
+// jar returns cookie jar of the client, it is created on the first request of the client
+func (c *cookieJars) jar(clientIP string) http.CookieJar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jar, ok := c.jars[clientIP]
+
+	if !ok {
+		// Without public suffix list any domain cookie is accepted, replayed hosts are trusted anyway
+		jar, _ = cookiejar.New(nil)
+		c.jars[clientIP] = jar
+	}
+
+	return jar
+}
+
+// withCookies returns copy of the client using cookie jar of the original client, requests of entries
+// without client IP are sent without cookies, as well as all requests when jars are nil
+func withCookies(client *http.Client, jars *cookieJars, clientIP string) *http.Client {
+	if jars == nil || clientIP == "" {
+		return client
+	}
+
+	withJar := *client
+	withJar.Jar = jars.jar(clientIP)
+
+	return &withJar
+}
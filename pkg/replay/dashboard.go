@@ -0,0 +1,214 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// How many log lines are kept at the bottom of the dashboard
+const dashboardLogLines = 5
+
+// Dashboard is a sink drawing live replay state on a terminal, transport errors and 5xx responses count as failures
+type Dashboard struct {
+	mu       sync.Mutex
+	start    time.Time
+	total    int
+	errors   int
+	inFlight int64
+	lag      time.Duration
+	logTime  time.Time
+	window   *WindowState
+	logLines []string
+
+	// Counters of the current interval, reset on every redraw
+	intervalStart   time.Time
+	intervalTotal   int
+	intervalErrors  int
+	intervalLatency *hdrhistogram.Histogram
+}
+
+// NewDashboard creates dashboard with no results
+func NewDashboard() *Dashboard {
+	now := time.Now()
+
+	return &Dashboard{
+		start:           now,
+		intervalStart:   now,
+		intervalLatency: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+	}
+}
+
+// Write accounts finished request
+func (d *Dashboard) Write(r *Result) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight--
+	d.total++
+	d.intervalTotal++
+
+	if r.Err != nil || r.Status >= 500 {
+		d.errors++
+		d.intervalErrors++
+	}
+
+	recordDuration(d.intervalLatency, r.Duration)
+
+	return nil
+}
+
+// RequestStarted accounts request being sent to the target
+func (d *Dashboard) RequestStarted() {
+	d.mu.Lock()
+	d.inFlight++
+	d.mu.Unlock()
+}
+
+// Dispatched records how far behind the schedule the replay is and the log time it reached
+func (d *Dashboard) Dispatched(lag time.Duration, logTime time.Time) {
+	d.mu.Lock()
+	d.lag = lag
+	d.logTime = logTime
+	d.mu.Unlock()
+}
+
+// WindowUpdated records the error window state
+func (d *Dashboard) WindowUpdated(state WindowState) {
+	d.mu.Lock()
+	d.window = &state
+	d.mu.Unlock()
+}
+
+// dashboardLog keeps the last log lines to be shown on the dashboard
+type dashboardLog struct {
+	d *Dashboard
+}
+
+func (l dashboardLog) Write(p []byte) (int, error) {
+	l.d.mu.Lock()
+	defer l.d.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		l.d.logLines = append(l.d.logLines, line)
+	}
+
+	if len(l.d.logLines) > dashboardLogLines {
+		l.d.logLines = l.d.logLines[len(l.d.logLines)-dashboardLogLines:]
+	}
+
+	return len(p), nil
+}
+
+// LogWriter returns writer showing its lines at the bottom of the dashboard,
+// log output is meant to be redirected there while the dashboard is drawn
+func (d *Dashboard) LogWriter() io.Writer {
+	return dashboardLog{d: d}
+}
+
+// Run redraws the dashboard every interval until the context is done, the last frame is left on the screen
+func (d *Dashboard) Run(ctx context.Context, out io.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Hide the cursor while drawing
+	fmt.Fprint(out, "\x1b[?25l\x1b[2J")
+	defer fmt.Fprint(out, "\x1b[?25h")
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.draw(out)
+			return
+		case <-ticker.C:
+			d.draw(out)
+		}
+	}
+}
+
+func (d *Dashboard) draw(out io.Writer) {
+	var buf bytes.Buffer
+
+	d.render(&buf)
+
+	// Move to the top left corner, clear every line before overwriting it and the rest of the screen after
+	frame := strings.ReplaceAll(buf.String(), "\n", "\x1b[K\n")
+	fmt.Fprint(out, "\x1b[H"+frame+"\x1b[J")
+}
+
+func (d *Dashboard) render(out io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(d.intervalStart)
+
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "log-replay\t%s elapsed\n\n", now.Sub(d.start).Round(time.Second))
+
+	var rps, errorRate float64
+
+	if elapsed > 0 {
+		rps = float64(d.intervalTotal) / elapsed.Seconds()
+	}
+
+	if d.intervalTotal > 0 {
+		errorRate = float64(d.intervalErrors) * 100 / float64(d.intervalTotal)
+	}
+
+	latency := func(q float64) time.Duration {
+		if d.intervalTotal == 0 {
+			return 0
+		}
+
+		return time.Duration(d.intervalLatency.ValueAtQuantile(q)).Round(time.Microsecond)
+	}
+
+	fmt.Fprintf(w, "Throughput:\t%.2f req/s\n", rps)
+	fmt.Fprintf(w, "Failures:\t%.2f%%\n", errorRate)
+	fmt.Fprintf(w, "Latency:\tp50 %s\tp90 %s\tp99 %s\n", latency(50), latency(90), latency(99))
+	fmt.Fprintf(w, "In flight:\t%d\n", d.inFlight)
+	fmt.Fprintf(w, "Requests:\t%d (%d failed)\n\n", d.total, d.errors)
+
+	if d.logTime.IsZero() {
+		fmt.Fprintf(w, "Log time:\t-\n")
+	} else {
+		fmt.Fprintf(w, "Log time:\t%s\n", d.logTime.Format(time.RFC3339))
+	}
+
+	fmt.Fprintf(w, "Lag:\t%s\n", d.lag.Round(time.Millisecond))
+
+	if d.window != nil {
+		state := "closed"
+
+		if d.window.Paused {
+			state = "paused"
+		}
+
+		fmt.Fprintf(w, "Window:\t%s, %d/%d samples, %.2f%% errors, latency %s, tripped %d times\n", state,
+			d.window.Samples, d.window.Size, d.window.ErrorRate, d.window.Latency.Round(time.Microsecond), d.window.Trips)
+	}
+
+	w.Flush()
+
+	if len(d.logLines) > 0 {
+		fmt.Fprintln(out)
+
+		for _, line := range d.logLines {
+			fmt.Fprintln(out, line)
+		}
+	}
+
+	d.intervalStart = now
+	d.intervalTotal = 0
+	d.intervalErrors = 0
+	d.intervalLatency.Reset()
+}
@@ -0,0 +1,185 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// diffRecord is a single line of the diff log
+type diffRecord struct {
+	Method           string `json:"method"`
+	URL              string `json:"url"`
+	Status           int    `json:"status"`
+	ShadowStatus     int    `json:"shadow_status"`
+	DurationNs       int64  `json:"duration_ns"`
+	ShadowDurationNs int64  `json:"shadow_duration_ns"`
+	BodyHash         string `json:"body_hash,omitempty"`
+	ShadowBodyHash   string `json:"shadow_body_hash,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ShadowError      string `json:"shadow_error,omitempty"`
+}
+
+// diffLogWriter is a sink writing requests with different responses as JSON lines
+type diffLogWriter struct {
+	encoder *json.Encoder
+}
+
+// NewDiffLogWriter creates sink writing requests which got different responses from the shadow target to w
+func NewDiffLogWriter(w io.Writer) ResultSink {
+	return &diffLogWriter{encoder: json.NewEncoder(w)}
+}
+
+func (d *diffLogWriter) Write(r *Result) error {
+	if r.Shadow == nil || !r.StatusDiffers() && !r.BodyDiffers() {
+		return nil
+	}
+
+	return d.encoder.Encode(newDiffRecord(r))
+}
+
+func newDiffRecord(r *Result) *diffRecord {
+	record := &diffRecord{
+		Method:           r.Method,
+		URL:              r.URL,
+		Status:           r.Status,
+		ShadowStatus:     r.Shadow.Status,
+		DurationNs:       r.Duration.Nanoseconds(),
+		ShadowDurationNs: r.Shadow.Duration.Nanoseconds(),
+		BodyHash:         r.BodyHash,
+		ShadowBodyHash:   r.Shadow.BodyHash,
+	}
+
+	if r.Err != nil {
+		record.Error = r.Err.Error()
+	}
+
+	if r.Shadow.Err != nil {
+		record.ShadowError = r.Shadow.Err.Error()
+	}
+
+	return record
+}
+
+// DiffSummary is a sink aggregating differences between the target and the shadow target
+type DiffSummary struct {
+	Total          int
+	StatusDiffers  int
+	BodyDiffers    int
+	ShadowErrors   int
+	Latency        *hdrhistogram.Histogram
+	ShadowLatency  *hdrhistogram.Histogram
+	StatusPairs    map[[2]int]int
+	comparedBodies bool
+}
+
+// NewDiffSummary creates empty comparison summary
+func NewDiffSummary() *DiffSummary {
+	return &DiffSummary{
+		Latency:       hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		ShadowLatency: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		StatusPairs:   make(map[[2]int]int),
+	}
+}
+
+// Write accounts single result with shadow response
+func (d *DiffSummary) Write(r *Result) error {
+	if r.Shadow == nil {
+		return nil
+	}
+
+	d.Total++
+
+	if r.Shadow.Err != nil {
+		d.ShadowErrors++
+	}
+
+	// Only compare latencies of the requests both targets answered
+	if r.Err == nil && r.Shadow.Err == nil {
+		recordDuration(d.Latency, r.Duration)
+		recordDuration(d.ShadowLatency, r.Shadow.Duration)
+	}
+
+	if r.StatusDiffers() {
+		d.StatusDiffers++
+		d.StatusPairs[[2]int{r.ReportedStatus(), r.Shadow.ReportedStatus()}]++
+	}
+
+	if r.BodyHash != "" && r.Shadow.BodyHash != "" {
+		d.comparedBodies = true
+
+		if r.BodyDiffers() {
+			d.BodyDiffers++
+		}
+	}
+
+	return nil
+}
+
+func formatDelta(d time.Duration) string {
+	if d >= 0 {
+		return "+" + d.String()
+	}
+
+	return d.String()
+}
+
+// Print writes human readable comparison report
+func (d *DiffSummary) Print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	if d.Total == 0 {
+		return
+	}
+
+	percent := func(n int) float64 {
+		return float64(n) * 100 / float64(d.Total)
+	}
+
+	fmt.Fprintf(w, "Shadow:\n")
+	fmt.Fprintf(w, "  errors\t%d (%.2f%%)\n", d.ShadowErrors, percent(d.ShadowErrors))
+	fmt.Fprintf(w, "  status diff\t%d (%.2f%%)\n", d.StatusDiffers, percent(d.StatusDiffers))
+
+	var pairs [][2]int
+
+	for pair := range d.StatusPairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0] || pairs[i][0] == pairs[j][0] && pairs[i][1] < pairs[j][1]
+	})
+
+	for _, pair := range pairs {
+		fmt.Fprintf(w, "    %d -> %d\t%d\n", pair[0], pair[1], d.StatusPairs[pair])
+	}
+
+	if d.comparedBodies {
+		fmt.Fprintf(w, "  body diff\t%d (%.2f%%)\n", d.BodyDiffers, percent(d.BodyDiffers))
+	}
+
+	if d.Latency.TotalCount() == 0 {
+		return
+	}
+
+	value := func(v int64) time.Duration {
+		return time.Duration(v).Round(time.Microsecond)
+	}
+
+	fmt.Fprintf(w, "Shadow latency:\ttarget\tshadow\tdelta\n")
+
+	for _, q := range []float64{50, 90, 99} {
+		a := value(d.Latency.ValueAtQuantile(q))
+		b := value(d.ShadowLatency.ValueAtQuantile(q))
+		fmt.Fprintf(w, "  p%g\t%s\t%s\t%s\n", q, a, b, formatDelta(b-a))
+	}
+
+	a, b := value(d.Latency.Max()), value(d.ShadowLatency.Max())
+	fmt.Fprintf(w, "  max\t%s\t%s\t%s\n", a, b, formatDelta(b-a))
+}
@@ -0,0 +1,101 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// EndpointSummary is a sink aggregating results by method and route, paths not matching
+// any of the routes are normalized by replacing identifier segments with {id}. GraphQL
+// requests are told apart by their operations
+type EndpointSummary struct {
+	Endpoints map[string]*Endpoint
+	routes    []Route
+}
+
+// Endpoint aggregates results of a single endpoint
+type Endpoint struct {
+	Method    string
+	Path      string
+	Total     int
+	Errors    int
+	Status5xx int
+	Latency   *hdrhistogram.Histogram
+}
+
+// NewEndpointSummary creates empty endpoint summary, routes are matched in order
+func NewEndpointSummary(routes ...Route) *EndpointSummary {
+	return &EndpointSummary{Endpoints: make(map[string]*Endpoint), routes: routes}
+}
+
+// Write accounts single result
+func (e *EndpointSummary) Write(r *Result) error {
+	path := endpointName(r, e.routes)
+	// Results read back from tsv logs have no method
+	key := strings.TrimSpace(r.Method + " " + path)
+	endpoint, ok := e.Endpoints[key]
+
+	if !ok {
+		endpoint = &Endpoint{
+			Method:  r.Method,
+			Path:    path,
+			Latency: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		}
+		e.Endpoints[key] = endpoint
+	}
+
+	endpoint.Total++
+
+	if r.Err != nil {
+		endpoint.Errors++
+	} else if r.Status >= 500 {
+		endpoint.Status5xx++
+	}
+
+	recordDuration(endpoint.Latency, r.Duration)
+
+	return nil
+}
+
+// Print writes table of the endpoints with the most requests, limit 0 means all of them
+func (e *EndpointSummary) Print(out io.Writer, limit int) {
+	if len(e.Endpoints) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	var endpoints []*Endpoint
+
+	for _, endpoint := range e.Endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Total != endpoints[j].Total {
+			return endpoints[i].Total > endpoints[j].Total
+		}
+
+		return endpoints[i].Method+endpoints[i].Path < endpoints[j].Method+endpoints[j].Path
+	})
+
+	fmt.Fprintf(w, "Endpoints:\trequests\terrors\t5xx\tp50\tp99\n")
+
+	for i, endpoint := range endpoints {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(w, "  ... %d more\n", len(endpoints)-limit)
+			break
+		}
+
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%s\t%s\n", strings.TrimSpace(endpoint.Method+" "+endpoint.Path), endpoint.Total, endpoint.Errors, endpoint.Status5xx,
+			time.Duration(endpoint.Latency.ValueAtQuantile(50)).Round(time.Microsecond),
+			time.Duration(endpoint.Latency.ValueAtQuantile(99)).Round(time.Microsecond))
+	}
+}
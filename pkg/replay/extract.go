@@ -0,0 +1,292 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// extractMaxBytes is how much of response bodies is kept for ExtractRule
+const extractMaxBytes = 1024 * 1024
+
+// ExtractRule takes a value of responses into the Name variable of the session, responses without
+// the value leave the variable as it was
+type ExtractRule struct {
+	Spec string
+	Name string
+	// route limits the rule to responses of requests with path matching it
+	route *Route
+	// json is the path of the value in JSON body, header name of the response header
+	json   []string
+	header string
+	// re is matched against the body or the header, the first group (or the whole match) is the value
+	re *regexp.Regexp
+}
+
+// ParseExtractRule parses [/route ]name=source rules, the source is json:path.to.value (numbers index arrays),
+// header:Name with optional ~regexp or body~regexp
+func ParseExtractRule(spec string) (ExtractRule, error) {
+	rule := ExtractRule{Spec: spec}
+	rest := strings.TrimSpace(spec)
+
+	if strings.HasPrefix(rest, "/") {
+		pattern, after, ok := strings.Cut(rest, " ")
+
+		if !ok {
+			return rule, fmt.Errorf("extract rule has to look like [/route ]name=source, not '%s'", spec)
+		}
+
+		route, err := ParseRoute(pattern)
+
+		if err != nil {
+			return rule, err
+		}
+
+		rule.route = &route
+		rest = strings.TrimSpace(after)
+	}
+
+	name, source, ok := strings.Cut(rest, "=")
+
+	if !ok || name == "" || strings.ContainsAny(name, "{} ") {
+		return rule, fmt.Errorf("extract rule has to look like [/route ]name=source, not '%s'", spec)
+	}
+
+	rule.Name = name
+
+	var expr string
+
+	switch {
+	case strings.HasPrefix(source, "json:") && len(source) > len("json:"):
+		rule.json = strings.Split(strings.TrimPrefix(source, "json:"), ".")
+	case strings.HasPrefix(source, "header:"):
+		rule.header, expr, _ = strings.Cut(strings.TrimPrefix(source, "header:"), "~")
+
+		if rule.header == "" {
+			return rule, fmt.Errorf("missing header name in extract rule '%s'", spec)
+		}
+	case strings.HasPrefix(source, "body~"):
+		expr = strings.TrimPrefix(source, "body~")
+
+		if expr == "" {
+			return rule, fmt.Errorf("missing regexp in extract rule '%s'", spec)
+		}
+	default:
+		return rule, fmt.Errorf("unknown source of extract rule '%s', expected json:path, header:Name or body~regexp", spec)
+	}
+
+	if expr != "" {
+		re, err := regexp.Compile(expr)
+
+		if err != nil {
+			return rule, fmt.Errorf("invalid regexp in extract rule '%s': %s", spec, err)
+		}
+
+		rule.re = re
+	}
+
+	return rule, nil
+}
+
+// needsBody tells whether the value is taken from the response body
+func (rule ExtractRule) needsBody() bool {
+	return rule.header == ""
+}
+
+// extract returns the value of the response of the URL, ok is false when the response does not have it
+func (rule ExtractRule) extract(url string, header http.Header, body []byte) (string, bool) {
+	if rule.route != nil && !rule.route.Match(urlPath(url)) {
+		return "", false
+	}
+
+	if rule.json != nil {
+		return jsonValue(body, rule.json)
+	}
+
+	var value string
+
+	if rule.header != "" {
+		value = header.Get(rule.header)
+
+		if value == "" {
+			return "", false
+		}
+
+		if rule.re == nil {
+			return value, true
+		}
+	} else {
+		value = string(body)
+	}
+
+	match := rule.re.FindStringSubmatch(value)
+
+	switch {
+	case match == nil:
+		return "", false
+	case len(match) > 1:
+		return match[1], true
+	default:
+		return match[0], true
+	}
+}
+
+// jsonValue looks up value of the path in JSON document, objects and arrays are returned as JSON
+func jsonValue(body []byte, path []string) (string, bool) {
+	var value interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	if decoder.Decode(&value) != nil {
+		return "", false
+	}
+
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+
+			if value, ok = v[key]; !ok {
+				return "", false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+
+			if err != nil || i < 0 || i >= len(v) {
+				return "", false
+			}
+
+			value = v[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		encoded, err := json.Marshal(v)
+
+		return string(encoded), err == nil
+	}
+}
+
+// Header is sent with every request, {{name}} variables are substituted in the value
+type Header struct {
+	Name  string
+	Value string
+}
+
+// variables are values extracted from responses by the session they belong to
+type variables struct {
+	mu        sync.Mutex
+	bySession map[string]map[string]string
+}
+
+func newVariables() *variables {
+	return &variables{bySession: make(map[string]map[string]string)}
+}
+
+func (v *variables) set(session, name, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	vars, ok := v.bySession[session]
+
+	if !ok {
+		vars = make(map[string]string)
+		v.bySession[session] = vars
+	}
+
+	vars[name] = value
+}
+
+// expand replaces {{name}} in s with variables of the session, ok is false when some of them are not set yet
+// and are left as they were
+func (v *variables) expand(session, s string) (string, bool) {
+	if !strings.Contains(s, "{{") {
+		return s, true
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	vars := v.bySession[session]
+	ok := true
+
+	var b strings.Builder
+
+	for {
+		start := strings.Index(s, "{{")
+
+		if start < 0 {
+			break
+		}
+
+		end := strings.Index(s[start:], "}}")
+
+		if end < 0 {
+			break
+		}
+
+		end += start
+		value, found := vars[s[start+2:end]]
+
+		if !found {
+			ok = false
+			value = s[start : end+2]
+		}
+
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[end+2:]
+	}
+
+	b.WriteString(s)
+
+	return b.String(), ok
+}
+
+// session returns the session variables of the entry are kept in, the Options.Sessions key or the client IP
+func (r *Replayer) session(entry *reader.LogEntry) string {
+	if r.opts.Sessions != nil {
+		return r.opts.Sessions(entry)
+	}
+
+	return entry.ClientIP
+}
+
+// extractBuffer returns buffer for the response body when some of the extract rules need it
+func (r *Replayer) extractBuffer() *limitedBuffer {
+	for _, rule := range r.opts.Extract {
+		if rule.needsBody() {
+			return &limitedBuffer{limit: extractMaxBytes}
+		}
+	}
+
+	return nil
+}
+
+// extract sets variables of the session to the values of the response
+func (r *Replayer) extract(session, url string, resp *http.Response, body *limitedBuffer) {
+	data, _ := body.body()
+
+	for _, rule := range r.opts.Extract {
+		if value, ok := rule.extract(url, resp.Header, data); ok {
+			r.vars.set(session, rule.Name, value)
+		}
+	}
+}
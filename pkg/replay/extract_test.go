@@ -0,0 +1,92 @@
+package replay
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseExtractRule(t *testing.T) {
+	tests := []struct {
+		spec string
+		name string
+		err  string
+	}{
+		{spec: "token=json:data.token", name: "token"},
+		{spec: " /login token=json:data.token ", name: "token"},
+		{spec: "csrf=header:X-CSRF-Token", name: "csrf"},
+		{spec: "sid=header:Set-Cookie~sid=([^;]+)", name: "sid"},
+		{spec: "id=body~\"id\":(\\d+)", name: "id"},
+		{spec: "/login", err: "has to look like"},
+		{spec: "=json:token", err: "has to look like"},
+		{spec: "{token}=json:token", err: "has to look like"},
+		{spec: "token", err: "has to look like"},
+		{spec: "token=json:", err: "unknown source"},
+		{spec: "token=cookie:sid", err: "unknown source"},
+		{spec: "token=header:", err: "missing header name"},
+		{spec: "token=body~", err: "missing regexp"},
+		{spec: "token=body~(", err: "invalid regexp"},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseExtractRule(tt.spec)
+
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseExtractRule(%q): expected error containing %q, got %v", tt.spec, tt.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseExtractRule(%q): %v", tt.spec, err)
+			continue
+		}
+
+		if rule.Name != tt.name || rule.Spec != tt.spec {
+			t.Errorf("ParseExtractRule(%q) = name %q, spec %q", tt.spec, rule.Name, rule.Spec)
+		}
+	}
+}
+
+func TestExtractRuleExtract(t *testing.T) {
+	header := http.Header{"X-Csrf-Token": []string{"t0k"}, "Set-Cookie": []string{"sid=s1; Path=/"}}
+	body := []byte(`{"data":{"token":"abc","items":[{"id":7},{"id":8}],"user":{"name":"x"},"empty":null},"id":42}`)
+
+	tests := []struct {
+		spec  string
+		url   string
+		value string
+		ok    bool
+	}{
+		{spec: "t=json:data.token", url: "/", value: "abc", ok: true},
+		{spec: "t=json:data.items.1.id", url: "/", value: "8", ok: true},
+		{spec: "t=json:data.user", url: "/", value: `{"name":"x"}`, ok: true},
+		{spec: "t=json:data.items.2.id", url: "/"},
+		{spec: "t=json:data.empty", url: "/"},
+		{spec: "t=json:data.token.more", url: "/"},
+		{spec: "t=header:X-CSRF-Token", url: "/", value: "t0k", ok: true},
+		{spec: "t=header:Set-Cookie~sid=([^;]+)", url: "/", value: "s1", ok: true},
+		{spec: "t=header:X-Missing", url: "/"},
+		{spec: `t=body~"id":\d+`, url: "/", value: `"id":7`, ok: true},
+		{spec: `t=body~"token":"(\w+)"`, url: "/", value: "abc", ok: true},
+		{spec: `t=body~"secret":"(\w+)"`, url: "/"},
+		{spec: "/login t=json:data.token", url: "/login?next=/", value: "abc", ok: true},
+		{spec: "/login t=json:data.token", url: "/logout"},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseExtractRule(tt.spec)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		value, ok := rule.extract(tt.url, header, body)
+
+		if value != tt.value || ok != tt.ok {
+			t.Errorf("%q of %s: got %q, %t, expected %q, %t", tt.spec, tt.url, value, ok, tt.value, tt.ok)
+		}
+	}
+}
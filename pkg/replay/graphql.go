@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// graphQLOperationRegexp finds name of the first named operation in GraphQL document
+var graphQLOperationRegexp = regexp.MustCompile(`\b(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// graphQLRequest is the body of GraphQL POST request
+type graphQLRequest struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName"`
+}
+
+// GraphQLOperation returns operation name of GraphQL request, ok is false for other requests. GraphQL requests
+// have JSON payload with query or operationName (persisted queries), or a batch of them, or query parameter.
+// Operations without name are anonymous, names of batched operations are joined with commas
+func GraphQLOperation(method, url, payload string) (string, bool) {
+	trimmed := strings.TrimSpace(payload)
+
+	if method == "GET" || trimmed == "" {
+		query := queryParam(url, "query")
+
+		if query == "" {
+			return "", false
+		}
+
+		return graphQLOperationName(queryParam(url, "operationName"), query), true
+	}
+
+	var batch []graphQLRequest
+
+	switch trimmed[0] {
+	case '{':
+		var req graphQLRequest
+
+		if json.Unmarshal([]byte(trimmed), &req) != nil {
+			return "", false
+		}
+
+		batch = append(batch, req)
+	case '[':
+		if json.Unmarshal([]byte(trimmed), &batch) != nil || len(batch) == 0 {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	names := make([]string, 0, len(batch))
+
+	for _, req := range batch {
+		if req.Query == "" && req.OperationName == "" {
+			return "", false
+		}
+
+		names = append(names, graphQLOperationName(req.OperationName, req.Query))
+	}
+
+	return strings.Join(names, ","), true
+}
+
+// graphQLOperationName returns the given operation name or the first one of the query
+func graphQLOperationName(name, query string) string {
+	if name != "" {
+		return name
+	}
+
+	if match := graphQLOperationRegexp.FindStringSubmatch(query); match != nil {
+		return match[1]
+	}
+
+	return "anonymous"
+}
@@ -0,0 +1,142 @@
+package replay
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// grpcCodec makes gRPC request messages of payloads, JSON payloads are converted with the descriptors
+type grpcCodec struct {
+	files *protoregistry.Files
+}
+
+// newGRPCCodec loads the FileDescriptorSet file, JSON payloads are not supported without it
+func newGRPCCodec(descriptorSet string) (*grpcCodec, error) {
+	codec := &grpcCodec{}
+
+	if descriptorSet == "" {
+		return codec, nil
+	}
+
+	data, err := ioutil.ReadFile(descriptorSet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var set descriptorpb.FileDescriptorSet
+
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %s", descriptorSet, err)
+	}
+
+	if codec.files, err = protodesc.NewFiles(&set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %s", descriptorSet, err)
+	}
+
+	return codec, nil
+}
+
+// body returns length prefixed message of the call of the method path (/package.Service/Method), the payload
+// is a JSON object or base64 encoded protobuf message
+func (c *grpcCodec) body(path, payload string) ([]byte, error) {
+	var message []byte
+	var err error
+
+	if payload = strings.TrimSpace(payload); strings.HasPrefix(payload, "{") {
+		message, err = c.fromJSON(path, payload)
+	} else if message, err = base64.StdEncoding.DecodeString(payload); err != nil {
+		err = fmt.Errorf("gRPC request of %s is neither JSON nor base64 encoded message: %s", path, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Uncompressed flag and big endian length
+	body := make([]byte, 5+len(message))
+	binary.BigEndian.PutUint32(body[1:5], uint32(len(message)))
+	copy(body[5:], message)
+
+	return body, nil
+}
+
+// fromJSON converts JSON payload to protobuf message of the input type of the method
+func (c *grpcCodec) fromJSON(path, payload string) ([]byte, error) {
+	if c.files == nil {
+		return nil, fmt.Errorf("JSON request of %s needs proto descriptors", path)
+	}
+
+	i := strings.LastIndex(path, "/")
+
+	if i <= 0 {
+		return nil, fmt.Errorf("%s is not a gRPC method, expected /package.Service/Method", path)
+	}
+
+	service := path[strings.LastIndex(path[:i], "/")+1 : i]
+
+	desc, err := c.files.FindDescriptorByName(protoreflect.FullName(service))
+
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found in proto descriptors", service)
+	}
+
+	serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+
+	method := serviceDesc.Methods().ByName(protoreflect.Name(path[i+1:]))
+
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in proto descriptors", path)
+	}
+
+	message := dynamicpb.NewMessage(method.Input())
+
+	if err := protojson.Unmarshal([]byte(payload), message); err != nil {
+		return nil, fmt.Errorf("invalid JSON request of %s: %s", path, err)
+	}
+
+	return proto.Marshal(message)
+}
+
+// grpcStatus returns HTTP status equivalent to grpc-status of the response, which is a trailer or a header
+// of trailers-only responses. Responses without it are reported with their HTTP status (e.g. 502 of a proxy)
+// or as internal errors
+func grpcStatus(resp *http.Response) int {
+	code := resp.Trailer.Get("Grpc-Status")
+
+	if code == "" {
+		code = resp.Header.Get("Grpc-Status")
+	}
+
+	if code == "" {
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode
+		}
+
+		return http.StatusInternalServerError
+	}
+
+	if status := reader.GRPCStatus(code); status != 0 {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
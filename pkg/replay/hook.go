@@ -0,0 +1,27 @@
+package replay
+
+import (
+	"net/http"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// HookRequest is the request of a log entry about to be sent, Options.OnRequest can change it
+type HookRequest struct {
+	Method  string
+	URL     string
+	Payload string
+	// Header values replace the ones the request would be sent with
+	Header http.Header
+}
+
+// RequestHook is called before every request is sent, with variables of Options.Extract substituted.
+// The request is skipped when it returns false and reported as failed when it returns an error
+type RequestHook func(entry *reader.LogEntry, req *HookRequest) (bool, error)
+
+// setHeader replaces values of the request headers with the ones of the header
+func setHeader(req *http.Request, header http.Header) {
+	for name, values := range header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+}
@@ -0,0 +1,198 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	influxMeasurement  = "log_replay"
+	defaultInfluxSize  = 5000
+	defaultInfluxFlush = 5 * time.Second
+)
+
+// Commas, spaces and equal signs have to be escaped in tag values of line protocol
+var influxTagReplacer = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// statusClass returns 2xx, 3xx... or error for failed requests
+func statusClass(r *Result) string {
+	if r.Err != nil {
+		return "error"
+	}
+
+	return strconv.Itoa(r.Status/100) + "xx"
+}
+
+// appendInfluxLine appends the result as InfluxDB line protocol point tagged by method, endpoint,
+// status class and target with nanosecond timestamp
+func appendInfluxLine(buf []byte, r *Result, routes []Route) []byte {
+	buf = append(buf, influxMeasurement...)
+	buf = append(buf, ",method="...)
+	buf = append(buf, influxTagReplacer.Replace(r.Method)...)
+
+	if endpoint := endpointName(r, routes); endpoint != "" {
+		buf = append(buf, ",endpoint="...)
+		buf = append(buf, influxTagReplacer.Replace(endpoint)...)
+	}
+
+	buf = append(buf, ",status_class="...)
+	buf = append(buf, statusClass(r)...)
+
+	if r.Target != "" {
+		buf = append(buf, ",target="...)
+		buf = append(buf, influxTagReplacer.Replace(r.Target)...)
+	}
+
+	buf = append(buf, " duration_ns="...)
+	buf = strconv.AppendInt(buf, r.Duration.Nanoseconds(), 10)
+	buf = append(buf, "i,status="...)
+	buf = strconv.AppendInt(buf, int64(r.ReportedStatus()), 10)
+	buf = append(buf, "i,error="...)
+	buf = strconv.AppendBool(buf, r.Err != nil)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, r.Start.UnixNano(), 10)
+
+	return append(buf, '\n')
+}
+
+// influxWriter writes one line protocol point per result
+type influxWriter struct {
+	w      io.Writer
+	routes []Route
+	buf    []byte
+}
+
+func (i *influxWriter) Write(r *Result) error {
+	i.buf = appendInfluxLine(i.buf[:0], r, i.routes)
+
+	_, err := i.w.Write(i.buf)
+
+	return err
+}
+
+// IsInfluxURL tells if the name is influx+http:// or influx+https:// write URL
+func IsInfluxURL(name string) bool {
+	return strings.HasPrefix(name, "influx+http://") || strings.HasPrefix(name, "influx+https://")
+}
+
+// InfluxHTTPWriter is a sink sending results in line protocol to InfluxDB write endpoint in batches,
+// points are sent when the batch is full and every flush interval, errors of background flushes
+// are returned by the next Write
+type InfluxHTTPWriter struct {
+	client *http.Client
+	url    string
+	user   *url.Userinfo
+	token  string
+	routes []Route
+	batch  *batcher
+	buf    []byte
+}
+
+// NewInfluxHTTPWriter creates writer of influx+http://host:8086/write?db=replay (1.x) or
+// influx+http://host:8086/api/v2/write?org=org&bucket=replay (2.x) URL, query parameters besides
+// the ones passed to InfluxDB are token (sent as Authorization: Token), size (points per request)
+// and flush (interval to send incomplete batches in), credentials in the URL are sent as basic auth
+func NewInfluxHTTPWriter(rawURL string, routes ...Route) (*InfluxHTTPWriter, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("InfluxDB URL has to be influx+http://host:port/write?db=name, not '%s'", rawURL)
+	}
+
+	query := u.Query()
+
+	size := defaultInfluxSize
+
+	if value := query.Get("size"); value != "" {
+		if size, err = strconv.Atoi(value); err != nil || size <= 0 {
+			return nil, fmt.Errorf("InfluxDB batch size has to be a positive number, not '%s'", value)
+		}
+	}
+
+	flush := defaultInfluxFlush
+
+	if value := query.Get("flush"); value != "" {
+		if flush, err = time.ParseDuration(value); err != nil || flush <= 0 {
+			return nil, fmt.Errorf("InfluxDB flush interval has to be a positive duration, not '%s'", value)
+		}
+	}
+
+	w := &InfluxHTTPWriter{
+		client: http.DefaultClient,
+		user:   u.User,
+		token:  query.Get("token"),
+		routes: routes,
+	}
+
+	query.Del("token")
+	query.Del("size")
+	query.Del("flush")
+
+	u.Scheme = strings.TrimPrefix(u.Scheme, "influx+")
+	u.User = nil
+	u.RawQuery = query.Encode()
+	w.url = u.String()
+	w.batch = newBatcher(size, flush, w.flush)
+
+	return w, nil
+}
+
+// Write queues the point, the batch is sent when it is full
+func (w *InfluxHTTPWriter) Write(r *Result) error {
+	return w.batch.add(func() {
+		w.buf = appendInfluxLine(w.buf, r, w.routes)
+	})
+}
+
+// flush sends the batch
+func (w *InfluxHTTPWriter) flush() error {
+	body := bytes.NewReader(w.buf)
+	defer func() { w.buf = w.buf[:0] }()
+
+	req, err := http.NewRequestWithContext(context.Background(), "POST", w.url, body)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	} else if w.user != nil {
+		password, _ := w.user.Password()
+		req.SetBasicAuth(w.user.Username(), password)
+	}
+
+	resp, err := w.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("ERROR %s while writing points to InfluxDB: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+// Close sends the remaining points
+func (w *InfluxHTTPWriter) Close() error {
+	return w.batch.close()
+}
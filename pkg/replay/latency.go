@@ -0,0 +1,99 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// LatencyComparison is a sink comparing replayed request durations with the originally logged ones
+type LatencyComparison struct {
+	Total    int
+	Unknown  int
+	Original *hdrhistogram.Histogram
+	Replayed *hdrhistogram.Histogram
+	// Slower and Faster are distributions of the deltas of requests which took longer and shorter than originally
+	Slower *hdrhistogram.Histogram
+	Faster *hdrhistogram.Histogram
+}
+
+// NewLatencyComparison creates empty latency comparison
+func NewLatencyComparison() *LatencyComparison {
+	return &LatencyComparison{
+		Original: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		Replayed: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		Slower:   hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		Faster:   hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+	}
+}
+
+// Write accounts single result, failed requests and entries without logged duration are skipped
+func (l *LatencyComparison) Write(r *Result) error {
+	if r.OriginalDuration == 0 || r.Err != nil {
+		l.Unknown++
+		return nil
+	}
+
+	l.Total++
+
+	recordDuration(l.Original, r.OriginalDuration)
+	recordDuration(l.Replayed, r.Duration)
+
+	if delta := r.Duration - r.OriginalDuration; delta >= 0 {
+		recordDuration(l.Slower, delta)
+	} else {
+		recordDuration(l.Faster, -delta)
+	}
+
+	return nil
+}
+
+// Print writes human readable comparison report
+func (l *LatencyComparison) Print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	if l.Unknown > 0 {
+		fmt.Fprintf(w, "Latency vs original:\n  unknown\t%d\n", l.Unknown)
+	} else {
+		fmt.Fprintf(w, "Latency vs original:\n")
+	}
+
+	if l.Total == 0 {
+		return
+	}
+
+	value := func(v int64) time.Duration {
+		return time.Duration(v).Round(time.Microsecond)
+	}
+
+	fmt.Fprintf(w, "  \toriginal\treplayed\tdelta\n")
+
+	for _, q := range []float64{50, 90, 99} {
+		a := value(l.Original.ValueAtQuantile(q))
+		b := value(l.Replayed.ValueAtQuantile(q))
+		fmt.Fprintf(w, "  p%g\t%s\t%s\t%s\n", q, a, b, formatDelta(b-a))
+	}
+
+	a, b := value(l.Original.Max()), value(l.Replayed.Max())
+	fmt.Fprintf(w, "  max\t%s\t%s\t%s\n", a, b, formatDelta(b-a))
+
+	// Deltas of single requests, unlike the percentile differences above
+	delta := func(title string, sign string, h *hdrhistogram.Histogram) {
+		count := int(h.TotalCount())
+
+		if count == 0 {
+			fmt.Fprintf(w, "  %s\t0\n", title)
+			return
+		}
+
+		fmt.Fprintf(w, "  %s\t%d (%.2f%%)\tp50 %s%s\tp99 %s%s\n", title, count, float64(count)*100/float64(l.Total),
+			sign, value(h.ValueAtQuantile(50)), sign, value(h.ValueAtQuantile(99)))
+	}
+
+	delta("slower", "+", l.Slower)
+	delta("faster", "-", l.Faster)
+}
@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds of latency histogram buckets in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics is a sink keeping live replay counters exposed in Prometheus text format
+type Metrics struct {
+	mu           sync.Mutex
+	requests     map[string]uint64
+	inFlight     int64
+	bucketCounts []uint64
+	latencySum   float64
+	latencyCount uint64
+	lag          time.Duration
+	logTime      time.Time
+}
+
+// NewMetrics creates empty metrics
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:     make(map[string]uint64),
+		bucketCounts: make([]uint64, len(latencyBuckets)),
+	}
+}
+
+// RequestStarted accounts request being sent to the target
+func (m *Metrics) RequestStarted() {
+	m.mu.Lock()
+	m.inFlight++
+	m.mu.Unlock()
+}
+
+// Write accounts finished request and its result
+func (m *Metrics) Write(r *Result) error {
+	status := "error"
+
+	if r.Err == nil {
+		status = strconv.Itoa(r.Status)
+	}
+
+	seconds := r.Duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight--
+	m.requests[status]++
+	m.latencySum += seconds
+	m.latencyCount++
+
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			m.bucketCounts[i]++
+		}
+	}
+
+	return nil
+}
+
+// Dispatched records how far behind the schedule the replay is and the log time it reached
+func (m *Metrics) Dispatched(lag time.Duration, logTime time.Time) {
+	m.mu.Lock()
+	m.lag = lag
+	m.logTime = logTime
+	m.mu.Unlock()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Expose writes metrics in Prometheus text exposition format
+func (m *Metrics) Expose(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var statuses []string
+
+	for status := range m.requests {
+		statuses = append(statuses, status)
+	}
+
+	sort.Strings(statuses)
+
+	fmt.Fprintln(w, "# HELP log_replay_requests_total Replayed requests by response status, error means no response was received.")
+	fmt.Fprintln(w, "# TYPE log_replay_requests_total counter")
+
+	for _, status := range statuses {
+		fmt.Fprintf(w, "log_replay_requests_total{status=%q} %d\n", status, m.requests[status])
+	}
+
+	fmt.Fprintln(w, "# HELP log_replay_requests_in_flight Requests sent and waiting for the response.")
+	fmt.Fprintln(w, "# TYPE log_replay_requests_in_flight gauge")
+	fmt.Fprintf(w, "log_replay_requests_in_flight %d\n", m.inFlight)
+
+	fmt.Fprintln(w, "# HELP log_replay_request_duration_seconds Latency of replayed requests.")
+	fmt.Fprintln(w, "# TYPE log_replay_request_duration_seconds histogram")
+
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(w, "log_replay_request_duration_seconds_bucket{le=%q} %d\n", formatFloat(bound), m.bucketCounts[i])
+	}
+
+	fmt.Fprintf(w, "log_replay_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.latencyCount)
+	fmt.Fprintf(w, "log_replay_request_duration_seconds_sum %s\n", formatFloat(m.latencySum))
+	fmt.Fprintf(w, "log_replay_request_duration_seconds_count %d\n", m.latencyCount)
+
+	fmt.Fprintln(w, "# HELP log_replay_lag_seconds How far behind the original (ratio adjusted) log timing the replay is.")
+	fmt.Fprintln(w, "# TYPE log_replay_lag_seconds gauge")
+	fmt.Fprintf(w, "log_replay_lag_seconds %s\n", formatFloat(m.lag.Seconds()))
+
+	if !m.logTime.IsZero() {
+		fmt.Fprintln(w, "# HELP log_replay_log_time_seconds Timestamp of the last dispatched log entry.")
+		fmt.Fprintln(w, "# TYPE log_replay_log_time_seconds gauge")
+		fmt.Fprintf(w, "log_replay_log_time_seconds %s\n", formatFloat(float64(m.logTime.UnixNano())/float64(time.Second)))
+	}
+}
+
+// Serve exposes metrics on /metrics of the given address
+func (m *Metrics) Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.Expose(w)
+	})
+
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,27 @@
+package replay
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Path segments which look like identifiers: numbers, UUIDs and long hex strings
+var idSegmentRegexp = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// normalizePath drops query string and replaces identifier segments with {id},
+// so metrics of /users/1 and /users/2 end up under /users/{id}
+func normalizePath(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+
+	segments := strings.Split(url, "/")
+
+	for i, segment := range segments {
+		if idSegmentRegexp.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
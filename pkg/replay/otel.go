@@ -0,0 +1,215 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otlpTracesPath = "/v1/traces"
+	otlpBatchSize  = 512
+	otlpFlush      = 5 * time.Second
+	// OTLP span kind and status codes
+	otlpSpanKindClient  = 3
+	otlpStatusCodeError = 2
+)
+
+// OTLP/JSON encoding of the trace export request, IDs are hex encoded and 64 bit numbers are strings
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) otlpAttribute {
+	s := strconv.FormatInt(value, 10)
+
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+// OTLPExporter is a sink exporting a client span of every traced request (see Options.TraceHeaders)
+// to OpenTelemetry collector over OTLP/HTTP with JSON encoding
+type OTLPExporter struct {
+	client  *http.Client
+	url     string
+	service string
+	batch   *batcher
+	spans   []otlpSpan
+}
+
+// NewOTLPExporter creates exporter to the collector endpoint (e.g. http://localhost:4318),
+// /v1/traces path is added when the endpoint has none. Spans are sent in batches and every few seconds
+func NewOTLPExporter(endpoint string, service string) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("OTLP endpoint has to be http://host:4318 URL, not '%s'", endpoint)
+	}
+
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = otlpTracesPath
+	}
+
+	e := &OTLPExporter{client: http.DefaultClient, url: u.String(), service: service}
+	e.batch = newBatcher(otlpBatchSize, otlpFlush, e.flush)
+
+	return e, nil
+}
+
+// Write queues span of the result, results without trace context are skipped
+func (e *OTLPExporter) Write(r *Result) error {
+	if r.TraceID == "" {
+		return nil
+	}
+
+	return e.batch.add(func() {
+		e.spans = append(e.spans, newOTLPSpan(r))
+	})
+}
+
+func newOTLPSpan(r *Result) otlpSpan {
+	span := otlpSpan{
+		TraceID:           r.TraceID,
+		SpanID:            r.SpanID,
+		Name:              r.Method,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: strconv.FormatInt(r.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(r.Start.Add(r.Duration).UnixNano(), 10),
+		Attributes: []otlpAttribute{
+			stringAttribute("http.request.method", r.Method),
+			stringAttribute("url.path", r.URL),
+			intAttribute("log_replay.worker", int64(r.Worker)),
+		},
+	}
+
+	if r.Target != "" {
+		span.Attributes = append(span.Attributes, stringAttribute("url.full", r.Target+r.URL))
+	}
+
+	if !r.LogTime.IsZero() {
+		span.Attributes = append(span.Attributes, stringAttribute("log_replay.log_time", r.LogTime.Format(time.RFC3339Nano)))
+	}
+
+	if r.Attempts > 1 {
+		span.Attributes = append(span.Attributes, intAttribute("http.request.resend_count", int64(r.Attempts-1)))
+	}
+
+	switch {
+	case r.Err != nil:
+		span.Attributes = append(span.Attributes, stringAttribute("error.type", "transport"))
+		span.Status = otlpStatus{Code: otlpStatusCodeError, Message: r.Err.Error()}
+	default:
+		span.Attributes = append(span.Attributes, intAttribute("http.response.status_code", int64(r.Status)))
+
+		// Client spans of 4xx and 5xx responses are errors by the HTTP semantic conventions
+		if r.Status >= 400 {
+			span.Attributes = append(span.Attributes, stringAttribute("error.type", strconv.Itoa(r.Status)))
+			span.Status = otlpStatus{Code: otlpStatusCodeError}
+		}
+	}
+
+	return span
+}
+
+// flush sends the batch
+func (e *OTLPExporter) flush() error {
+	var request otlpExportRequest
+	var resource otlpResourceSpans
+	var scope otlpScopeSpans
+
+	resource.Resource.Attributes = []otlpAttribute{stringAttribute("service.name", e.service)}
+	scope.Scope.Name = "log-replay"
+	scope.Spans = e.spans
+	resource.ScopeSpans = []otlpScopeSpans{scope}
+	request.ResourceSpans = []otlpResourceSpans{resource}
+
+	payload, err := json.Marshal(&request)
+	e.spans = e.spans[:0]
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), "POST", e.url, bytes.NewReader(payload))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("ERROR %s while exporting spans to %s: %s", resp.Status, e.url, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+// Close sends the remaining spans
+func (e *OTLPExporter) Close() error {
+	return e.batch.close()
+}
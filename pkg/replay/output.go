@@ -0,0 +1,246 @@
+package replay
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// NewResultWriter creates sink writing results to w in tsv, json (lines), csv or influx (line protocol) format,
+// routes name endpoints the influx points are tagged with
+func NewResultWriter(format string, w io.Writer, routes ...Route) (ResultSink, error) {
+	switch format {
+	case "tsv":
+		return &tsvWriter{w: w}, nil
+	case "json":
+		return &jsonWriter{encoder: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case "influx":
+		return &influxWriter{w: w, routes: routes}, nil
+	default:
+		return nil, fmt.Errorf("output-format can be one of tsv, json, csv or influx, not '%s'", format)
+	}
+}
+
+// tsvWriter writes tab separated values, error column is present only for failed requests
+type tsvWriter struct {
+	w io.Writer
+}
+
+func (t *tsvWriter) Write(r *Result) error {
+	var err error
+
+	if r.Err != nil {
+		_, err = fmt.Fprintf(t.w, "%d\t%d\t%d\t%s\t%s\t%s\n", r.ReportedStatus(), r.Start.Unix(), r.Duration.Nanoseconds(), r.URL, r.Payload, r.Err)
+	} else {
+		_, err = fmt.Fprintf(t.w, "%d\t%d\t%d\t%s\t%s\n", r.ReportedStatus(), r.Start.Unix(), r.Duration.Nanoseconds(), r.URL, r.Payload)
+	}
+
+	return err
+}
+
+// jsonRecord is a single line of JSON lines output
+type jsonRecord struct {
+	Status     int       `json:"status"`
+	TS         time.Time `json:"ts"`
+	DurationNs int64     `json:"duration_ns"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Payload    string    `json:"payload,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Worker     int       `json:"worker"`
+	Target     string    `json:"target,omitempty"`
+	// Set only when redirect was followed
+	InitialStatus int    `json:"initial_status,omitempty"`
+	FinalURL      string `json:"final_url,omitempty"`
+	// Set only when request was retried
+	Attempts int `json:"attempts,omitempty"`
+	// Set only when the body was captured, inline as base64 or in the file
+	Body          []byte `json:"body,omitempty"`
+	BodyFile      string `json:"body_file,omitempty"`
+	BodyTruncated bool   `json:"body_truncated,omitempty"`
+	// Set only when statuses are verified
+	OriginalStatus int  `json:"original_status,omitempty"`
+	StatusMismatch bool `json:"status_mismatch,omitempty"`
+	// Set only when latencies are compared
+	OriginalDurationNs int64 `json:"original_duration_ns,omitempty"`
+	// Set only when response headers are recorded
+	Headers map[string]string `json:"headers,omitempty"`
+	// Set only when trace headers are sent
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+	// Set only for WebSocket sessions
+	WebSocket *webSocketRecord `json:"websocket,omitempty"`
+	// Set only for GraphQL requests
+	Operation string `json:"operation,omitempty"`
+}
+
+// webSocketRecord is the outcome of WebSocket session in json output
+type webSocketRecord struct {
+	CloseCode  int   `json:"close_code,omitempty"`
+	Sent       int   `json:"sent"`
+	Received   int   `json:"received"`
+	DurationNs int64 `json:"duration_ns"`
+}
+
+// jsonWriter writes one JSON object per line
+type jsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonWriter) Write(r *Result) error {
+	return j.encoder.Encode(newJSONRecord(r))
+}
+
+func newJSONRecord(r *Result) *jsonRecord {
+	record := jsonRecord{
+		Status:             r.ReportedStatus(),
+		TS:                 r.Start,
+		DurationNs:         r.Duration.Nanoseconds(),
+		Method:             r.Method,
+		URL:                r.URL,
+		Payload:            r.Payload,
+		Worker:             r.Worker,
+		Target:             r.Target,
+		InitialStatus:      r.InitialStatus,
+		FinalURL:           r.FinalURL,
+		Body:               r.Body,
+		BodyFile:           r.BodyFile,
+		BodyTruncated:      r.BodyTruncated,
+		Headers:            r.Headers,
+		OriginalStatus:     r.OriginalStatus,
+		StatusMismatch:     r.StatusMismatch(),
+		OriginalDurationNs: r.OriginalDuration.Nanoseconds(),
+		TraceID:            r.TraceID,
+		SpanID:             r.SpanID,
+		Operation:          r.Operation,
+	}
+
+	if r.Err != nil {
+		record.Error = r.Err.Error()
+	}
+
+	if r.Attempts > 1 {
+		record.Attempts = r.Attempts
+	}
+
+	if ws := r.WebSocket; ws != nil {
+		record.WebSocket = &webSocketRecord{CloseCode: ws.CloseCode, Sent: ws.Sent, Received: ws.Received, DurationNs: ws.Duration.Nanoseconds()}
+	}
+
+	return &record
+}
+
+// documentWriter hands every result encoded as in json output to send
+type documentWriter struct {
+	send func(doc []byte) error
+}
+
+// NewDocumentWriter creates sink passing results as JSON documents (same fields as json output) to send,
+// e.g. to index them in a search engine or publish them to a message queue
+func NewDocumentWriter(send func(doc []byte) error) ResultSink {
+	return &documentWriter{send: send}
+}
+
+func (d *documentWriter) Write(r *Result) error {
+	doc, err := json.Marshal(newJSONRecord(r))
+
+	if err != nil {
+		return err
+	}
+
+	return d.send(doc)
+}
+
+var csvHeader = []string{"status", "ts", "duration_ns", "method", "url", "payload", "error", "worker", "initial_status", "final_url", "attempts", "target", "original_status", "status_mismatch", "original_duration_ns", "trace_id", "span_id"}
+
+// csvWriter writes comma separated values with a header row, quoting values when needed.
+// Recorded response headers are added as "header:Name" columns
+type csvWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+	headers       []string
+}
+
+func (c *csvWriter) Write(r *Result) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+
+		// Every result has the same headers, empty when missing in the response
+		for name := range r.Headers {
+			c.headers = append(c.headers, name)
+		}
+
+		sort.Strings(c.headers)
+
+		columns := append([]string{}, csvHeader...)
+
+		for _, name := range c.headers {
+			columns = append(columns, "header:"+name)
+		}
+
+		if err := c.writer.Write(columns); err != nil {
+			return err
+		}
+	}
+
+	var errString, initialStatus, originalStatus, statusMismatch, originalDuration string
+
+	if r.Err != nil {
+		errString = r.Err.Error()
+	}
+
+	if r.InitialStatus != 0 {
+		initialStatus = strconv.Itoa(r.InitialStatus)
+	}
+
+	if r.OriginalStatus != 0 {
+		originalStatus = strconv.Itoa(r.OriginalStatus)
+	}
+
+	// Empty unless the statuses differ, like in json output
+	if r.StatusMismatch() {
+		statusMismatch = "true"
+	}
+
+	if r.OriginalDuration != 0 {
+		originalDuration = strconv.FormatInt(r.OriginalDuration.Nanoseconds(), 10)
+	}
+
+	record := []string{
+		strconv.Itoa(r.ReportedStatus()),
+		r.Start.Format(time.RFC3339Nano),
+		strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+		r.Method,
+		r.URL,
+		r.Payload,
+		errString,
+		strconv.Itoa(r.Worker),
+		initialStatus,
+		r.FinalURL,
+		strconv.Itoa(r.Attempts),
+		r.Target,
+		originalStatus,
+		statusMismatch,
+		originalDuration,
+		r.TraceID,
+		r.SpanID,
+	}
+
+	for _, name := range c.headers {
+		record = append(record, r.Headers[name])
+	}
+
+	if err := c.writer.Write(record); err != nil {
+		return err
+	}
+
+	c.writer.Flush()
+
+	return c.writer.Error()
+}
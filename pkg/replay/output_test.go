@@ -0,0 +1,60 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCSVWriterStatusMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		mismatch string
+	}{
+		{name: "not verified", result: Result{Status: 200}},
+		{name: "same status", result: Result{Status: 200, OriginalStatus: 200}},
+		{name: "different status", result: Result{Status: 500, OriginalStatus: 200}, mismatch: "true"},
+		{name: "failed request", result: Result{Err: errors.New("timeout"), OriginalStatus: 200}, mismatch: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			sink, err := NewResultWriter("csv", &buf)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.result.Start = time.Date(2019, time.May, 1, 13, 55, 0, 0, time.UTC)
+
+			if err := sink.Write(&tt.result); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := csv.NewReader(&buf).ReadAll()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(records) != 2 || len(records[0]) != len(records[1]) {
+				t.Fatalf("expected header and row of the same length, got %q", records)
+			}
+
+			row := make(map[string]string)
+
+			for i, column := range records[0] {
+				row[column] = records[1][i]
+			}
+
+			if value, ok := row["status_mismatch"]; !ok || value != tt.mismatch {
+				t.Errorf("status_mismatch is %q (present %t), expected %q", value, ok, tt.mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RampStep linearly changes request rate from the previous step rate to Rate over Duration
+type RampStep struct {
+	Rate     float64
+	Duration time.Duration
+}
+
+// ParseRamp parses comma separated list of rate:duration steps, e.g. "10:60s,100:300s,500:600s"
+func ParseRamp(spec string) ([]RampStep, error) {
+	var steps []RampStep
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, ":", 2)
+
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("Invalid ramp step '%s', expected rate:duration", part)
+		}
+
+		r, err := strconv.ParseFloat(kv[0], 64)
+
+		if err != nil || r < 0 {
+			return nil, fmt.Errorf("Invalid ramp rate in step '%s'", part)
+		}
+
+		d, err := time.ParseDuration(kv[1])
+
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("Invalid ramp duration in step '%s'", part)
+		}
+
+		steps = append(steps, RampStep{Rate: r, Duration: d})
+	}
+
+	return steps, nil
+}
+
+// pacer spaces requests out at a given rate, ignoring log timestamps.
+// Requests are scheduled against absolute deadlines, so slow reads or sends
+// are caught up with instead of accumulating as drift.
+type pacer struct {
+	start time.Time
+	sent  float64
+	rate  float64
+	steps []RampStep
+	// random makes arrivals a Poisson process: requests are counted in exponentially distributed
+	// fractions, which spaces them at random intervals with the same (possibly changing) mean rate
+	random *rand.Rand
+}
+
+// newPacer creates pacer starting at rate requests per second,
+// optional ramp steps change the rate over time and the last step rate is kept afterwards
+func newPacer(rate float64, steps []RampStep) *pacer {
+	return &pacer{rate: rate, steps: steps}
+}
+
+// newPoissonPacer creates pacer with Poisson arrivals at the rate, the seed makes the intervals repeatable
+func newPoissonPacer(rate float64, steps []RampStep, seed uint64) *pacer {
+	return &pacer{rate: rate, steps: steps, random: rand.New(rand.NewPCG(seed, 0))}
+}
+
+// offset returns time since start at which n requests should have been sent,
+// false is returned if the rate drops to zero before that
+func (p *pacer) offset(n float64) (time.Duration, bool) {
+	var count, elapsed float64
+
+	from := p.rate
+
+	for _, step := range p.steps {
+		d := step.Duration.Seconds()
+		area := (from + step.Rate) / 2 * d
+
+		if count+area >= n {
+			// Solve a*x^2 + b*x = n - count for the linear rate change within the step
+			a := (step.Rate - from) / (2 * d)
+			b := from
+			k := n - count
+
+			var x float64
+
+			if k <= 0 {
+				x = 0
+			} else if a == 0 {
+				x = k / b
+			} else {
+				x = (-b + math.Sqrt(b*b+4*a*k)) / (2 * a)
+			}
+
+			return time.Duration((elapsed + x) * float64(time.Second)), true
+		}
+
+		count += area
+		elapsed += d
+		from = step.Rate
+	}
+
+	if from <= 0 {
+		return 0, false
+	}
+
+	return time.Duration((elapsed + (n-count)/from) * float64(time.Second)), true
+}
+
+// delay shifts the schedule by d, e.g. after the replay was paused
+func (p *pacer) delay(d time.Duration) {
+	if !p.start.IsZero() {
+		p.start = p.start.Add(d)
+	}
+}
+
+// Wait blocks until the next request is due and returns the time it was scheduled for,
+// false means the schedule is over or the context was cancelled
+func (p *pacer) Wait(ctx context.Context) (time.Time, bool) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+
+	offset, ok := p.offset(p.sent)
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if p.random != nil {
+		p.sent += p.random.ExpFloat64()
+	} else {
+		p.sent++
+	}
+
+	due := p.start.Add(offset)
+
+	if err := sleep(ctx, time.Until(due)); err != nil {
+		return time.Time{}, false
+	}
+
+	return due, true
+}
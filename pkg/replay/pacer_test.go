@@ -0,0 +1,179 @@
+package replay
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRamp(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []RampStep
+		err      string
+	}{
+		{spec: "10:60s,100:5m", expected: []RampStep{{Rate: 10, Duration: time.Minute}, {Rate: 100, Duration: 5 * time.Minute}}},
+		{spec: " 0:30s , 2.5:1s, ", expected: []RampStep{{Rate: 0, Duration: 30 * time.Second}, {Rate: 2.5, Duration: time.Second}}},
+		{spec: ""},
+		{spec: "10", err: "expected rate:duration"},
+		{spec: "10:60s,100", err: "Invalid ramp step '100'"},
+		{spec: "abc:60s", err: "Invalid ramp rate"},
+		{spec: "-1:60s", err: "Invalid ramp rate"},
+		{spec: "10:abc", err: "Invalid ramp duration"},
+		{spec: "10:0s", err: "Invalid ramp duration"},
+		{spec: "10:-1s", err: "Invalid ramp duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			steps, err := ParseRamp(tt.spec)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(steps, tt.expected) {
+				t.Errorf("steps %+v, expected %+v", steps, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPacerOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		steps    []RampStep
+		n        float64
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "constant rate", rate: 10, n: 5, expected: 500 * time.Millisecond, ok: true},
+		{name: "first request", rate: 10, n: 0, expected: 0, ok: true},
+		{name: "constant step", rate: 10, steps: []RampStep{{Rate: 10, Duration: 10 * time.Second}}, n: 5, expected: 500 * time.Millisecond, ok: true},
+		// rate(t) = t, n = t^2/2
+		{name: "increasing ramp", rate: 0, steps: []RampStep{{Rate: 10, Duration: 10 * time.Second}}, n: 8, expected: 4 * time.Second, ok: true},
+		// rate(t) = 10 - t, n = 10t - t^2/2
+		{name: "decreasing ramp", rate: 10, steps: []RampStep{{Rate: 0, Duration: 10 * time.Second}}, n: 32, expected: 4 * time.Second, ok: true},
+		{name: "zero rate step", rate: 0, steps: []RampStep{{Rate: 0, Duration: 5 * time.Second}, {Rate: 10, Duration: 10 * time.Second}}, n: 8, expected: 9 * time.Second, ok: true},
+		{name: "zero rate step first request", rate: 0, steps: []RampStep{{Rate: 0, Duration: 5 * time.Second}}, n: 0, expected: 0, ok: true},
+		{name: "last step rate is kept", rate: 0, steps: []RampStep{{Rate: 10, Duration: 10 * time.Second}}, n: 60, expected: 11 * time.Second, ok: true},
+		{name: "rate ends at zero", rate: 10, steps: []RampStep{{Rate: 0, Duration: 10 * time.Second}}, n: 51},
+		{name: "zero rate", rate: 0, n: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, ok := newPacer(tt.rate, tt.steps).offset(tt.n)
+
+			if ok != tt.ok {
+				t.Fatalf("offset returned %t, expected %t", ok, tt.ok)
+			}
+
+			if offset.Round(time.Microsecond) != tt.expected {
+				t.Errorf("offset %s, expected %s", offset, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPacerDelay(t *testing.T) {
+	p := newPacer(10, nil)
+	p.delay(time.Second)
+
+	if !p.start.IsZero() {
+		t.Fatalf("delay before the first request moved the start to %s", p.start)
+	}
+
+	start := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	p.start = start
+	p.delay(time.Second)
+
+	if !p.start.Equal(start.Add(time.Second)) {
+		t.Errorf("start %s, expected %s", p.start, start.Add(time.Second))
+	}
+}
+
+// poissonOffsets returns offsets of the first n requests of a Poisson pacer without sleeping
+func poissonOffsets(rate float64, steps []RampStep, seed uint64, n int) []time.Duration {
+	p := newPoissonPacer(rate, steps, seed)
+	p.start = time.Now().Add(-time.Hour)
+
+	var offsets []time.Duration
+
+	for i := 0; i < n; i++ {
+		due, ok := p.Wait(context.Background())
+
+		if !ok {
+			break
+		}
+
+		offsets = append(offsets, due.Sub(p.start))
+	}
+
+	return offsets
+}
+
+func TestPoissonPacer(t *testing.T) {
+	first := poissonOffsets(100, nil, 42, 100)
+	second := poissonOffsets(100, nil, 42, 100)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("the same seed gave different intervals")
+	}
+
+	if reflect.DeepEqual(first, poissonOffsets(100, nil, 43, 100)) {
+		t.Error("different seeds gave the same intervals")
+	}
+
+	if first[0] != 0 {
+		t.Errorf("first request is due at %s, expected right away", first[0])
+	}
+
+	equal := true
+
+	for i := 2; i < len(first); i++ {
+		if first[i]-first[i-1] != first[1]-first[0] {
+			equal = false
+		}
+	}
+
+	if equal {
+		t.Error("intervals are not random")
+	}
+}
+
+func TestPoissonPacerMeanRate(t *testing.T) {
+	// 100 rps for 10s, then 1000 rps for 10s
+	steps := []RampStep{{Rate: 100, Duration: 10 * time.Second}, {Rate: 1000, Duration: time.Millisecond}, {Rate: 1000, Duration: 10 * time.Second}}
+	offsets := poissonOffsets(100, steps, 1, 20000)
+
+	var slow, fast int
+
+	for _, offset := range offsets {
+		switch {
+		case offset < 10*time.Second:
+			slow++
+		case offset >= 10*time.Second+time.Millisecond && offset < 20*time.Second+time.Millisecond:
+			fast++
+		}
+	}
+
+	if slow < 900 || slow > 1100 {
+		t.Errorf("%d requests in the 100 rps step, expected about 1000", slow)
+	}
+
+	if fast < 9500 || fast > 10500 {
+		t.Errorf("%d requests in the 1000 rps step, expected about 10000", fast)
+	}
+}
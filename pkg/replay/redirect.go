@@ -0,0 +1,34 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type initialStatusKey struct{}
+
+// withInitialStatus makes status of the first response of a redirect chain recorded into the pointer
+func withInitialStatus(req *http.Request, status *int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), initialStatusKey{}, status))
+}
+
+// checkRedirect stops at the first response unless Options.FollowRedirects is set,
+// in which case at most Options.MaxRedirects hops are followed
+func (r *Replayer) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !r.opts.FollowRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	if len(via) == 1 && req.Response != nil {
+		if status, ok := req.Context().Value(initialStatusKey{}).(*int); ok {
+			*status = req.Response.StatusCode
+		}
+	}
+
+	if len(via) > r.opts.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", r.opts.MaxRedirects)
+	}
+
+	return nil
+}
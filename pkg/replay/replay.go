@@ -0,0 +1,1248 @@
+// Package replay sends requests parsed from access logs to a target, keeping the original
+// timing (or a given rate) and reporting results to pluggable sinks
+package replay
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// Defaults of Options.MaxInFlight, Options.ReadBuffer and Options.ResultBuffer
+const (
+	defaultMaxInFlight = 10000
+	defaultBuffer      = 1024
+)
+
+// Defaults of Options.MaxIdleConns and Options.IdleConnTimeout
+const (
+	defaultMaxIdleConns    = 1000
+	defaultIdleConnTimeout = 10 * time.Second
+)
+
+// defaultContentType of Options.ContentType is what HTML forms post
+const defaultContentType = "application/x-www-form-urlencoded"
+
+// ErrErrorRateExceeded is returned by Run when the rolling window error rate reached Options.ErrorRate
+var ErrErrorRateExceeded = errors.New("error rate exceeded")
+
+// ErrLatencyExceeded is returned by Run when the rolling window latency percentile exceeded Options.MaxLatency
+var ErrLatencyExceeded = errors.New("latency exceeded")
+
+// Options of the replay, zero values of Ratio, Targets and LatencyPercentile default to 1, http://localhost and 95
+type Options struct {
+	// Targets requests are spread across proportionally to their weights, unix:///path/to.sock:/prefix
+	// targets are Unix domain sockets (with the transport configured from the options).
+	// AbsoluteURLs sends entries with absolute URLs (e.g. of proxy logs) to their scheme and host instead
+	Targets      []Target
+	AbsoluteURLs bool
+	// Ratio speeds up the original log timing, ignored with SkipSleep, Rate or Ramp
+	Ratio     int64
+	SkipSleep bool
+	// MaxSleep caps gaps between requests (after Ratio), so that quiet periods of the log do not idle the replay, 0 means no cap
+	MaxSleep time.Duration
+	// Rate fires requests at a constant rate (requests per second) ignoring log timestamps,
+	// Ramp steps change it linearly over time
+	Rate float64
+	Ramp []RampStep
+	// Poisson spaces requests of Rate and Ramp at random exponentially distributed intervals,
+	// Seed makes them the same in every run
+	Poisson bool
+	Seed    uint64
+	// Concurrency is the number of workers sending requests, 0 means a goroutine per request
+	// with at most MaxInFlight (10000 by default) of them, dispatching waits for a free slot when reached
+	Concurrency int
+	MaxInFlight int
+	// Sessions replays requests with the same session key one after another, a request waits for both its
+	// schedule and the response to the previous one of its session. Sessions are replayed concurrently,
+	// requests of no session are sent right away. It can not be combined with Concurrency
+	Sessions SessionKey
+	// ReadBuffer is the number of entries parsed ahead of the schedule and ResultBuffer the number of results
+	// waiting for slow sinks before requests are held back, both 1024 by default
+	ReadBuffer   int
+	ResultBuffer int
+
+	Timeout time.Duration
+	Debug   bool
+	// RouteTimeouts override Timeout for paths matching their route, the first matching one is used
+	RouteTimeouts []RouteTimeout
+
+	// Replay is stopped when ErrorRate percent of the last WindowSize requests failed (transport errors
+	// and 5xx responses, 4xx too with Window4xx) or their
+	// LatencyPercentile (95 by default) exceeded MaxLatency, or paused for WindowCooldown when it is set.
+	// The window is checked after every result or every WindowInterval, OnTrip is called when it trips
+	EnableWindow      bool
+	WindowSize        int
+	ErrorRate         float64
+	Window4xx         bool
+	MaxLatency        time.Duration
+	LatencyPercentile float64
+	WindowInterval    time.Duration
+	WindowCooldown    time.Duration
+	OnTrip            func(Trip)
+
+	BasicAuthUser     string
+	BasicAuthPassword string
+	HostHeader        string
+	FollowRedirects   bool
+	MaxRedirects      int
+	Retries           int
+	RetryBackoff      time.Duration
+	RetryMaxBackoff   time.Duration
+
+	// CookieJar keeps cookies set by responses for every client IP of the log and sends them with later requests
+	// of the same client, so that sessions (log in, then browse) work. Entries without client IP get no cookies
+	CookieJar bool
+
+	// UserAgent is sent instead of logged user agents, or one of UserAgents picked for every client IP of the log
+	// (for every request of entries without client IP). With PreserveUA they are sent only with entries without logged
+	// user agent. Entries without logged user agent are sent without User-Agent by default
+	UserAgent  string
+	UserAgents []string
+	PreserveUA bool
+
+	// GRPC replays entries as unary gRPC calls over HTTP2 or H2C: the URL is the method (/package.Service/Method),
+	// the payload is base64 encoded request message or JSON converted with ProtoDescriptors (FileDescriptorSet file of
+	// protoc --include_imports --descriptor_set_out). gRPC status codes are recorded as equivalent HTTP statuses
+	GRPC             bool
+	ProtoDescriptors string
+
+	// MySQLDSN and PostgresDSN run payloads of entries as statements on the database instead of sending HTTP
+	// requests, the URL path is the database (/name, the one of the DSN when empty). Only reading statements
+	// are run, in read only transactions, unless AllowWrites is set
+	MySQLDSN    string
+	PostgresDSN string
+	AllowWrites bool
+
+	// GraphQL records operation names of GraphQL requests in results, endpoints are reported per operation
+	GraphQL bool
+
+	// ContentType is sent with POST requests and requests with payload of entries without logged content type,
+	// application/x-www-form-urlencoded by default
+	ContentType string
+
+	// LogHeaders are names of logged request headers (e.g. Referer, Accept, Accept-Encoding) sent with requests,
+	// "*" sends all of them except hop-by-hop ones. Headers take precedence
+	LogHeaders []string
+
+	// ForwardClientIP sends client IP of the log entry in X-Forwarded-For and X-Real-IP headers,
+	// unless Headers set them
+	ForwardClientIP bool
+
+	// Extract rules take values of responses into variables of the session (Sessions key, client IP without it),
+	// {{name}} in URLs, payloads and Headers of later requests of the session is replaced by them. The shadow target
+	// gets the same values. Headers with variables which are not set yet are not sent
+	Extract []ExtractRule
+	Headers []Header
+
+	// OnRequest can change or skip requests right before they are sent
+	OnRequest RequestHook
+
+	// ShadowPrefix target gets every request too, its responses are attached to results
+	ShadowPrefix string
+	DiffBody     bool
+
+	// VerifyStatus records statuses of log entries in results to be compared with the replayed ones
+	VerifyStatus bool
+
+	// CompareLatency records durations of log entries in results to be compared with the replayed ones
+	CompareLatency bool
+
+	// ResponseHeaders are recorded in results
+	ResponseHeaders []string
+
+	// TraceHeaders are sent with trace context generated for every request, which is recorded in results
+	// with the log entry time: traceparent (W3C), b3 (single header), b3multi (X-B3-* headers)
+	// and any other header name (e.g. X-Request-Id) for the trace ID
+	TraceHeaders []string
+
+	// Bodies of responses with status matching CaptureStatus are attached to results, up to CaptureMaxBytes
+	CaptureStatus   func(status int) bool
+	CaptureMaxBytes int64
+
+	// Transport is used as is when set, otherwise it is configured from the options below
+	Transport          http.RoundTripper
+	SSLSkipVerify      bool
+	HTTP2              bool
+	H2C                bool
+	TLSCert            string
+	TLSKey             string
+	TLSCA              string
+	Proxy              string
+	OAuth2TokenURL     string
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	OAuth2Scopes       []string
+
+	// Connection pool keeps up to MaxIdleConns (1000 by default) idle connections, MaxIdleConnsPerHost of them
+	// (as many as MaxIdleConns by default) to a single host, for IdleConnTimeout (10s by default).
+	// MaxConnsPerHost limits all connections to a host, 0 means no limit. DisableKeepAlives opens
+	// a connection for every request, TCPKeepAlive is the interval of keep-alive probes (negative disables them)
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	TCPKeepAlive        time.Duration
+
+	// Resolve maps lower case host:port of requests to the address connections are made to instead, like curl --resolve
+	Resolve map[string]string
+
+	// SourceIPs are local addresses connections are made from, requests take them in turns or with SourceByClient
+	// requests of the same client IP of the log always use the same one
+	SourceIPs      []net.IP
+	SourceByClient bool
+}
+
+// request is a log entry scheduled for replay
+type request struct {
+	Entry     *reader.LogEntry
+	Scheduled time.Time
+	Worker    int
+	// header is set by Options.OnRequest
+	header http.Header
+}
+
+// Replayer replays log entries of the reader and passes results to the sinks
+type Replayer struct {
+	opts      Options
+	reader    reader.LogReader
+	sinks     []ResultSink
+	observers []Observer
+	// windowObservers are sinks implementing WindowObserver
+	windowObservers []WindowObserver
+	client          *http.Client
+	balancer        *balancer
+	// unix are Unix domain socket targets by their prefix
+	unix map[string]unixTarget
+	// routeClients have timeouts of Options.RouteTimeouts in the same order
+	routeClients []*http.Client
+	// cookies of the targets and shadowCookies of the shadow target are kept with Options.CookieJar
+	cookies       *cookieJars
+	shadowCookies *cookieJars
+
+	// grpc makes request bodies of Options.GRPC calls
+	grpc *grpcCodec
+
+	// wsDialer opens sessions of WebSocket entries
+	wsDialer *websocket.Dialer
+	// db runs statements instead of HTTP requests
+	db *databaseBackend
+	// vars are set by Options.Extract rules
+	vars *variables
+
+	requests chan *request
+	// inFlight holds a slot of every request sent without workers, queued requests of sessions too
+	inFlight chan struct{}
+	sessions *sessions
+	results  chan *Result
+	window   chan windowSample
+	gate     pauseGate
+	httpWg   sync.WaitGroup
+}
+
+// New creates replayer of the reader entries, sinks implementing Observer are notified about the progress
+// and sinks implementing WindowObserver about the error window
+func New(opts Options, rdr reader.LogReader, sinks ...ResultSink) (*Replayer, error) {
+	if opts.Ratio == 0 {
+		opts.Ratio = 1
+	}
+
+	if len(opts.Targets) == 0 {
+		opts.Targets = []Target{{URL: "http://localhost", Weight: 1}}
+	}
+
+	for _, target := range opts.Targets {
+		if target.Weight <= 0 {
+			return nil, fmt.Errorf("prefix weight has to be positive, not '%d'", target.Weight)
+		}
+	}
+
+	if opts.MaxInFlight == 0 {
+		opts.MaxInFlight = defaultMaxInFlight
+	}
+
+	if opts.ReadBuffer == 0 {
+		opts.ReadBuffer = defaultBuffer
+	}
+
+	if opts.ResultBuffer == 0 {
+		opts.ResultBuffer = defaultBuffer
+	}
+
+	if opts.MaxIdleConns == 0 {
+		opts.MaxIdleConns = defaultMaxIdleConns
+	}
+
+	if opts.MaxIdleConnsPerHost == 0 {
+		opts.MaxIdleConnsPerHost = opts.MaxIdleConns
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = defaultContentType
+	}
+
+	if opts.IdleConnTimeout == 0 {
+		opts.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	if opts.MaxIdleConns < 0 || opts.MaxIdleConnsPerHost < 0 || opts.MaxConnsPerHost < 0 {
+		return nil, fmt.Errorf("connection limits have to be positive, not '%d', '%d' and '%d'", opts.MaxIdleConns, opts.MaxIdleConnsPerHost, opts.MaxConnsPerHost)
+	}
+
+	if opts.Sessions != nil && opts.Concurrency > 0 {
+		return nil, errors.New("sessions can not be combined with concurrency, every session is replayed on its own")
+	}
+
+	if opts.UserAgent != "" && len(opts.UserAgents) > 0 {
+		return nil, errors.New("user agent can not be combined with user agents to pick from")
+	}
+
+	if opts.GRPC && opts.Transport == nil && !opts.HTTP2 && !opts.H2C {
+		return nil, errors.New("gRPC needs HTTP2 or H2C")
+	}
+
+	if opts.MySQLDSN != "" && opts.PostgresDSN != "" {
+		return nil, errors.New("MySQL DSN can not be combined with PostgreSQL DSN")
+	}
+
+	if opts.AllowWrites && opts.MySQLDSN == "" && opts.PostgresDSN == "" {
+		return nil, errors.New("allow writes needs a database")
+	}
+
+	if opts.SourceByClient && len(opts.SourceIPs) == 0 {
+		return nil, errors.New("source by client needs source IPs")
+	}
+
+	if opts.MaxInFlight < 0 {
+		return nil, fmt.Errorf("max in flight has to be positive, not '%d'", opts.MaxInFlight)
+	}
+
+	if opts.ReadBuffer < 0 || opts.ResultBuffer < 0 {
+		return nil, fmt.Errorf("buffer sizes have to be positive, not '%d' and '%d'", opts.ReadBuffer, opts.ResultBuffer)
+	}
+
+	if opts.LatencyPercentile == 0 {
+		opts.LatencyPercentile = 95
+	}
+
+	if opts.EnableWindow && opts.WindowSize <= 0 {
+		return nil, fmt.Errorf("window size has to be positive, not '%d'", opts.WindowSize)
+	}
+
+	// ErrorRate is a percentage, 0 would trip on the first full window
+	if opts.EnableWindow && (opts.ErrorRate <= 0 || opts.ErrorRate > 100) {
+		return nil, fmt.Errorf("error rate has to be above 0 and at most 100 percent, not '%g'", opts.ErrorRate)
+	}
+
+	if opts.LatencyPercentile < 0 || opts.LatencyPercentile > 100 {
+		return nil, fmt.Errorf("latency percentile has to be between 0 and 100, not '%g'", opts.LatencyPercentile)
+	}
+
+	if opts.CaptureStatus != nil && opts.CaptureMaxBytes <= 0 {
+		return nil, fmt.Errorf("capture max bytes has to be positive, not '%d'", opts.CaptureMaxBytes)
+	}
+
+	if opts.Ratio < 0 {
+		return nil, fmt.Errorf("ratio has to be positive, not '%d'", opts.Ratio)
+	}
+
+	if opts.Poisson && opts.Rate <= 0 && len(opts.Ramp) == 0 {
+		return nil, errors.New("poisson arrivals need rate or ramp")
+	}
+
+	for _, rt := range opts.RouteTimeouts {
+		if rt.Timeout < 0 {
+			return nil, fmt.Errorf("timeout of route '%s' has to be positive, not '%s'", rt.Route.Pattern, rt.Timeout)
+		}
+	}
+
+	if opts.MaxSleep < 0 {
+		return nil, fmt.Errorf("max sleep has to be positive, not '%s'", opts.MaxSleep)
+	}
+
+	unix, err := unixTargets(opts.Targets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transport := opts.Transport
+
+	if transport == nil {
+		if transport, err = newTransport(&opts); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &Replayer{
+		opts:     opts,
+		reader:   rdr,
+		sinks:    sinks,
+		balancer: newBalancer(opts.Targets),
+		unix:     unix,
+	}
+
+	r.client = &http.Client{
+		Transport:     transport,
+		Timeout:       opts.Timeout,
+		CheckRedirect: r.checkRedirect,
+	}
+
+	for _, rt := range opts.RouteTimeouts {
+		client := *r.client
+		client.Timeout = rt.Timeout
+		r.routeClients = append(r.routeClients, &client)
+	}
+
+	if r.wsDialer, err = newWebSocketDialer(&opts); err != nil {
+		return nil, err
+	}
+
+	if opts.GRPC {
+		if r.grpc, err = newGRPCCodec(opts.ProtoDescriptors); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case opts.MySQLDSN != "":
+		r.db, err = newMySQLBackend(&r.opts)
+	case opts.PostgresDSN != "":
+		r.db, err = newPostgresBackend(&r.opts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.CookieJar {
+		r.cookies = newCookieJars()
+		r.shadowCookies = newCookieJars()
+	}
+
+	if len(opts.Extract) > 0 || len(opts.Headers) > 0 {
+		r.vars = newVariables()
+	}
+
+	for _, sink := range sinks {
+		if observer, ok := sink.(Observer); ok {
+			r.observers = append(r.observers, observer)
+		}
+
+		if observer, ok := sink.(WindowObserver); ok {
+			r.windowObservers = append(r.windowObservers, observer)
+		}
+	}
+
+	return r, nil
+}
+
+// Run replays all entries of the reader and waits for all requests to finish,
+// it returns early when the context is cancelled, a sink fails or the error window is exceeded
+func (r *Replayer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.results = make(chan *Result, r.opts.ResultBuffer)
+
+	var sinkErr error
+	var sinkWg sync.WaitGroup
+
+	sinkWg.Add(1)
+
+	go func() {
+		defer sinkWg.Done()
+
+		for res := range r.results {
+			if sinkErr != nil {
+				continue
+			}
+
+			for _, sink := range r.sinks {
+				if err := sink.Write(res); err != nil {
+					sinkErr = err
+					cancel()
+
+					break
+				}
+			}
+		}
+	}()
+
+	var windowErr error
+	var windowWg sync.WaitGroup
+
+	if r.opts.EnableWindow {
+		r.window = make(chan windowSample)
+		windowWg.Add(1)
+
+		go func() {
+			defer windowWg.Done()
+
+			windowErr = r.windowLoop(cancel)
+		}()
+	}
+
+	err := r.dispatchLoop(ctx)
+
+	if r.opts.Debug {
+		log.Println("Waiting for all http goroutines to stop")
+	}
+
+	r.httpWg.Wait()
+	close(r.results)
+
+	if r.db != nil {
+		r.db.close()
+	}
+
+	if r.window != nil {
+		close(r.window)
+		windowWg.Wait()
+	}
+
+	if r.opts.Debug {
+		log.Println("Waiting for log goroutine to stop")
+	}
+
+	sinkWg.Wait()
+
+	switch {
+	case windowErr != nil:
+		return windowErr
+	case sinkErr != nil:
+		return sinkErr
+	default:
+		return err
+	}
+}
+
+// Pause stops dispatching of requests until Resume, requests in flight are completed and reported.
+// It returns false when the replay was already paused with Pause
+func (r *Replayer) Pause() bool {
+	return r.gate.pause("manual")
+}
+
+// Resume continues dispatching paused with Pause, the schedule is shifted by the pause.
+// It returns false when the replay was not paused with Pause
+func (r *Replayer) Resume() bool {
+	return r.gate.unpause("manual")
+}
+
+// Paused tells if dispatching is paused, manually or by the window
+func (r *Replayer) Paused() bool {
+	return r.gate.paused()
+}
+
+// sleep waits for the duration unless the context is cancelled first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// readResult is an entry read ahead by the reading goroutine
+type readResult struct {
+	entry *reader.LogEntry
+	err   error
+}
+
+// readLoop reads entries in a separate goroutine, so that the replay can be stopped
+// even while the reader is blocked waiting for input
+func (r *Replayer) readLoop(ctx context.Context) <-chan readResult {
+	entries := make(chan readResult, r.opts.ReadBuffer)
+
+	go func() {
+		for {
+			entry, err := reader.ReadContext(ctx, r.reader)
+
+			select {
+			case entries <- readResult{entry: entry, err: err}:
+			case <-ctx.Done():
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return entries
+}
+
+func (r *Replayer) dispatched(lag time.Duration, logTime time.Time) {
+	for _, observer := range r.observers {
+		observer.Dispatched(lag, logTime)
+	}
+}
+
+func (r *Replayer) dispatchLoop(ctx context.Context) error {
+	var firstTime time.Time
+	// lastTime is the latest log time seen, lastScheduled the time its request was due
+	var lastTime time.Time
+	var lastScheduled time.Time
+	var replayStart time.Time
+	var p *pacer
+
+	if r.opts.Poisson {
+		p = newPoissonPacer(r.opts.Rate, r.opts.Ramp, r.opts.Seed)
+	} else if r.opts.Rate > 0 || len(r.opts.Ramp) > 0 {
+		p = newPacer(r.opts.Rate, r.opts.Ramp)
+	}
+
+	// saturated is set when MaxInFlight was reached, it is logged once
+	var saturated bool
+
+	if r.opts.Concurrency > 0 {
+		r.requests = make(chan *request)
+		defer close(r.requests)
+
+		for i := 1; i <= r.opts.Concurrency; i++ {
+			go r.workerLoop(ctx, i)
+		}
+	} else {
+		r.inFlight = make(chan struct{}, r.opts.MaxInFlight)
+	}
+
+	if r.opts.Sessions != nil {
+		r.sessions = newSessions()
+	}
+
+	entries := r.readLoop(ctx)
+
+	for {
+		var next readResult
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case next = <-entries:
+		}
+
+		rec, err := next.entry, next.err
+
+		if err == io.EOF {
+			log.Println("Reached EOF")
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		paused, err := r.gate.wait(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		// Schedule is shifted by the pause, so that requests are not fired in a burst to catch up
+		if paused > 0 {
+			if p != nil {
+				p.delay(paused)
+			}
+
+			replayStart = replayStart.Add(paused)
+		}
+
+		var scheduled time.Time
+
+		if p != nil {
+			var ok bool
+
+			if scheduled, ok = p.Wait(ctx); !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				log.Println("Reached end of the ramp schedule")
+				return nil
+			}
+		} else if !r.opts.SkipSleep {
+			if firstTime.IsZero() {
+				firstTime = rec.Time
+				lastTime = rec.Time
+				replayStart = time.Now()
+			}
+
+			// Longer gaps are clamped by moving the schedule of the rest of the log closer
+			if gap := rec.Time.Sub(lastTime) / time.Duration(r.opts.Ratio); r.opts.MaxSleep > 0 && gap > r.opts.MaxSleep {
+				replayStart = replayStart.Add(r.opts.MaxSleep - gap)
+			}
+
+			if rec.Time.After(lastTime) {
+				lastTime = rec.Time
+			}
+
+			// Requests are due at their offset in the log divided by the ratio from the replay start instead of
+			// sleeping the gaps one by one, so that time spent parsing and timer inaccuracy do not add up.
+			// Entries logged out of order are due together with the previous one
+			scheduled = replayStart.Add(rec.Time.Sub(firstTime) / time.Duration(r.opts.Ratio))
+
+			if scheduled.Before(lastScheduled) {
+				scheduled = lastScheduled
+			}
+
+			lastScheduled = scheduled
+			wait := time.Until(scheduled)
+
+			if r.opts.Debug {
+				if wait > 0 {
+					log.Printf("Sleeping for: %.2f seconds", wait.Seconds())
+				} else {
+					log.Println("No need for sleep!")
+				}
+			}
+
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+
+		if firstTime.IsZero() {
+			firstTime = rec.Time
+			replayStart = now
+		}
+
+		if scheduled.IsZero() {
+			scheduled = now
+			r.dispatched(now.Sub(replayStart)-rec.Time.Sub(firstTime)/time.Duration(r.opts.Ratio), rec.Time)
+		} else {
+			r.dispatched(now.Sub(scheduled), rec.Time)
+		}
+
+		req := &request{Entry: rec, Scheduled: scheduled}
+
+		r.httpWg.Add(1)
+
+		if r.opts.Concurrency > 0 {
+			select {
+			case r.requests <- req:
+			case <-ctx.Done():
+				r.httpWg.Done()
+				return ctx.Err()
+			}
+		} else {
+			select {
+			case r.inFlight <- struct{}{}:
+			default:
+				if !saturated {
+					saturated = true
+					log.Printf("%d requests in flight, dispatching waits for responses", cap(r.inFlight))
+				}
+
+				select {
+				case r.inFlight <- struct{}{}:
+				case <-ctx.Done():
+					r.httpWg.Done()
+					return ctx.Err()
+				}
+			}
+
+			if r.sessions != nil {
+				if key := r.opts.Sessions(rec); key != "" {
+					r.enqueue(ctx, key, req)
+					continue
+				}
+			}
+
+			go func() {
+				r.send(ctx, req)
+				<-r.inFlight
+			}()
+		}
+	}
+}
+
+func (r *Replayer) workerLoop(ctx context.Context, worker int) {
+	for req := range r.requests {
+		req.Worker = worker
+		r.send(ctx, req)
+	}
+}
+
+func (r *Replayer) send(ctx context.Context, rq *request) {
+	defer r.httpWg.Done()
+
+	method := rq.Entry.Method
+	url := rq.Entry.URL
+	payload := rq.Entry.Payload
+	ua := rq.Entry.UA
+
+	var session string
+
+	if r.vars != nil {
+		session = r.session(rq.Entry)
+		url, _ = r.vars.expand(session, url)
+		payload, _ = r.vars.expand(session, payload)
+	}
+
+	var hookErr error
+
+	if r.opts.OnRequest != nil {
+		hooked := &HookRequest{Method: method, URL: url, Payload: payload, Header: make(http.Header)}
+
+		var send bool
+
+		if send, hookErr = r.opts.OnRequest(rq.Entry, hooked); !send && hookErr == nil {
+			if r.opts.Debug {
+				log.Printf("Skipping %s %s\n", method, url)
+			}
+
+			return
+		}
+
+		method, url, payload, rq.header = hooked.Method, hooked.URL, hooked.Payload, hooked.Header
+	}
+
+	if r.db != nil && r.db.skip(payload) {
+		if r.opts.Debug {
+			log.Printf("Skipping write statement %s\n", payload)
+		}
+
+		return
+	}
+
+	target := r.balancer.Pick()
+	path := target + url
+
+	if unix, ok := r.unix[target]; ok {
+		path = unix.base + url
+	}
+
+	// shadowURL is the URL relative to the shadow prefix
+	shadowURL := url
+	absolute := false
+
+	if r.opts.AbsoluteURLs {
+		if origin, rest, ok := splitOrigin(url); ok {
+			target, path, shadowURL, absolute = origin, url, rest, true
+		}
+	}
+
+	if r.opts.Debug {
+		log.Printf("Querying %s %s %s %s\n", method, path, payload, ua)
+	}
+
+	for _, observer := range r.observers {
+		observer.RequestStarted()
+	}
+
+	res := &Result{
+		Scheduled: rq.Scheduled,
+		Start:     time.Now(),
+		Method:    method,
+		URL:       url,
+		Payload:   payload,
+		Worker:    rq.Worker,
+	}
+
+	if len(r.opts.Targets) > 1 || absolute {
+		res.Target = target
+	}
+
+	if r.opts.VerifyStatus {
+		res.OriginalStatus = rq.Entry.Status
+	}
+
+	if r.opts.GraphQL {
+		res.Operation, _ = GraphQLOperation(method, url, payload)
+	}
+
+	if r.opts.CompareLatency {
+		res.OriginalDuration = rq.Entry.Duration
+	}
+
+	if len(r.opts.TraceHeaders) > 0 {
+		res.TraceID, res.SpanID = newTraceIDs()
+		res.LogTime = rq.Entry.Time
+	}
+
+	if len(r.opts.ResponseHeaders) > 0 {
+		res.Headers = make(map[string]string, len(r.opts.ResponseHeaders))
+
+		for _, name := range r.opts.ResponseHeaders {
+			res.Headers[name] = ""
+		}
+	}
+
+	if hookErr != nil {
+		res.Err = hookErr
+		r.results <- res
+
+		return
+	}
+
+	if r.db != nil {
+		r.db.run(ctx, res, url, payload)
+
+		if r.window != nil {
+			r.window <- windowSample{failed: r.windowFailure(res), status: res.Status, duration: res.Duration}
+		}
+		r.results <- res
+
+		return
+	}
+
+	if rq.Entry.WebSocket {
+		r.replayWebSocket(ctx, rq, res, path, session)
+
+		if r.window != nil {
+			r.window <- windowSample{failed: r.windowFailure(res), status: res.Status, duration: res.Duration}
+		}
+		r.results <- res
+
+		return
+	}
+
+	var shadow chan *ShadowResult
+
+	if r.opts.ShadowPrefix != "" {
+		shadow = make(chan *ShadowResult, 1)
+
+		go func() {
+			shadow <- r.sendShadow(ctx, rq, method, shadowURL, payload, session)
+		}()
+	}
+
+	req, err := r.newRequest(ctx, method, path, payload, rq.Entry, session)
+
+	if err != nil {
+		if r.opts.Debug {
+			log.Printf("ERROR %s while creating new request to %s", err, path)
+		}
+		res.Err = err
+
+		if shadow != nil {
+			res.Shadow = <-shadow
+		}
+
+		r.results <- res
+
+		return
+	}
+
+	setHeader(req, rq.header)
+	setTraceHeaders(req.Header, r.opts.TraceHeaders, res)
+
+	if r.opts.FollowRedirects {
+		req = withInitialStatus(req, &res.InitialStatus)
+	}
+
+	var resp *http.Response
+	// extracted is the body of the last response kept for Options.Extract
+	var extracted *limitedBuffer
+
+	client := withCookies(r.clientFor(url), r.cookies, rq.Entry.ClientIP)
+
+	for {
+		res.Attempts++
+		res.InitialStatus = 0
+
+		if res.Attempts > 1 {
+			req.Body, _ = req.GetBody()
+		}
+
+		resp, err = client.Do(req)
+		extracted = nil
+
+		if err == nil {
+			capture := r.captureBuffer(resp.StatusCode)
+			extracted = r.extractBuffer()
+			res.BodyHash, err = r.readBody(resp.Body, capture, extracted)
+			res.Body, res.BodyTruncated = capture.body()
+		}
+
+		var status int
+
+		if err == nil {
+			status = r.status(resp)
+		}
+
+		if res.Attempts > r.opts.Retries || !retryable(status, err) {
+			break
+		}
+
+		delay := r.retryDelay(res.Attempts)
+
+		if err != nil {
+			log.Printf(`Attempt %d of %s %s failed with "%s", retrying in %s`, res.Attempts, method, path, err, delay)
+		} else {
+			log.Printf("Attempt %d of %s %s failed with status %d, retrying in %s", res.Attempts, method, path, status, delay)
+		}
+
+		// Cancelled replay reports the last attempt
+		if sleep(ctx, delay) != nil {
+			break
+		}
+	}
+
+	res.Duration = time.Since(res.Start)
+
+	if err != nil {
+		if r.opts.Debug {
+			log.Printf(`ERROR "%s" while querying "%s"`, err, path)
+		}
+		res.Err = err
+	} else {
+		res.Status = r.status(resp)
+
+		for _, name := range r.opts.ResponseHeaders {
+			res.Headers[name] = resp.Header.Get(name)
+		}
+
+		if res.InitialStatus != 0 {
+			res.FinalURL = resp.Request.URL.String()
+		}
+
+		if len(r.opts.Extract) > 0 {
+			r.extract(session, url, resp, extracted)
+		}
+	}
+
+	if shadow != nil {
+		res.Shadow = <-shadow
+	}
+
+	if r.window != nil {
+		r.window <- windowSample{failed: r.windowFailure(res), status: res.Status, duration: res.Duration}
+	}
+	r.results <- res
+}
+
+// status returns status of the response, gRPC status is reported as the equivalent HTTP status
+func (r *Replayer) status(resp *http.Response) int {
+	if r.grpc != nil {
+		return grpcStatus(resp)
+	}
+
+	return resp.StatusCode
+}
+
+// clientFor returns client with the timeout of the first of Options.RouteTimeouts matching the URL
+func (r *Replayer) clientFor(url string) *http.Client {
+	if len(r.routeClients) == 0 {
+		return r.client
+	}
+
+	path := urlPath(url)
+
+	for i, rt := range r.opts.RouteTimeouts {
+		if rt.Route.Match(path) {
+			return r.routeClients[i]
+		}
+	}
+
+	return r.client
+}
+
+// newRequest creates request to the target with headers common for all replayed requests
+func (r *Replayer) newRequest(ctx context.Context, method, target, payload string, entry *reader.LogEntry, session string) (*http.Request, error) {
+	if r.opts.SourceByClient {
+		ctx = withClientIP(ctx, entry.ClientIP)
+	}
+
+	body := []byte(payload)
+
+	if r.grpc != nil {
+		var err error
+
+		if body, err = r.grpc.body(urlPath(target), payload); err != nil {
+			return nil, err
+		}
+
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.grpc != nil {
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+	} else if entry.ContentType != "" {
+		req.Header.Set("Content-Type", entry.ContentType)
+	} else if method == "POST" || payload != "" {
+		req.Header.Set("Content-Type", r.opts.ContentType)
+	}
+
+	if len(r.opts.BasicAuthUser) > 0 && len(r.opts.BasicAuthPassword) > 0 {
+		req.SetBasicAuth(r.opts.BasicAuthUser, r.opts.BasicAuthPassword)
+	}
+
+	req.Header.Set("User-Agent", r.userAgent(entry))
+
+	for _, header := range r.opts.Headers {
+		if value, ok := r.vars.expand(session, header.Value); ok {
+			req.Header.Add(header.Name, value)
+		}
+	}
+
+	r.setLogHeaders(req.Header, entry)
+
+	if r.opts.ForwardClientIP && entry.ClientIP != "" {
+		setIfMissing(req.Header, "X-Forwarded-For", entry.ClientIP)
+		setIfMissing(req.Header, "X-Real-IP", entry.ClientIP)
+	}
+
+	if r.opts.HostHeader != "" {
+		req.Host = r.opts.HostHeader
+	} else if r.isUnixHost(req.URL.Host) {
+		req.Host = "localhost"
+	}
+
+	return req, nil
+}
+
+// setIfMissing sets the header unless it has a value already
+func setIfMissing(header http.Header, name, value string) {
+	if header.Get(name) == "" {
+		header.Set(name, value)
+	}
+}
+
+// hopByHop headers are left out when all logged headers are sent, they belong to the original connection
+var hopByHop = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Connection":    true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Content-Length":      true,
+}
+
+// setLogHeaders sets Options.LogHeaders of the entry which are not set yet
+func (r *Replayer) setLogHeaders(header http.Header, entry *reader.LogEntry) {
+	for _, name := range r.opts.LogHeaders {
+		if name != "*" {
+			if value := loggedHeader(entry, name); value != "" {
+				setIfMissing(header, name, value)
+			}
+
+			continue
+		}
+
+		if entry.Referer != "" {
+			setIfMissing(header, "Referer", entry.Referer)
+		}
+
+		for name, values := range entry.Headers {
+			if !hopByHop[name] && header.Get(name) == "" {
+				header[name] = values
+			}
+		}
+	}
+}
+
+// loggedHeader returns value of the request header of the entry, Referer and Content-Type are fields of their own
+func loggedHeader(entry *reader.LogEntry, name string) string {
+	switch http.CanonicalHeaderKey(name) {
+	case "Referer":
+		return entry.Referer
+	case "Content-Type":
+		return entry.ContentType
+	default:
+		return entry.Headers.Get(name)
+	}
+}
+
+// readBody reads and closes response body, returning its hash when bodies are compared with the shadow target,
+// the body is copied to buffers which are not nil
+func (r *Replayer) readBody(body io.ReadCloser, buffers ...*limitedBuffer) (string, error) {
+	defer body.Close()
+
+	writers := []io.Writer{ioutil.Discard}
+	var digest hash.Hash
+
+	if r.opts.DiffBody {
+		digest = sha256.New()
+		writers = append(writers, digest)
+	}
+
+	for _, buffer := range buffers {
+		if buffer != nil {
+			writers = append(writers, buffer)
+		}
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), body); err != nil {
+		return "", err
+	}
+
+	if digest == nil {
+		return "", nil
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// captureBuffer returns buffer for the body of response with the status, nil when it is not captured
+func (r *Replayer) captureBuffer(status int) *limitedBuffer {
+	if r.opts.CaptureStatus == nil || !r.opts.CaptureStatus(status) {
+		return nil
+	}
+
+	return &limitedBuffer{limit: r.opts.CaptureMaxBytes}
+}
+
+// limitedBuffer keeps up to limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if room := b.limit - int64(b.Len()); int64(len(p)) > room {
+		p = p[:room]
+		b.truncated = true
+	}
+
+	b.Buffer.Write(p)
+
+	return n, nil
+}
+
+// body returns bytes kept and whether some were discarded
+func (b *limitedBuffer) body() ([]byte, bool) {
+	if b == nil {
+		return nil, false
+	}
+
+	return b.Bytes(), b.truncated
+}
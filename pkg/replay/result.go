@@ -0,0 +1,60 @@
+package replay
+
+import (
+	"time"
+)
+
+// Result of a single replayed request
+type Result struct {
+	Status    int
+	Scheduled time.Time
+	Start     time.Time
+	Duration  time.Duration
+	Method    string
+	URL       string
+	Payload   string
+	Err       error
+	Worker    int
+	// Target is the prefix request was sent to, set when there are several
+	Target string
+	// InitialStatus and FinalURL are set when a redirect was followed
+	InitialStatus int
+	FinalURL      string
+	// Attempts is the number of times the request was sent, Duration covers all of them
+	Attempts int
+	// BodyHash is hex encoded SHA-256 of the response body, set with Options.DiffBody
+	BodyHash string
+	// Body is the response body captured with Options.CaptureStatus, BodyTruncated is set when
+	// it was longer than Options.CaptureMaxBytes
+	Body          []byte
+	BodyTruncated bool
+	// BodyFile is where BodyCapture stored the body
+	BodyFile string
+	// OriginalStatus is the status of the log entry, set with Options.VerifyStatus, 0 when unknown
+	OriginalStatus int
+	// OriginalDuration is how long the log entry took originally, set with Options.CompareLatency, 0 when unknown
+	OriginalDuration time.Duration
+	// Headers are values of Options.ResponseHeaders, empty for headers missing in the response
+	Headers map[string]string
+	// TraceID and SpanID are hex encoded trace context of the request, LogTime is the time
+	// of the log entry, set with Options.TraceHeaders
+	TraceID string
+	SpanID  string
+	LogTime time.Time
+	// Shadow is the response of the Options.ShadowPrefix target to the same request
+	Shadow *ShadowResult
+	// WebSocket is set for entries which opened WebSocket sessions
+	WebSocket *WebSocketResult
+	// Operation is the name of GraphQL operation, set with Options.GraphQL
+	Operation string
+}
+
+// ReportedStatus is the status written to the output log,
+// failed requests are reported with 500 status
+func (r *Result) ReportedStatus() int {
+	if r.Err != nil {
+		return 500
+	}
+
+	return r.Status
+}
@@ -0,0 +1,210 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultReader reads results back from a timings log, io.EOF is returned at the end
+type ResultReader interface {
+	Read() (*Result, error)
+}
+
+// NewResultReader creates reader of the timings log written by NewResultWriter in the format
+func NewResultReader(format string, r io.Reader) (ResultReader, error) {
+	switch format {
+	case "tsv":
+		return &tsvReader{scanner: bufio.NewScanner(r)}, nil
+	case "json":
+		return &jsonReader{decoder: json.NewDecoder(r)}, nil
+	case "csv":
+		return &csvReader{reader: csv.NewReader(r)}, nil
+	default:
+		return nil, fmt.Errorf("output-format can be one of tsv, json or csv, not '%s'", format)
+	}
+}
+
+// tsvReader reads status, start time, duration, url, payload and optional error columns
+type tsvReader struct {
+	scanner *bufio.Scanner
+}
+
+func (t *tsvReader) Read() (*Result, error) {
+	for t.scanner.Scan() {
+		line := t.scanner.Text()
+
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("Not enough columns in timings log line: %s", line)
+		}
+
+		status, err := strconv.Atoi(fields[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		start, err := strconv.ParseInt(fields[1], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		duration, err := strconv.ParseInt(fields[2], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		res := &Result{
+			Status:   status,
+			Start:    time.Unix(start, 0),
+			Duration: time.Duration(duration),
+			URL:      fields[3],
+		}
+
+		if len(fields) > 4 {
+			res.Payload = fields[4]
+		}
+
+		if len(fields) > 5 && fields[5] != "" {
+			res.Status = 0
+			res.Err = errors.New(fields[5])
+		}
+
+		return res, nil
+	}
+
+	if err := t.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+type jsonReader struct {
+	decoder *json.Decoder
+}
+
+func (j *jsonReader) Read() (*Result, error) {
+	var record jsonRecord
+
+	if err := j.decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Status:           record.Status,
+		Start:            record.TS,
+		Duration:         time.Duration(record.DurationNs),
+		Method:           record.Method,
+		URL:              record.URL,
+		Payload:          record.Payload,
+		Worker:           record.Worker,
+		Target:           record.Target,
+		InitialStatus:    record.InitialStatus,
+		FinalURL:         record.FinalURL,
+		Attempts:         record.Attempts,
+		Headers:          record.Headers,
+		OriginalStatus:   record.OriginalStatus,
+		OriginalDuration: time.Duration(record.OriginalDurationNs),
+		TraceID:          record.TraceID,
+		SpanID:           record.SpanID,
+		Operation:        record.Operation,
+	}
+
+	if record.Error != "" {
+		res.Status = 0
+		res.Err = errors.New(record.Error)
+	}
+
+	return res, nil
+}
+
+// csvReader looks columns up by the header, so logs written by older versions can be read too
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func (c *csvReader) Read() (*Result, error) {
+	if c.columns == nil {
+		header, err := c.reader.Read()
+
+		if err != nil {
+			return nil, err
+		}
+
+		c.columns = make(map[string]int)
+
+		for i, name := range header {
+			c.columns[name] = i
+		}
+
+		// Records with added columns are fine
+		c.reader.FieldsPerRecord = -1
+	}
+
+	record, err := c.reader.Read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	value := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+
+		return ""
+	}
+
+	number := func(name string) int64 {
+		n, _ := strconv.ParseInt(value(name), 10, 64)
+
+		return n
+	}
+
+	start, err := time.Parse(time.RFC3339Nano, value("ts"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Status:           int(number("status")),
+		Start:            start,
+		Duration:         time.Duration(number("duration_ns")),
+		Method:           value("method"),
+		URL:              value("url"),
+		Payload:          value("payload"),
+		Worker:           int(number("worker")),
+		Target:           value("target"),
+		InitialStatus:    int(number("initial_status")),
+		FinalURL:         value("final_url"),
+		Attempts:         int(number("attempts")),
+		OriginalStatus:   int(number("original_status")),
+		OriginalDuration: time.Duration(number("original_duration_ns")),
+		TraceID:          value("trace_id"),
+		SpanID:           value("span_id"),
+	}
+
+	if errString := value("error"); errString != "" {
+		res.Status = 0
+		res.Err = errors.New(errString)
+	}
+
+	return res, nil
+}
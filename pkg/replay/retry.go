@@ -0,0 +1,41 @@
+package replay
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// retryable tells whether the attempt failed for a reason which is likely to go away shortly,
+// failed connections and gateway errors are retried
+func retryable(status int, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// retryDelay returns exponentially growing delay before the next attempt with full jitter
+func (r *Replayer) retryDelay(attempt int) time.Duration {
+	backoff := r.opts.RetryBackoff
+
+	for i := 1; i < attempt && backoff < r.opts.RetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > r.opts.RetryMaxBackoff {
+		backoff = r.opts.RetryMaxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Route is a user supplied endpoint pattern like /users/{id}/orders, {name} segments match
+// any single path segment and trailing * matches the rest of the path
+type Route struct {
+	Pattern  string
+	segments []string
+}
+
+// ParseRoute parses the route pattern
+func ParseRoute(pattern string) (Route, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return Route{}, fmt.Errorf("route has to start with /, not '%s'", pattern)
+	}
+
+	segments := strings.Split(pattern, "/")
+
+	for i, segment := range segments {
+		if segment == "*" && i != len(segments)-1 {
+			return Route{}, fmt.Errorf("* can only be the last segment of route '%s'", pattern)
+		}
+	}
+
+	return Route{Pattern: pattern, segments: segments}, nil
+}
+
+// Match tells whether the path (without query string) matches the route
+func (r Route) Match(path string) bool {
+	segments := strings.Split(path, "/")
+
+	for i, segment := range r.segments {
+		if segment == "*" {
+			return true
+		}
+
+		if i >= len(segments) {
+			return false
+		}
+
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return false
+			}
+
+			continue
+		}
+
+		if segment != segments[i] {
+			return false
+		}
+	}
+
+	return len(segments) == len(r.segments)
+}
+
+// RouteTimeout is the request timeout of paths matching the route, 0 means no timeout
+type RouteTimeout struct {
+	Route   Route
+	Timeout time.Duration
+}
+
+// splitOrigin splits absolute URL into scheme://host and the path with query, ok is false for relative URLs
+func splitOrigin(url string) (string, string, bool) {
+	i := strings.Index(url, "://")
+
+	if i <= 0 || strings.ContainsAny(url[:i], "/?#") {
+		return "", url, false
+	}
+
+	end := strings.IndexAny(url[i+3:], "/?#")
+
+	if end < 0 {
+		return url, "", true
+	}
+
+	return url[:i+3+end], url[i+3+end:], true
+}
+
+// urlPath drops scheme and host of absolute URLs, query string and fragment of the URL
+func urlPath(url string) string {
+	if _, rest, ok := splitOrigin(url); ok {
+		url = rest
+	}
+
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		return url[:i]
+	}
+
+	return url
+}
+
+// endpointName returns endpointPath of the result, GraphQL operation is appended after #
+func endpointName(r *Result, routes []Route) string {
+	path := endpointPath(r.URL, routes)
+
+	if r.Operation != "" {
+		return path + "#" + r.Operation
+	}
+
+	return path
+}
+
+// endpointPath returns the first matching route pattern, or the path with identifiers replaced by {id}
+func endpointPath(url string, routes []Route) string {
+	path := urlPath(url)
+
+	for _, route := range routes {
+		if route.Match(path) {
+			return route.Pattern
+		}
+	}
+
+	return normalizePath(path)
+}
@@ -0,0 +1,46 @@
+package replay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientFor(t *testing.T) {
+	var timeouts []RouteTimeout
+
+	for _, spec := range []struct {
+		pattern string
+		timeout time.Duration
+	}{{"/poll/*", 5 * time.Minute}, {"/users/{id}", time.Second}, {"/stream", 0}} {
+		route, err := ParseRoute(spec.pattern)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		timeouts = append(timeouts, RouteTimeout{Route: route, Timeout: spec.timeout})
+	}
+
+	r, err := New(Options{Timeout: 30 * time.Second, RouteTimeouts: timeouts}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url     string
+		timeout time.Duration
+	}{
+		{url: "/poll/a/b?wait=1", timeout: 5 * time.Minute},
+		{url: "/users/42", timeout: time.Second},
+		{url: "/users/42/posts", timeout: 30 * time.Second},
+		{url: "/stream?x=1", timeout: 0},
+		{url: "/other", timeout: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if timeout := r.clientFor(tt.url).Timeout; timeout != tt.timeout {
+			t.Errorf("%s has timeout %s, expected %s", tt.url, timeout, tt.timeout)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// SessionKey returns the session the entry belongs to, empty for entries which are not part of any
+type SessionKey func(entry *reader.LogEntry) string
+
+// ParseSessionKey parses comma separated list of entry fields making the session key: client (IP), ua, host
+// and query:name for the value of the name query parameter. Entries with all of them empty have no session
+func ParseSessionKey(spec string) (SessionKey, error) {
+	var parts []func(entry *reader.LogEntry) string
+
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+
+		switch {
+		case field == "client":
+			parts = append(parts, func(entry *reader.LogEntry) string { return entry.ClientIP })
+		case field == "ua":
+			parts = append(parts, func(entry *reader.LogEntry) string { return entry.UA })
+		case field == "host":
+			parts = append(parts, func(entry *reader.LogEntry) string { return entry.Host })
+		case strings.HasPrefix(field, "query:") && len(field) > len("query:"):
+			name := strings.TrimPrefix(field, "query:")
+			parts = append(parts, func(entry *reader.LogEntry) string { return queryParam(entry.URL, name) })
+		default:
+			return nil, fmt.Errorf("Unknown session key field '%s', expected client, ua, host or query:name", field)
+		}
+	}
+
+	return func(entry *reader.LogEntry) string {
+		var key strings.Builder
+		empty := true
+
+		for i, part := range parts {
+			value := part(entry)
+
+			if value != "" {
+				empty = false
+			}
+
+			if i > 0 {
+				key.WriteByte(0)
+			}
+
+			key.WriteString(value)
+		}
+
+		if empty {
+			return ""
+		}
+
+		return key.String()
+	}, nil
+}
+
+// queryParam returns value of the query parameter of the URL, empty when the URL does not have it
+func queryParam(rawURL, name string) string {
+	_, query, ok := strings.Cut(rawURL, "?")
+
+	if !ok {
+		return ""
+	}
+
+	values, _ := url.ParseQuery(query)
+
+	return values.Get(name)
+}
+
+// session is a virtual user sending requests of the same session one after another
+type session struct {
+	queue []*request
+	// running is set while a goroutine sends the queued requests
+	running bool
+}
+
+// sessions are the sessions with requests queued or in flight
+type sessions struct {
+	mu    sync.Mutex
+	byKey map[string]*session
+}
+
+func newSessions() *sessions {
+	return &sessions{byKey: make(map[string]*session)}
+}
+
+// enqueue queues the request of the session, the session goroutine is started unless it is running already
+func (r *Replayer) enqueue(ctx context.Context, key string, req *request) {
+	r.sessions.mu.Lock()
+
+	s, ok := r.sessions.byKey[key]
+
+	if !ok {
+		s = &session{}
+		r.sessions.byKey[key] = s
+	}
+
+	s.queue = append(s.queue, req)
+
+	if s.running {
+		r.sessions.mu.Unlock()
+		return
+	}
+
+	s.running = true
+	r.sessions.mu.Unlock()
+
+	go r.runSession(ctx, key, s)
+}
+
+// runSession sends queued requests of the session until the queue is empty, requests are not sent
+// once the replay is cancelled. Every request holds an in flight slot
+func (r *Replayer) runSession(ctx context.Context, key string, s *session) {
+	for {
+		r.sessions.mu.Lock()
+
+		if len(s.queue) == 0 {
+			delete(r.sessions.byKey, key)
+			r.sessions.mu.Unlock()
+
+			return
+		}
+
+		req := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		r.sessions.mu.Unlock()
+
+		if ctx.Err() != nil {
+			r.httpWg.Done()
+		} else {
+			r.send(ctx, req)
+		}
+
+		<-r.inFlight
+	}
+}
@@ -0,0 +1,51 @@
+package replay
+
+import (
+	"testing"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+func TestParseSessionKey(t *testing.T) {
+	entry := &reader.LogEntry{
+		URL:      "/cart?sid=abc&lang=en",
+		ClientIP: "10.0.0.1",
+		UA:       "curl/8.0",
+		Host:     "shop.example.com",
+	}
+
+	tests := []struct {
+		spec  string
+		entry *reader.LogEntry
+		key   string
+		err   bool
+	}{
+		{spec: "client", entry: entry, key: "10.0.0.1"},
+		{spec: "client, ua", entry: entry, key: "10.0.0.1\x00curl/8.0"},
+		{spec: "host,query:sid", entry: entry, key: "shop.example.com\x00abc"},
+		{spec: "query:sid", entry: &reader.LogEntry{URL: "/cart"}, key: ""},
+		// Values are kept apart, the key is empty only when all of them are
+		{spec: "client,ua", entry: &reader.LogEntry{UA: "curl/8.0"}, key: "\x00curl/8.0"},
+		{spec: "client,ua", entry: &reader.LogEntry{}, key: ""},
+		{spec: "cookie", err: true},
+		{spec: "query:", err: true},
+		{spec: "client,", err: true},
+	}
+
+	for _, tt := range tests {
+		key, err := ParseSessionKey(tt.spec)
+
+		if (err != nil) != tt.err {
+			t.Errorf("ParseSessionKey(%q) returned error %v", tt.spec, err)
+			continue
+		}
+
+		if tt.err {
+			continue
+		}
+
+		if got := key(tt.entry); got != tt.key {
+			t.Errorf("key of %q is %q, expected %q", tt.spec, got, tt.key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ShadowResult is the response of the Options.ShadowPrefix target
+type ShadowResult struct {
+	Status   int
+	Duration time.Duration
+	BodyHash string
+	Err      error
+}
+
+// ReportedStatus mirrors Result.ReportedStatus, failed requests are reported with 500 status
+func (s *ShadowResult) ReportedStatus() int {
+	if s.Err != nil {
+		return 500
+	}
+
+	return s.Status
+}
+
+// sendShadow replays request with the URL and payload against the shadow target, it is sent once without retries
+// and keeps cookies of the client apart from the ones of the targets
+func (r *Replayer) sendShadow(ctx context.Context, rq *request, method, url, payload, session string) *ShadowResult {
+	res := &ShadowResult{}
+	start := time.Now()
+
+	req, err := r.newRequest(ctx, method, r.opts.ShadowPrefix+url, payload, rq.Entry, session)
+
+	if err == nil {
+		var resp *http.Response
+
+		setHeader(req, rq.header)
+		resp, err = withCookies(r.clientFor(url), r.shadowCookies, rq.Entry.ClientIP).Do(req)
+
+		if err == nil {
+			res.BodyHash, err = r.readBody(resp.Body, nil)
+			res.Status = r.status(resp)
+		}
+	}
+
+	res.Duration = time.Since(start)
+	res.Err = err
+
+	return res
+}
+
+// StatusDiffers tells whether the shadow target responded with a different status,
+// failing to get a response from only one of the targets counts as a difference
+func (r *Result) StatusDiffers() bool {
+	if (r.Err != nil) != (r.Shadow.Err != nil) {
+		return true
+	}
+
+	return r.Err == nil && r.Status != r.Shadow.Status
+}
+
+// BodyDiffers tells whether bodies of successful responses have different hashes,
+// hashes are only present with Options.DiffBody
+func (r *Result) BodyDiffers() bool {
+	return r.BodyHash != "" && r.Shadow.BodyHash != "" && r.BodyHash != r.Shadow.BodyHash
+}
@@ -0,0 +1,40 @@
+package replay
+
+import (
+	"time"
+)
+
+// ResultSink receives results of replayed requests, Write is called from a single goroutine
+// in the order requests finish, returned error stops the replay
+type ResultSink interface {
+	Write(r *Result) error
+}
+
+// Observer is optionally implemented by sinks interested in the progress of the replay
+// before the results come in, methods are called concurrently with Write
+type Observer interface {
+	// Dispatched is called when a log entry is handed over to be sent, lag is how far behind
+	// the schedule the replay is and logTime is the original timestamp of the entry
+	Dispatched(lag time.Duration, logTime time.Time)
+	// RequestStarted is called right before the request is sent to the target
+	RequestStarted()
+}
+
+// WindowState is a snapshot of the error window
+type WindowState struct {
+	// Samples is the number of requests in the window, it stays at Size once the window is full
+	Samples int
+	Size    int
+	// ErrorRate is the percentage of failed requests among the samples
+	ErrorRate float64
+	// Latency is the Options.LatencyPercentile of request durations among the samples
+	Latency time.Duration
+	Paused  bool
+	Trips   int
+}
+
+// WindowObserver is optionally implemented by sinks interested in the error window,
+// WindowUpdated is called every time the window is evaluated, paused or resumed
+type WindowObserver interface {
+	WindowUpdated(state WindowState)
+}
@@ -0,0 +1,71 @@
+package replay
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// clientIPKey is the context key of the client IP requests are bound to a source address by
+type clientIPKey struct{}
+
+// withClientIP returns context of request of the client for Options.SourceByClient
+func withClientIP(ctx context.Context, clientIP string) context.Context {
+	if clientIP == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, clientIPKey{}, clientIP)
+}
+
+// sourceTransport spreads requests over transports connecting from different local addresses,
+// every transport keeps its own connections so that a reused connection does not change the source
+type sourceTransport struct {
+	transports []http.RoundTripper
+	byClient   bool
+	next       uint32
+}
+
+// newSourceTransport creates transport for every address of Options.SourceIPs
+func newSourceTransport(opts *Options) (http.RoundTripper, error) {
+	t := &sourceTransport{byClient: opts.SourceByClient}
+
+	for _, ip := range opts.SourceIPs {
+		transport, err := newBaseTransport(opts, &net.TCPAddr{IP: ip})
+
+		if err != nil {
+			return nil, err
+		}
+
+		t.transports = append(t.transports, transport)
+	}
+
+	return t, nil
+}
+
+// RoundTrip sends the request from the source of its client, requests without client IP (or all of them
+// without Options.SourceByClient) take the sources in turns
+func (t *sourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := uint32(len(t.transports))
+	clientIP, _ := req.Context().Value(clientIPKey{}).(string)
+
+	if t.byClient && clientIP != "" {
+		h := fnv.New32a()
+		h.Write([]byte(clientIP))
+
+		return t.transports[h.Sum32()%n].RoundTrip(req)
+	}
+
+	return t.transports[(atomic.AddUint32(&t.next, 1)-1)%n].RoundTrip(req)
+}
+
+// CloseIdleConnections closes idle connections of all sources
+func (t *sourceTransport) CloseIdleConnections() {
+	for _, transport := range t.transports {
+		if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"database/sql"
+
+	// Pure Go driver, keeps the binary free of cgo
+	_ "modernc.org/sqlite"
+)
+
+// Rows are inserted in transactions of this size, committing every row is too slow
+const sqliteBatchSize = 1000
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	id INTEGER PRIMARY KEY,
+	status INTEGER NOT NULL,
+	ts TEXT NOT NULL,
+	duration_ns INTEGER NOT NULL,
+	method TEXT NOT NULL,
+	url TEXT NOT NULL,
+	payload TEXT,
+	error TEXT,
+	worker INTEGER NOT NULL,
+	target TEXT,
+	attempts INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_status ON results (status);
+CREATE INDEX IF NOT EXISTS results_ts ON results (ts);
+CREATE INDEX IF NOT EXISTS results_url ON results (url);
+`
+
+const sqliteInsert = `INSERT INTO results (status, ts, duration_ns, method, url, payload, error, worker, target, attempts)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// Fixed width UTC timestamps sort as text and work with SQLite date functions
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000"
+
+// SQLiteWriter is a sink inserting results into results table of SQLite database,
+// status is reported as in other outputs (500 for failed requests) with the error in error column
+type SQLiteWriter struct {
+	db    *sql.DB
+	tx    *sql.Tx
+	stmt  *sql.Stmt
+	count int
+}
+
+// NewSQLiteWriter opens (or creates) the database and the results table
+func NewSQLiteWriter(path string) (*SQLiteWriter, error) {
+	db, err := sql.Open("sqlite", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteWriter{db: db}, nil
+}
+
+// Write inserts single result, rows are committed in batches and on Close
+func (s *SQLiteWriter) Write(r *Result) error {
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+
+		if err != nil {
+			return err
+		}
+
+		stmt, err := tx.Prepare(sqliteInsert)
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		s.tx = tx
+		s.stmt = stmt
+	}
+
+	var errString sql.NullString
+
+	if r.Err != nil {
+		errString = sql.NullString{String: r.Err.Error(), Valid: true}
+	}
+
+	_, err := s.stmt.Exec(r.ReportedStatus(), r.Start.UTC().Format(sqliteTimeLayout), r.Duration.Nanoseconds(), r.Method, r.URL,
+		nullString(r.Payload), errString, r.Worker, nullString(r.Target), r.Attempts)
+
+	if err != nil {
+		return err
+	}
+
+	if s.count++; s.count%sqliteBatchSize == 0 {
+		return s.commit()
+	}
+
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (s *SQLiteWriter) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	s.stmt.Close()
+	err := s.tx.Commit()
+	s.tx = nil
+	s.stmt = nil
+
+	return err
+}
+
+// Close commits pending rows and closes the database
+func (s *SQLiteWriter) Close() error {
+	if err := s.commit(); err != nil {
+		s.db.Close()
+		return err
+	}
+
+	return s.db.Close()
+}
@@ -0,0 +1,88 @@
+package replay
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// IntervalStats is a sink logging throughput, errors and latency of every interval of the replay
+type IntervalStats struct {
+	mu        sync.Mutex
+	start     time.Time
+	total     int
+	errors    int
+	status5xx int
+	latency   *hdrhistogram.Histogram
+}
+
+// NewIntervalStats creates stats with the first interval starting now
+func NewIntervalStats() *IntervalStats {
+	return &IntervalStats{
+		start:   time.Now(),
+		latency: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+	}
+}
+
+// Write accounts finished request
+func (s *IntervalStats) Write(r *Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.total++
+
+	if r.Err != nil {
+		s.errors++
+	} else if r.Status >= 500 {
+		s.status5xx++
+	}
+
+	recordDuration(s.latency, r.Duration)
+
+	return nil
+}
+
+// Run logs stats of the interval every interval until the context is done
+func (s *IntervalStats) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.print()
+		}
+	}
+}
+
+func (s *IntervalStats) print() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(s.start)
+
+	var errorRate, rate5xx float64
+	var p50, p99 time.Duration
+
+	if s.total > 0 {
+		errorRate = float64(s.errors) * 100 / float64(s.total)
+		rate5xx = float64(s.status5xx) * 100 / float64(s.total)
+		p50 = time.Duration(s.latency.ValueAtQuantile(50)).Round(time.Microsecond)
+		p99 = time.Duration(s.latency.ValueAtQuantile(99)).Round(time.Microsecond)
+	}
+
+	log.Printf("Last %s: %.2f req/s, errors %.2f%%, 5xx %.2f%%, p50 %s, p99 %s", elapsed.Round(time.Second),
+		float64(s.total)/elapsed.Seconds(), errorRate, rate5xx, p50, p99)
+
+	s.start = now
+	s.total = 0
+	s.errors = 0
+	s.status5xx = 0
+	s.latency.Reset()
+}
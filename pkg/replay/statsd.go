@@ -0,0 +1,62 @@
+package replay
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// StatsdClient is a sink sending per request metrics over UDP in StatsD format,
+// tags are appended in DogStatsD format when enabled
+type StatsdClient struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+}
+
+// NewStatsdClient creates client sending metrics named with the prefix to the UDP address
+func NewStatsdClient(addr string, prefix string, tags bool) (*StatsdClient, error) {
+	conn, err := net.Dial("udp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	return &StatsdClient{conn: conn, prefix: prefix, tags: tags}, nil
+}
+
+// Tag values can not contain separators used by DogStatsD format
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", " ", "_")
+
+// Write reports request count and timing of a single result
+func (s *StatsdClient) Write(r *Result) error {
+	status := "error"
+
+	if r.Err == nil {
+		status = strconv.Itoa(r.Status)
+	}
+
+	var tags string
+
+	if s.tags {
+		tags = fmt.Sprintf("|#method:%s,path:%s,status:%s",
+			statsdTagReplacer.Replace(r.Method), statsdTagReplacer.Replace(normalizePath(r.URL)), status)
+	}
+
+	packet := fmt.Sprintf("%srequests:1|c%s\n%sduration:%.3f|ms%s",
+		s.prefix, tags, s.prefix, float64(r.Duration.Nanoseconds())/1e6, tags)
+
+	// Metrics are best effort, UDP write errors are not worth stopping the replay for
+	s.conn.Write([]byte(packet))
+
+	return nil
+}
+
+func (s *StatsdClient) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,220 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latencies are recorded in nanoseconds with microsecond resolution up to an hour
+const (
+	histogramMin     = int64(time.Microsecond)
+	histogramMax     = int64(time.Hour)
+	histogramSigFigs = 3
+)
+
+// Summary is a sink aggregating results of the whole run
+type Summary struct {
+	Total    int
+	Errors   int
+	Statuses map[int]int
+	First    time.Time
+	Last     time.Time
+	// Latency is the service time, from the moment request was sent until the response was read
+	Latency *hdrhistogram.Histogram
+	// Corrected is the response time from the moment request was scheduled to be sent,
+	// it includes time spent waiting for a free worker and is not affected by coordinated omission
+	Corrected *hdrhistogram.Histogram
+	// Targets break results down by prefix when requests are spread across several
+	Targets map[string]*targetSummary
+}
+
+// targetSummary aggregates results of a single prefix
+type targetSummary struct {
+	Total   int
+	Errors  int
+	Latency *hdrhistogram.Histogram
+}
+
+// NewSummary creates empty summary
+func NewSummary() *Summary {
+	return &Summary{
+		Statuses:  make(map[int]int),
+		Latency:   hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		Corrected: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs),
+		Targets:   make(map[string]*targetSummary),
+	}
+}
+
+func recordDuration(h *hdrhistogram.Histogram, d time.Duration) {
+	v := int64(d)
+
+	if v < histogramMin {
+		v = histogramMin
+	} else if v > histogramMax {
+		v = histogramMax
+	}
+
+	h.RecordValue(v)
+}
+
+// Write accounts single result
+func (s *Summary) Write(r *Result) error {
+	s.Total++
+
+	if r.Err != nil {
+		s.Errors++
+	} else {
+		s.Statuses[r.Status]++
+	}
+
+	recordDuration(s.Latency, r.Duration)
+
+	corrected := r.Duration
+
+	if !r.Scheduled.IsZero() && r.Scheduled.Before(r.Start) {
+		corrected += r.Start.Sub(r.Scheduled)
+	}
+
+	recordDuration(s.Corrected, corrected)
+
+	if r.Target != "" {
+		target, ok := s.Targets[r.Target]
+
+		if !ok {
+			target = &targetSummary{Latency: hdrhistogram.New(histogramMin, histogramMax, histogramSigFigs)}
+			s.Targets[r.Target] = target
+		}
+
+		target.Total++
+
+		if r.Err != nil {
+			target.Errors++
+		}
+
+		recordDuration(target.Latency, r.Duration)
+	}
+
+	if s.First.IsZero() || r.Start.Before(s.First) {
+		s.First = r.Start
+	}
+
+	if end := r.Start.Add(r.Duration); end.After(s.Last) {
+		s.Last = end
+	}
+
+	return nil
+}
+
+func printLatency(w io.Writer, title string, h *hdrhistogram.Histogram) {
+	value := func(v int64) time.Duration {
+		return time.Duration(v).Round(time.Microsecond)
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	fmt.Fprintf(w, "  min\t%s\n", value(h.Min()))
+	fmt.Fprintf(w, "  avg\t%s\n", value(int64(h.Mean())))
+	fmt.Fprintf(w, "  p50\t%s\n", value(h.ValueAtQuantile(50)))
+	fmt.Fprintf(w, "  p90\t%s\n", value(h.ValueAtQuantile(90)))
+	fmt.Fprintf(w, "  p99\t%s\n", value(h.ValueAtQuantile(99)))
+	fmt.Fprintf(w, "  max\t%s\n", value(h.Max()))
+}
+
+// Print writes human readable report
+func (s *Summary) Print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Requests:\t%d\n", s.Total)
+
+	if s.Total == 0 {
+		return
+	}
+
+	elapsed := s.Last.Sub(s.First)
+
+	fmt.Fprintf(w, "Errors:\t%d (%.2f%%)\n", s.Errors, float64(s.Errors)*100/float64(s.Total))
+	fmt.Fprintf(w, "Elapsed:\t%s\n", elapsed.Round(time.Millisecond))
+
+	if elapsed > 0 {
+		fmt.Fprintf(w, "Throughput:\t%.2f req/s\n", float64(s.Total)/elapsed.Seconds())
+	}
+
+	var statuses []int
+
+	for status := range s.Statuses {
+		statuses = append(statuses, status)
+	}
+
+	sort.Ints(statuses)
+
+	fmt.Fprintf(w, "Status codes:\n")
+
+	for _, status := range statuses {
+		fmt.Fprintf(w, "  %d\t%d\n", status, s.Statuses[status])
+	}
+
+	printLatency(w, "Latency", s.Latency)
+
+	// Only worth showing when requests had to wait for their turn
+	if s.Corrected.Max() > s.Latency.Max() {
+		printLatency(w, "Latency (corrected for coordinated omission)", s.Corrected)
+	}
+
+	// Target names are long, align them separately to keep the report above compact
+	w.Flush()
+	s.printTargets(out)
+}
+
+func (s *Summary) printTargets(out io.Writer) {
+	if len(s.Targets) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	var targets []string
+
+	for target := range s.Targets {
+		targets = append(targets, target)
+	}
+
+	sort.Strings(targets)
+
+	fmt.Fprintf(w, "Targets:\trequests\terrors\tp50\tp99\n")
+
+	for _, target := range targets {
+		t := s.Targets[target]
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%s\t%s\n", target, t.Total, t.Errors,
+			time.Duration(t.Latency.ValueAtQuantile(50)).Round(time.Microsecond),
+			time.Duration(t.Latency.ValueAtQuantile(99)).Round(time.Microsecond))
+	}
+}
+
+// WriteHistogram writes corrected latency histogram in HdrHistogram log format
+func (s *Summary) WriteHistogram(out io.Writer) error {
+	lw := hdrhistogram.NewHistogramLogWriter(out)
+
+	s.Corrected.SetStartTimeMs(s.First.UnixNano() / int64(time.Millisecond))
+	s.Corrected.SetEndTimeMs(s.Last.UnixNano() / int64(time.Millisecond))
+	lw.SetBaseTime(s.Corrected.StartTimeMs())
+
+	if err := lw.OutputLogFormatVersion(); err != nil {
+		return err
+	}
+
+	if err := lw.OutputStartTime(s.Corrected.StartTimeMs()); err != nil {
+		return err
+	}
+
+	if err := lw.OutputLegend(); err != nil {
+		return err
+	}
+
+	return lw.OutputIntervalHistogram(s.Corrected)
+}
@@ -0,0 +1,43 @@
+package replay
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+)
+
+// newTraceIDs returns random 16 byte trace and 8 byte span IDs, hex encoded
+func newTraceIDs() (string, string) {
+	var ids [24]byte
+
+	binary.LittleEndian.PutUint64(ids[0:], rand.Uint64())
+	binary.LittleEndian.PutUint64(ids[8:], rand.Uint64())
+	binary.LittleEndian.PutUint64(ids[16:], rand.Uint64())
+
+	return hex.EncodeToString(ids[:16]), hex.EncodeToString(ids[16:])
+}
+
+// TraceParent returns W3C traceparent header value of the sampled request span
+func (r *Result) TraceParent() string {
+	return "00-" + r.TraceID + "-" + r.SpanID + "-01"
+}
+
+// setTraceHeaders sets the headers to the trace context of the result
+func setTraceHeaders(header http.Header, names []string, r *Result) {
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "traceparent":
+			header.Set("traceparent", r.TraceParent())
+		case "b3":
+			header.Set("b3", r.TraceID+"-"+r.SpanID+"-1")
+		case "b3multi":
+			header.Set("X-B3-TraceId", r.TraceID)
+			header.Set("X-B3-SpanId", r.SpanID)
+			header.Set("X-B3-Sampled", "1")
+		default:
+			header.Set(name, r.TraceID)
+		}
+	}
+}
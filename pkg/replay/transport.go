@@ -0,0 +1,214 @@
+package replay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// loadCertPool adds PEM encoded certificates from the file to the system pool
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No PEM encoded certificates found in %s", file)
+	}
+
+	return pool, nil
+}
+
+// newTransport configures http.RoundTripper according to the options
+func newTransport(opts *Options) (http.RoundTripper, error) {
+	var transport http.RoundTripper
+	var err error
+
+	if len(opts.SourceIPs) > 0 {
+		transport, err = newSourceTransport(opts)
+	} else {
+		transport, err = newBaseTransport(opts, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OAuth2TokenURL != "" {
+		transport = withOAuth2(opts, transport)
+	}
+
+	return transport, nil
+}
+
+// withOAuth2 attaches bearer token obtained with OAuth2 client credentials grant,
+// token is cached and fetched again when it expires
+func withOAuth2(opts *Options, base http.RoundTripper) http.RoundTripper {
+	config := &clientcredentials.Config{
+		ClientID:     opts.OAuth2ClientID,
+		ClientSecret: opts.OAuth2ClientSecret,
+		TokenURL:     opts.OAuth2TokenURL,
+		Scopes:       opts.OAuth2Scopes,
+	}
+
+	return &oauth2.Transport{
+		Source: config.TokenSource(context.Background()),
+		Base:   base,
+	}
+}
+
+// resolve returns address the host:port is overridden with, port is kept when the address has none
+func resolve(overrides map[string]string, addr string) string {
+	override, ok := overrides[strings.ToLower(addr)]
+
+	if !ok {
+		return addr
+	}
+
+	if _, _, err := net.SplitHostPort(override); err == nil {
+		return override
+	}
+
+	_, port, _ := net.SplitHostPort(addr)
+
+	return net.JoinHostPort(override, port)
+}
+
+// dialSettings are how connections to the targets are made, shared by HTTP and WebSocket clients
+type dialSettings struct {
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig *tls.Config
+	proxy     func(*http.Request) (*url.URL, error)
+}
+
+// newDialSettings configures connections from the local address, any address when it is nil
+func newDialSettings(opts *Options, localAddr net.Addr) (*dialSettings, error) {
+	targets, err := unixTargets(opts.Targets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := unixSockets(targets)
+	dialer := &net.Dialer{KeepAlive: opts.TCPKeepAlive, LocalAddr: localAddr}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if socket, ok := sockets[addr]; ok {
+			unixDialer := &net.Dialer{KeepAlive: opts.TCPKeepAlive}
+			return unixDialer.DialContext(ctx, "unix", socket)
+		}
+
+		return dialer.DialContext(ctx, network, resolve(opts.Resolve, addr))
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.SSLSkipVerify}
+
+	if opts.HostHeader != "" {
+		serverName := opts.HostHeader
+
+		if host, _, err := net.SplitHostPort(opts.HostHeader); err == nil {
+			serverName = host
+		}
+
+		tlsConfig.ServerName = serverName
+	}
+
+	if opts.TLSCert != "" || opts.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.TLSCA != "" {
+		pool, err := loadCertPool(opts.TLSCA)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are respected unless Proxy is given
+	proxy := http.ProxyFromEnvironment
+
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			return nil, fmt.Errorf("proxy scheme can be one of http, https or socks5, not '%s'", u.Scheme)
+		}
+
+		proxy = http.ProxyURL(u)
+	}
+
+	return &dialSettings{dial: dial, tlsConfig: tlsConfig, proxy: proxy}, nil
+}
+
+// newBaseTransport configures transport connecting from the local address, any address when it is nil
+func newBaseTransport(opts *Options, localAddr net.Addr) (http.RoundTripper, error) {
+	s, err := newDialSettings(opts, localAddr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// HTTP/2 over cleartext with prior knowledge, no HTTP/1.1 upgrade dance
+	if opts.H2C {
+		if opts.Proxy != "" {
+			return nil, fmt.Errorf("proxy is not supported together with h2c")
+		}
+
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return s.dial(context.Background(), network, addr)
+			},
+		}, nil
+	}
+
+	transport := &http.Transport{
+		DialContext:         s.dial,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		DisableKeepAlives:   opts.DisableKeepAlives,
+		TLSClientConfig:     s.tlsConfig,
+		Proxy:               s.proxy,
+	}
+
+	if opts.HTTP2 {
+		if err := http2.ConfigureTransport(transport); err != nil {
+			return nil, err
+		}
+	}
+
+	return transport, nil
+}
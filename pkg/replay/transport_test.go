@@ -0,0 +1,71 @@
+package replay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	overrides := map[string]string{
+		"api.example.com:443": "10.0.0.5",
+		"api.example.com:80":  "10.0.0.6:8080",
+		"v6.example.com:443":  "::1",
+		"v6.example.com:80":   "[::1]:8080",
+	}
+
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "api.example.com:443", expected: "10.0.0.5:443"},
+		{addr: "API.Example.com:443", expected: "10.0.0.5:443"},
+		{addr: "api.example.com:80", expected: "10.0.0.6:8080"},
+		{addr: "v6.example.com:443", expected: "[::1]:443"},
+		{addr: "v6.example.com:80", expected: "[::1]:8080"},
+		{addr: "api.example.com:8443", expected: "api.example.com:8443"},
+		{addr: "other.example.com:443", expected: "other.example.com:443"},
+	}
+
+	for _, tt := range tests {
+		if addr := resolve(overrides, tt.addr); addr != tt.expected {
+			t.Errorf("%s resolved to %s, expected %s", tt.addr, addr, tt.expected)
+		}
+	}
+}
+
+func TestResolveDial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	transport, err := newTransport(&Options{Resolve: map[string]string{"api.example.com:80": addr}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: transport}
+	resp, err := client.Get("http://api.example.com/health")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection goes to the override, the Host header stays
+	if string(body) != "api.example.com" {
+		t.Errorf("server got Host %q, expected api.example.com", body)
+	}
+}
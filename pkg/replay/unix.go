@@ -0,0 +1,68 @@
+package replay
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unixScheme prefixes targets listening on a Unix domain socket, unix:///run/app.sock:/api
+// sends requests to the socket with /api path prefix
+const unixScheme = "unix://"
+
+// unixTarget is a Unix domain socket target, requests are sent to its placeholder host
+// which the transport dials the socket for, so that every socket gets its own connection pool
+type unixTarget struct {
+	socket string
+	host   string
+	// base replaces the target prefix in request URLs
+	base string
+}
+
+// unixHost is the placeholder host of the i-th target
+func unixHost(i int) string {
+	return fmt.Sprintf("unix-socket-%d", i)
+}
+
+// unixTargets returns Unix domain socket targets by their prefix
+func unixTargets(targets []Target) (map[string]unixTarget, error) {
+	sockets := make(map[string]unixTarget)
+
+	for i, target := range targets {
+		if !strings.HasPrefix(target.URL, unixScheme) {
+			continue
+		}
+
+		socket, path, _ := strings.Cut(strings.TrimPrefix(target.URL, unixScheme), ":")
+
+		if socket == "" {
+			return nil, fmt.Errorf("unix target has to be unix:///path/to.sock with optional :/path prefix, not '%s'", target.URL)
+		}
+
+		host := unixHost(i)
+		sockets[target.URL] = unixTarget{socket: socket, host: host, base: "http://" + host + strings.TrimSuffix(path, "/")}
+	}
+
+	return sockets, nil
+}
+
+// unixSockets returns sockets by host:port the transport is asked to dial for them
+func unixSockets(targets map[string]unixTarget) map[string]string {
+	sockets := make(map[string]string, len(targets))
+
+	for _, target := range targets {
+		sockets[target.host+":80"] = target.socket
+	}
+
+	return sockets
+}
+
+// isUnixHost tells whether the host is a placeholder of a Unix domain socket target
+func (r *Replayer) isUnixHost(host string) bool {
+	for _, target := range r.unix {
+		if target.host == host {
+			return true
+		}
+	}
+
+	return false
+}
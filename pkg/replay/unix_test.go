@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnixTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		targets  []Target
+		expected map[string]unixTarget
+		err      string
+	}{
+		{name: "http only", targets: []Target{{URL: "http://localhost:8080", Weight: 1}}, expected: map[string]unixTarget{}},
+		{
+			name:     "socket",
+			targets:  []Target{{URL: "unix:///run/app.sock", Weight: 1}},
+			expected: map[string]unixTarget{"unix:///run/app.sock": {socket: "/run/app.sock", host: "unix-socket-0", base: "http://unix-socket-0"}},
+		},
+		{
+			name:    "sockets with path prefixes",
+			targets: []Target{{URL: "http://localhost:8080", Weight: 1}, {URL: "unix:///run/a.sock:/api/", Weight: 1}, {URL: "unix:///run/b.sock:/v2", Weight: 1}},
+			expected: map[string]unixTarget{
+				"unix:///run/a.sock:/api/": {socket: "/run/a.sock", host: "unix-socket-1", base: "http://unix-socket-1/api"},
+				"unix:///run/b.sock:/v2":   {socket: "/run/b.sock", host: "unix-socket-2", base: "http://unix-socket-2/v2"},
+			},
+		},
+		{name: "missing socket", targets: []Target{{URL: "unix://:/api", Weight: 1}}, err: "unix target has to be unix:///path/to.sock"},
+		{name: "empty", targets: []Target{{URL: "unix://", Weight: 1}}, err: "not 'unix://'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := unixTargets(tt.targets)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(targets, tt.expected) {
+				t.Errorf("targets %+v, expected %+v", targets, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnixDial(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+
+	if err != nil {
+		t.Skip(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	targets := []Target{{URL: "http://localhost:1", Weight: 1}, {URL: "unix://" + socket + ":/api", Weight: 1}}
+	transport, err := newTransport(&Options{Targets: targets})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unix, err := unixTargets(targets)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: transport}
+	resp, err := client.Get(unix[targets[1].URL].base + "/users")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "/api/users" {
+		t.Errorf("server got path %q, expected /api/users", body)
+	}
+}
@@ -0,0 +1,38 @@
+package replay
+
+import (
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// userAgent returns User-Agent of the request of the entry, the logged one unless Options.UserAgent
+// or Options.UserAgents replace it
+func (r *Replayer) userAgent(entry *reader.LogEntry) string {
+	replaced := r.opts.UserAgent != "" || len(r.opts.UserAgents) > 0
+
+	if entry.UA != "" && (r.opts.PreserveUA || !replaced) {
+		return entry.UA
+	}
+
+	if r.opts.UserAgent != "" {
+		return r.opts.UserAgent
+	}
+
+	n := len(r.opts.UserAgents)
+
+	if n == 0 {
+		return entry.UA
+	}
+
+	if entry.ClientIP == "" {
+		return r.opts.UserAgents[rand.Intn(n)]
+	}
+
+	// Clients keep their user agent for the whole replay
+	h := fnv.New32a()
+	h.Write([]byte(entry.ClientIP))
+
+	return r.opts.UserAgents[h.Sum32()%uint32(n)]
+}
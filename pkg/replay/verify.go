@@ -0,0 +1,80 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// StatusMismatch tells whether the replayed request did not get the originally logged status,
+// failed requests count as mismatches, entries without logged status are never mismatched
+func (r *Result) StatusMismatch() bool {
+	if r.OriginalStatus == 0 {
+		return false
+	}
+
+	return r.Err != nil || r.Status != r.OriginalStatus
+}
+
+// VerifySummary is a sink aggregating replayed statuses not matching the originally logged ones
+type VerifySummary struct {
+	Total       int
+	Unknown     int
+	Mismatches  int
+	StatusPairs map[[2]int]int
+}
+
+// NewVerifySummary creates empty verification summary
+func NewVerifySummary() *VerifySummary {
+	return &VerifySummary{StatusPairs: make(map[[2]int]int)}
+}
+
+// Write accounts single result
+func (v *VerifySummary) Write(r *Result) error {
+	if r.OriginalStatus == 0 {
+		v.Unknown++
+		return nil
+	}
+
+	v.Total++
+
+	if r.StatusMismatch() {
+		v.Mismatches++
+		v.StatusPairs[[2]int{r.OriginalStatus, r.ReportedStatus()}]++
+	}
+
+	return nil
+}
+
+// Print writes human readable verification report
+func (v *VerifySummary) Print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 14, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Status verify:\n")
+
+	if v.Unknown > 0 {
+		fmt.Fprintf(w, "  unknown\t%d\n", v.Unknown)
+	}
+
+	if v.Total == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "  mismatch\t%d (%.2f%%)\n", v.Mismatches, float64(v.Mismatches)*100/float64(v.Total))
+
+	var pairs [][2]int
+
+	for pair := range v.StatusPairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0] || pairs[i][0] == pairs[j][0] && pairs[i][1] < pairs[j][1]
+	})
+
+	for _, pair := range pairs {
+		fmt.Fprintf(w, "    %d -> %d\t%d\n", pair[0], pair[1], v.StatusPairs[pair])
+	}
+}
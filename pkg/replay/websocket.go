@@ -0,0 +1,202 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// webSocketCloseTimeout is how long the server has to answer the close frame
+const webSocketCloseTimeout = time.Second
+
+// WebSocketResult is the outcome of a replayed WebSocket session
+type WebSocketResult struct {
+	// CloseCode is the code of the close frame of the server, 1006 when the connection was dropped without it
+	// and 0 when the server did not answer the close frame in time
+	CloseCode int
+	Sent      int
+	Received  int
+	// Duration is how long the session was open, Result.Duration is the handshake latency
+	Duration time.Duration
+}
+
+// newWebSocketDialer makes WebSocket connections the same way as HTTP ones
+func newWebSocketDialer(opts *Options) (*websocket.Dialer, error) {
+	s, err := newDialSettings(opts, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &websocket.Dialer{
+		NetDialContext:   s.dial,
+		TLSClientConfig:  s.tlsConfig,
+		Proxy:            s.proxy,
+		HandshakeTimeout: opts.Timeout,
+	}, nil
+}
+
+// webSocketURL returns ws:// or wss:// URL of the http:// or https:// target
+func webSocketURL(target string) string {
+	if strings.HasPrefix(target, "https://") {
+		return "wss://" + strings.TrimPrefix(target, "https://")
+	}
+
+	return "ws://" + strings.TrimPrefix(target, "http://")
+}
+
+// replayWebSocket opens WebSocket session of the entry and sends its messages at their original offsets (sped up
+// by Options.Ratio, right away with SkipSleep), the session is closed at the offset of the last logged message
+func (r *Replayer) replayWebSocket(ctx context.Context, rq *request, res *Result, target, session string) {
+	req, err := r.newRequest(ctx, http.MethodGet, target, "", rq.Entry, session)
+
+	if err != nil {
+		res.Err = err
+		return
+	}
+
+	setHeader(req, rq.header)
+	setTraceHeaders(req.Header, r.opts.TraceHeaders, res)
+
+	// The dialer sets handshake headers itself and refuses the logged ones
+	for _, name := range []string{"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions"} {
+		req.Header.Del(name)
+	}
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		req.Header.Set("Host", req.Host)
+	}
+
+	dialer := *r.wsDialer
+
+	if r.cookies != nil && rq.Entry.ClientIP != "" {
+		dialer.Jar = r.cookies.jar(rq.Entry.ClientIP)
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, webSocketURL(target), req.Header)
+	res.Duration = time.Since(res.Start)
+
+	if resp != nil {
+		res.Status = resp.StatusCode
+	}
+
+	if err != nil {
+		res.Err = err
+		return
+	}
+
+	defer conn.Close()
+
+	ws := &WebSocketResult{}
+	res.WebSocket = ws
+	opened := time.Now()
+
+	// Messages of the server are read until it closes the connection
+	var received int
+	closed := make(chan error, 1)
+
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+
+			received++
+		}
+	}()
+
+	var closeErr error
+	var last time.Duration
+	serverClosed := false
+
+	for _, msg := range rq.Entry.Messages {
+		offset := msg.Offset / time.Duration(r.opts.Ratio)
+
+		if offset > last {
+			last = offset
+		}
+
+		if !msg.Sent {
+			continue
+		}
+
+		if !r.opts.SkipSleep {
+			if serverClosed, closeErr = waitWebSocket(ctx, opened.Add(offset), closed); serverClosed || ctx.Err() != nil {
+				break
+			}
+		}
+
+		messageType := websocket.TextMessage
+
+		if msg.Binary {
+			messageType = websocket.BinaryMessage
+		}
+
+		if err := conn.WriteMessage(messageType, []byte(msg.Data)); err != nil {
+			break
+		}
+
+		ws.Sent++
+	}
+
+	if !serverClosed && !r.opts.SkipSleep && ctx.Err() == nil {
+		serverClosed, closeErr = waitWebSocket(ctx, opened.Add(last), closed)
+	}
+
+	if !serverClosed {
+		message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(webSocketCloseTimeout))
+
+		select {
+		case closeErr = <-closed:
+			serverClosed = true
+		case <-time.After(webSocketCloseTimeout):
+		}
+	}
+
+	ws.Duration = time.Since(opened)
+
+	if !serverClosed {
+		res.Err = errors.New("websocket close frame was not answered")
+		return
+	}
+
+	ws.Received = received
+
+	var ce *websocket.CloseError
+
+	if !errors.As(closeErr, &ce) {
+		ws.CloseCode = websocket.CloseAbnormalClosure
+		res.Err = closeErr
+
+		return
+	}
+
+	ws.CloseCode = ce.Code
+
+	if ce.Code != websocket.CloseNormalClosure && ce.Code != websocket.CloseGoingAway && ce.Code != websocket.CloseNoStatusReceived {
+		res.Err = fmt.Errorf("websocket closed with %d %s", ce.Code, ce.Text)
+	}
+}
+
+// waitWebSocket waits until the time unless the server closes the session (returning the read error)
+// or the replay is cancelled before
+func waitWebSocket(ctx context.Context, until time.Time, closed chan error) (bool, error) {
+	timer := time.NewTimer(time.Until(until))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return false, nil
+	case err := <-closed:
+		return true, err
+	case <-ctx.Done():
+		return false, nil
+	}
+}
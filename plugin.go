@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"plugin"
+	"strings"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+)
+
+// builtinFileTypes are -file-type values handled by newLogReader itself
+var builtinFileTypes = []string{"nginx", "nginx-json", "apache", "alb", "envoy", "envoy-json", "haproxy", "solr", "regex", "pcap", "grpc", "har", "es-slowlog", "mysql-slowlog", "postgres-csvlog", "jsonl"}
+
+// loadReaderPlugins opens Go plugins of -reader-plugin, init functions of the plugins register their readers with reader.Register
+func loadReaderPlugins() {
+	for _, path := range strings.Split(readerPlugins, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+
+		if _, err := plugin.Open(path); err != nil {
+			log.Fatalf("ERROR while loading reader plugin %s: %s", path, err)
+		}
+	}
+}
+
+// fileTypes lists built in and registered -file-type values for error messages
+func fileTypes() string {
+	types := append(append([]string{}, builtinFileTypes...), reader.Registered()...)
+
+	return strings.Join(types[:len(types)-1], ", ") + " or " + types[len(types)-1]
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// prefixList collects repeated -prefix flags with optional =weight suffix
+type prefixList struct {
+	targets []replay.Target
+	set     bool
+}
+
+func newPrefixList(defaultPrefix string) *prefixList {
+	return &prefixList{targets: []replay.Target{{URL: defaultPrefix, Weight: 1}}}
+}
+
+func (p *prefixList) String() string {
+	if p == nil {
+		return ""
+	}
+
+	var specs []string
+
+	for _, target := range p.targets {
+		if len(p.targets) == 1 {
+			specs = append(specs, target.URL)
+		} else {
+			specs = append(specs, fmt.Sprintf("%s=%d", target.URL, target.Weight))
+		}
+	}
+
+	return strings.Join(specs, ",")
+}
+
+// Set parses URL with optional =weight suffix, the first call replaces the default prefix
+func (p *prefixList) Set(spec string) error {
+	if !p.set {
+		p.set = true
+		p.targets = nil
+	}
+
+	target := replay.Target{URL: spec, Weight: 1}
+
+	if i := strings.LastIndex(spec, "="); i >= 0 {
+		if weight, err := strconv.Atoi(spec[i+1:]); err == nil {
+			if weight <= 0 {
+				return fmt.Errorf("prefix weight has to be positive, not '%d'", weight)
+			}
+
+			target.URL = spec[:i]
+			target.Weight = weight
+		}
+	}
+
+	p.targets = append(p.targets, target)
+
+	return nil
+}
+
+// routeList collects repeated -route flags
+type routeList struct {
+	routes []replay.Route
+}
+
+func (r *routeList) String() string {
+	if r == nil {
+		return ""
+	}
+
+	var patterns []string
+
+	for _, route := range r.routes {
+		patterns = append(patterns, route.Pattern)
+	}
+
+	return strings.Join(patterns, ",")
+}
+
+func (r *routeList) Set(pattern string) error {
+	route, err := replay.ParseRoute(pattern)
+
+	if err != nil {
+		return err
+	}
+
+	r.routes = append(r.routes, route)
+
+	return nil
+}
+
+// routeTimeoutList collects repeated -route-timeout flags
+type routeTimeoutList struct {
+	timeouts []replay.RouteTimeout
+}
+
+func (r *routeTimeoutList) String() string {
+	if r == nil {
+		return ""
+	}
+
+	var specs []string
+
+	for _, rt := range r.timeouts {
+		specs = append(specs, fmt.Sprintf("%s=%s", rt.Route.Pattern, rt.Timeout))
+	}
+
+	return strings.Join(specs, ",")
+}
+
+// Set parses route pattern with =timeout suffix
+func (r *routeTimeoutList) Set(spec string) error {
+	i := strings.LastIndex(spec, "=")
+
+	if i < 0 {
+		return fmt.Errorf("Invalid route timeout '%s', expected route=timeout like /poll/*=5m", spec)
+	}
+
+	route, err := replay.ParseRoute(spec[:i])
+
+	if err != nil {
+		return err
+	}
+
+	timeout, err := time.ParseDuration(spec[i+1:])
+
+	if err != nil || timeout < 0 {
+		return fmt.Errorf("Invalid timeout in '%s', expected duration like 5m or 0 for no timeout", spec)
+	}
+
+	r.timeouts = append(r.timeouts, replay.RouteTimeout{Route: route, Timeout: timeout})
+
+	return nil
+}
+
+// resolveList collects repeated -resolve flags in host:port:address form
+type resolveList struct {
+	addrs map[string]string
+}
+
+func (r *resolveList) String() string {
+	if r == nil {
+		return ""
+	}
+
+	var specs []string
+
+	for hostPort, addr := range r.addrs {
+		specs = append(specs, hostPort+":"+addr)
+	}
+
+	sort.Strings(specs)
+
+	return strings.Join(specs, ",")
+}
+
+// Set parses host:port:address, IPv6 addresses can be enclosed in brackets
+func (r *resolveList) Set(spec string) error {
+	parts := strings.SplitN(spec, ":", 3)
+
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return fmt.Errorf("Invalid resolve entry '%s', expected host:port:address like api.example.com:443:10.0.0.5", spec)
+	}
+
+	if port, err := strconv.Atoi(parts[1]); err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("Invalid port in resolve entry '%s'", spec)
+	}
+
+	if r.addrs == nil {
+		r.addrs = make(map[string]string)
+	}
+
+	host := strings.ToLower(parts[0])
+	addr := parts[2]
+
+	// [::1] is taken as a bare address, [::1]:8443 keeps the brackets to tell the port apart
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		addr = addr[1 : len(addr)-1]
+	}
+
+	r.addrs[net.JoinHostPort(host, parts[1])] = addr
+
+	return nil
+}
+
+// extractList collects repeated -extract flags
+type extractList struct {
+	rules []replay.ExtractRule
+}
+
+func (e *extractList) String() string {
+	if e == nil {
+		return ""
+	}
+
+	var specs []string
+
+	for _, rule := range e.rules {
+		specs = append(specs, rule.Spec)
+	}
+
+	return strings.Join(specs, " ")
+}
+
+func (e *extractList) Set(spec string) error {
+	rule, err := replay.ParseExtractRule(spec)
+
+	if err != nil {
+		return err
+	}
+
+	e.rules = append(e.rules, rule)
+
+	return nil
+}
+
+// headerList collects repeated -header flags in Name: value form
+type headerList struct {
+	headers []replay.Header
+}
+
+func (h *headerList) String() string {
+	if h == nil {
+		return ""
+	}
+
+	var specs []string
+
+	for _, header := range h.headers {
+		specs = append(specs, header.Name+": "+header.Value)
+	}
+
+	return strings.Join(specs, ", ")
+}
+
+func (h *headerList) Set(spec string) error {
+	name, value, ok := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("Invalid header '%s', expected Name: value", spec)
+	}
+
+	h.headers = append(h.headers, replay.Header{Name: name, Value: strings.TrimSpace(value)})
+
+	return nil
+}
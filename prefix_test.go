@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+func TestPrefixListSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		specs    []string
+		expected []replay.Target
+		err      string
+	}{
+		{name: "default", expected: []replay.Target{{URL: "http://localhost:8080", Weight: 1}}},
+		{name: "replaces default", specs: []string{"http://a:8080"}, expected: []replay.Target{{URL: "http://a:8080", Weight: 1}}},
+		{
+			name:     "weights",
+			specs:    []string{"http://a:8080=3", "http://b:8080", "http://c:8080=1"},
+			expected: []replay.Target{{URL: "http://a:8080", Weight: 3}, {URL: "http://b:8080", Weight: 1}, {URL: "http://c:8080", Weight: 1}},
+		},
+		{name: "equals sign in the prefix", specs: []string{"http://a/?key=value"}, expected: []replay.Target{{URL: "http://a/?key=value", Weight: 1}}},
+		{name: "weighted prefix with query", specs: []string{"http://a/?key=value=2"}, expected: []replay.Target{{URL: "http://a/?key=value", Weight: 2}}},
+		{name: "zero weight", specs: []string{"http://a:8080=0"}, err: "prefix weight has to be positive, not '0'"},
+		{name: "negative weight", specs: []string{"http://a:8080=1", "http://b:8080=-2"}, err: "prefix weight has to be positive, not '-2'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := newPrefixList("http://localhost:8080")
+
+			var err error
+
+			for _, spec := range tt.specs {
+				if err = list.Set(spec); err != nil {
+					break
+				}
+			}
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(list.targets, tt.expected) {
+				t.Errorf("targets %+v, expected %+v", list.targets, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRouteTimeoutListSet(t *testing.T) {
+	tests := []struct {
+		spec    string
+		pattern string
+		timeout time.Duration
+		err     string
+	}{
+		{spec: "/poll/*=5m", pattern: "/poll/*", timeout: 5 * time.Minute},
+		{spec: "/users/{id}=1.5s", pattern: "/users/{id}", timeout: 1500 * time.Millisecond},
+		{spec: "/stream=0", pattern: "/stream", timeout: 0},
+		{spec: "/search", err: "Invalid route timeout '/search', expected route=timeout"},
+		{spec: "search=1s", err: "route has to start with /"},
+		{spec: "/a/*/b=1s", err: "* can only be the last segment"},
+		{spec: "/search=", err: "Invalid timeout in '/search='"},
+		{spec: "/search=fast", err: "Invalid timeout"},
+		{spec: "/search=-1s", err: "Invalid timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			list := &routeTimeoutList{}
+			err := list.Set(tt.spec)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(list.timeouts) != 1 || list.timeouts[0].Route.Pattern != tt.pattern || list.timeouts[0].Timeout != tt.timeout {
+				t.Errorf("timeouts %+v, expected %s=%s", list.timeouts, tt.pattern, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestResolveListSet(t *testing.T) {
+	tests := []struct {
+		specs    []string
+		expected map[string]string
+		err      string
+	}{
+		{specs: []string{"api.example.com:443:10.0.0.5"}, expected: map[string]string{"api.example.com:443": "10.0.0.5"}},
+		{specs: []string{"API.example.com:80:10.0.0.5:8080"}, expected: map[string]string{"api.example.com:80": "10.0.0.5:8080"}},
+		{specs: []string{"a:80:[::1]", "b:443:[::1]:8443"}, expected: map[string]string{"a:80": "::1", "b:443": "[::1]:8443"}},
+		{specs: []string{"a:80:10.0.0.1", "a:80:10.0.0.2"}, expected: map[string]string{"a:80": "10.0.0.2"}},
+		{specs: []string{"a:80"}, err: "Invalid resolve entry 'a:80'"},
+		{specs: []string{":80:10.0.0.1"}, err: "Invalid resolve entry"},
+		{specs: []string{"a:80:"}, err: "Invalid resolve entry"},
+		{specs: []string{"a:http:10.0.0.1"}, err: "Invalid port in resolve entry 'a:http:10.0.0.1'"},
+		{specs: []string{"a:0:10.0.0.1"}, err: "Invalid port"},
+		{specs: []string{"a:65536:10.0.0.1"}, err: "Invalid port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.specs, ","), func(t *testing.T) {
+			list := &resolveList{}
+
+			var err error
+
+			for _, spec := range tt.specs {
+				if err = list.Set(spec); err != nil {
+					break
+				}
+			}
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(list.addrs, tt.expected) {
+				t.Errorf("addresses %v, expected %v", list.addrs, tt.expected)
+			}
+		})
+	}
+}
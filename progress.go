@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/objstore"
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// progress tracks how much of the local input files was read, it is a replay sink
+// to learn the log time the replay reached
+type progress struct {
+	total int64
+	read  atomic.Int64
+	start time.Time
+
+	mu      sync.Mutex
+	logTime time.Time
+}
+
+// inputProgress is set by openInput when -progress is enabled and the input is made of local files only
+var inputProgress *progress
+
+// newProgress sums sizes of the files, progress is not tracked (nil) when some of them are not local
+func newProgress(files []string) *progress {
+	p := &progress{start: time.Now()}
+
+	for _, name := range files {
+		if objstore.IsURL(name) {
+			return nil
+		}
+
+		info, err := os.Stat(name)
+
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		p.total += info.Size()
+	}
+
+	return p
+}
+
+// countingReader adds bytes read from the file to the progress
+type countingReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (c countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.p.read.Add(int64(n))
+
+	return n, err
+}
+
+func (p *progress) Write(r *replay.Result) error {
+	return nil
+}
+
+func (p *progress) Dispatched(lag time.Duration, logTime time.Time) {
+	p.mu.Lock()
+	p.logTime = logTime
+	p.mu.Unlock()
+}
+
+func (p *progress) RequestStarted() {}
+
+// report logs the progress every interval until the context is done
+func (p *progress) report(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.print()
+		}
+	}
+}
+
+func (p *progress) print() {
+	read := p.read.Load()
+	elapsed := time.Since(p.start)
+
+	p.mu.Lock()
+	logTime := p.logTime
+	p.mu.Unlock()
+
+	if read == 0 || p.total == 0 {
+		log.Printf("Progress 0.0%%, elapsed %s", elapsed.Round(time.Second))
+		return
+	}
+
+	// Reading runs ahead of the replay by the buffered entries only
+	if read > p.total {
+		read = p.total
+	}
+
+	eta := time.Duration(float64(elapsed) * float64(p.total-read) / float64(read))
+
+	var at string
+
+	if !logTime.IsZero() {
+		at = ", log time " + logTime.Format(time.RFC3339)
+	}
+
+	log.Printf("Progress %.1f%%%s, elapsed %s, ETA %s (%s)", float64(read)*100/float64(p.total), at,
+		elapsed.Round(time.Second), eta.Round(time.Second), time.Now().Add(eta).Format("15:04:05"))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// stringList collects values of a repeated flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+
+	return nil
+}
+
+// queryParam is a name=value pair given on the command line
+type queryParam struct {
+	Name  string
+	Value string
+}
+
+func parseQueryParams(flagName string, specs []string) ([]queryParam, error) {
+	var params []queryParam
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%s has to be in name=value form, not '%s'", flagName, spec)
+		}
+
+		params = append(params, queryParam{Name: parts[0], Value: parts[1]})
+	}
+
+	return params, nil
+}
+
+// queryEditor drops, overrides and adds query parameters of replayed URLs,
+// untouched parameters keep their original order and encoding
+type queryEditor struct {
+	drop map[string]bool
+	set  []queryParam
+	add  []queryParam
+}
+
+func newQueryEditor(drop, set, add []string) (*queryEditor, error) {
+	editor := &queryEditor{drop: make(map[string]bool)}
+
+	for _, names := range drop {
+		for _, name := range strings.Split(names, ",") {
+			editor.drop[name] = true
+		}
+	}
+
+	var err error
+
+	if editor.set, err = parseQueryParams("set-query", set); err != nil {
+		return nil, err
+	}
+
+	if editor.add, err = parseQueryParams("add-query", add); err != nil {
+		return nil, err
+	}
+
+	// Overridden parameters are removed and appended with the new value
+	for _, param := range editor.set {
+		editor.drop[param.Name] = true
+	}
+
+	return editor, nil
+}
+
+// Apply returns the url with modified query, the fragment is kept at the end
+func (q *queryEditor) Apply(rawURL string) string {
+	var fragment string
+
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		rawURL, fragment = rawURL[:i], rawURL[i:]
+	}
+
+	path, query := rawURL, ""
+
+	if i := strings.IndexByte(rawURL, '?'); i >= 0 {
+		path, query = rawURL[:i], rawURL[i+1:]
+	}
+
+	var pairs []string
+
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+
+		name := pair
+
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			name = pair[:i]
+		}
+
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+
+		if !q.drop[name] {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	for _, params := range [][]queryParam{q.set, q.add} {
+		for _, param := range params {
+			pairs = append(pairs, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+		}
+	}
+
+	if len(pairs) == 0 {
+		return path + fragment
+	}
+
+	return path + "?" + strings.Join(pairs, "&") + fragment
+}
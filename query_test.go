@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQueryParams(t *testing.T) {
+	tests := []struct {
+		specs    []string
+		expected []queryParam
+		err      string
+	}{
+		{specs: nil},
+		{specs: []string{"a=1", "b=x=y"}, expected: []queryParam{{Name: "a", Value: "1"}, {Name: "b", Value: "x=y"}}},
+		{specs: []string{"empty="}, expected: []queryParam{{Name: "empty", Value: ""}}},
+		{specs: []string{"a=1", "a=2"}, expected: []queryParam{{Name: "a", Value: "1"}, {Name: "a", Value: "2"}}},
+		{specs: []string{"a"}, err: "set-query has to be in name=value form, not 'a'"},
+		{specs: []string{"=1"}, err: "not '=1'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.specs, "&"), func(t *testing.T) {
+			params, err := parseQueryParams("set-query", tt.specs)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(params, tt.expected) {
+				t.Errorf("params %+v, expected %+v", params, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQueryEditor(t *testing.T) {
+	tests := []struct {
+		name     string
+		drop     []string
+		set      []string
+		add      []string
+		url      string
+		expected string
+	}{
+		{name: "no changes", url: "/a?b=1&a=2", expected: "/a?b=1&a=2"},
+		{name: "drop", drop: []string{"utm_source,utm_medium", "ts"}, url: "/a?utm_source=x&q=1&ts=2&utm_medium=y", expected: "/a?q=1"},
+		{name: "drop every value of repeated key", drop: []string{"id"}, url: "/a?id=1&q=1&id=2", expected: "/a?q=1"},
+		{name: "drop all", drop: []string{"q"}, url: "/a?q=1", expected: "/a"},
+		{name: "drop key without value", drop: []string{"debug"}, url: "/a?debug&q=1", expected: "/a?q=1"},
+		{name: "drop escaped key", drop: []string{"a b"}, url: "/a?a%20b=1&a+b=2&c=3", expected: "/a?c=3"},
+		{name: "set replaces repeated key", set: []string{"id=9"}, url: "/a?id=1&q=1&id=2", expected: "/a?q=1&id=9"},
+		{name: "set missing key", set: []string{"lang=en"}, url: "/a", expected: "/a?lang=en"},
+		{name: "set empty value", set: []string{"q="}, url: "/a?q=1", expected: "/a?q="},
+		{name: "add keeps original", add: []string{"id=9", "id=10"}, url: "/a?id=1", expected: "/a?id=1&id=9&id=10"},
+		{name: "add escapes", add: []string{"q=a b&c"}, url: "/a", expected: "/a?q=a+b%26c"},
+		{name: "untouched pairs keep encoding", set: []string{"x=1"}, url: "/a?q=a%20b&e=", expected: "/a?q=a%20b&e=&x=1"},
+		{name: "empty pairs", drop: []string{"q"}, url: "/a?&q=1&&b=2&", expected: "/a?b=2"},
+		{name: "empty query", drop: []string{"q"}, url: "/a?", expected: "/a"},
+		{name: "fragment", add: []string{"x=1"}, url: "/a?q=1#top", expected: "/a?q=1&x=1#top"},
+		{name: "fragment with question mark", drop: []string{"q"}, url: "/a?q=1#top?b", expected: "/a#top?b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor, err := newQueryEditor(tt.drop, tt.set, tt.add)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if url := editor.Apply(tt.url); url != tt.expected {
+				t.Errorf("edited to %q, expected %q", url, tt.expected)
+			}
+		})
+	}
+
+	if _, err := newQueryEditor(nil, nil, []string{"bad"}); err == nil || !strings.Contains(err.Error(), "add-query") {
+		t.Errorf("expected add-query error, got %v", err)
+	}
+}
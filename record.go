@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+	"github.com/Gonzih/log-replay/pkg/reader/jsonl"
+)
+
+var recordListen string
+var recordUpstream string
+var recordMaxBody int64
+
+// recordFlags registers flags of the record command
+func recordFlags(fs *flag.FlagSet) {
+	fs.StringVar(&recordListen, "listen", ":8080", "Address to accept requests on")
+	fs.StringVar(&recordUpstream, "upstream", "", "URL of the service to proxy requests to, e.g. http://localhost:8081")
+	fs.StringVar(&outputFile, "out", "-", "File to write the jsonl log to, gzip compressed when the name ends with gz, default is stdout")
+	fs.Int64Var(&recordMaxBody, "max-body", 1<<20, "Request bodies up to this many bytes are recorded as payloads, longer ones are proxied but left out")
+}
+
+// recorder is a reverse proxy writing every request it passes on to the jsonl log
+type recorder struct {
+	proxy   *httputil.ReverseProxy
+	maxBody int64
+
+	mu      sync.Mutex
+	writer  *jsonl.Writer
+	count   int
+	skipped int
+}
+
+// statusRecorder remembers the response status, Unwrap lets the proxy flush streamed responses
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	entry := &reader.LogEntry{
+		Time:   time.Now(),
+		Method: r.Method,
+		URL:    r.URL.RequestURI(),
+		Host:   r.Host,
+		Scheme: "http",
+	}
+
+	if r.TLS != nil {
+		entry.Scheme = "https"
+	}
+
+	entry.SetRequestHeaders(r.Header)
+
+	var tooLong bool
+
+	if r.Body != nil && r.Body != http.NoBody {
+		// The body is read up to the limit, the rest is streamed to the upstream as is
+		payload, err := io.ReadAll(io.LimitReader(r.Body, rec.maxBody+1))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tooLong = int64(len(payload)) > rec.maxBody
+
+		if !tooLong {
+			entry.Payload = string(payload)
+		}
+
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(payload), r.Body), r.Body}
+	}
+
+	sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	rec.proxy.ServeHTTP(sw, r)
+
+	entry.Status = sw.status
+	entry.Duration = time.Since(entry.Time)
+	entry.ClientIP = reader.ParseClientIP(r.RemoteAddr)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if tooLong {
+		rec.skipped++
+		log.Printf("Body of %s %s is longer than %d bytes, recorded without payload", entry.Method, entry.URL, rec.maxBody)
+	}
+
+	if err := rec.writer.Write(entry); err != nil {
+		log.Printf("ERROR while recording %s %s: %s", entry.Method, entry.URL, err)
+		return
+	}
+
+	rec.count++
+}
+
+// runRecord is the record command, a reverse proxy in front of the upstream writing requests
+// in the jsonl format replayed with -file-type jsonl, until interrupted
+func runRecord(fs *flag.FlagSet) {
+	if recordUpstream == "" {
+		log.Fatal("Upstream URL is required, e.g. -upstream http://localhost:8081")
+	}
+
+	upstream, err := url.Parse(recordUpstream)
+	reader.Must(err)
+
+	if upstream.Scheme == "" || upstream.Host == "" {
+		log.Fatalf("Invalid upstream URL '%s', expected http://host:port", recordUpstream)
+	}
+
+	var out io.Writer = os.Stdout
+	closeOutput := func() error { return nil }
+
+	if outputFile != "-" {
+		file, err := os.Create(outputFile)
+		reader.Must(err)
+		out = file
+		closeOutput = file.Close
+
+		if strings.HasSuffix(outputFile, "gz") {
+			gz := gzip.NewWriter(file)
+			out = gz
+			closeOutput = func() error {
+				if err := gz.Close(); err != nil {
+					return err
+				}
+
+				return file.Close()
+			}
+		}
+	}
+
+	rec := &recorder{
+		proxy:   httputil.NewSingleHostReverseProxy(upstream),
+		maxBody: recordMaxBody,
+		writer:  jsonl.NewWriter(out),
+	}
+
+	// Host header of the client is kept, the upstream may route by it
+	director := rec.proxy.Director
+	rec.proxy.Director = func(r *http.Request) {
+		host := r.Host
+		director(r)
+		r.Host = host
+	}
+
+	server := &http.Server{Addr: recordListen, Handler: rec}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Requests in flight are recorded before the log is closed
+	shutdown := make(chan struct{})
+
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Recording requests to %s on %s", upstream, recordListen)
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+
+	<-shutdown
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	reader.Must(closeOutput())
+	log.Printf("Recorded %d requests, %d without payload", rec.count, rec.skipped)
+}
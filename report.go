@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// reportFlags registers flags of the report command
+func reportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&inputLogFile, "file", "-", "Timings log written by replay to read. Read from STDIN if file name is '-'")
+	fs.StringVar(&outputFormat, "output-format", "tsv", "Format the timings log was written in (tsv, json or csv)")
+	fs.StringVar(&histogramFile, "histogram-file", "", "File to write latency histogram to in HdrHistogram log format")
+	fs.Var(routes, "route", "Endpoint pattern to group results by, can be repeated (see replay flags)")
+	fs.IntVar(&endpointsTop, "endpoints", 10, "Number of endpoints with the most requests shown, 0 hides them")
+	fs.Var(failIf, "fail-if", "Exit with status 1 when the run meets the condition, can be repeated (see replay flags)")
+}
+
+// runReport is the report command printing summary of the timings log
+func runReport(fs *flag.FlagSet) {
+	var input io.Reader = os.Stdin
+
+	if inputLogFile != "-" {
+		file, err := os.Open(inputLogFile)
+		reader.Must(err)
+		defer file.Close()
+		input = file
+	}
+
+	results, err := replay.NewResultReader(outputFormat, input)
+	reader.Must(err)
+
+	sum := replay.NewSummary()
+	verify := replay.NewVerifySummary()
+	latency := replay.NewLatencyComparison()
+	endpoints := replay.NewEndpointSummary(routes.routes...)
+
+	for {
+		res, err := results.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		reader.Must(err)
+		reader.Must(sum.Write(res))
+		reader.Must(verify.Write(res))
+		reader.Must(latency.Write(res))
+		reader.Must(endpoints.Write(res))
+	}
+
+	sum.Print(os.Stdout)
+
+	if endpointsTop > 0 {
+		endpoints.Print(os.Stdout, endpointsTop)
+	}
+
+	// Logs of -verify-status and -compare-latency runs have original statuses and durations
+	if verify.Total > 0 {
+		verify.Print(os.Stdout)
+	}
+
+	if latency.Total > 0 {
+		latency.Print(os.Stdout)
+	}
+
+	if histogramFile != "" {
+		file, err := os.Create(histogramFile)
+		reader.Must(err)
+		defer file.Close()
+		reader.Must(sum.WriteHistogram(file))
+	}
+
+	if failIf.check(sum, verify) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rewriteRule is a sed like s/regex/replacement/ substitution applied to replayed URLs
+type rewriteRule struct {
+	spec        string
+	re          *regexp.Regexp
+	replacement string
+	global      bool
+}
+
+// parseRewriteRule parses s<delim>regex<delim>replacement<delim>[g], any character following
+// the s is the delimiter and it can be escaped with a backslash inside the regex and replacement
+func parseRewriteRule(spec string) (*rewriteRule, error) {
+	if len(spec) < 2 || spec[0] != 's' {
+		return nil, fmt.Errorf("rewrite rule has to look like s#regex#replacement#, not '%s'", spec)
+	}
+
+	delim := spec[1]
+
+	var parts []string
+	var part strings.Builder
+
+	for i := 2; i < len(spec); i++ {
+		switch {
+		case spec[i] == '\\' && i+1 < len(spec) && spec[i+1] == delim:
+			part.WriteByte(delim)
+			i++
+		case spec[i] == delim:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(spec[i])
+		}
+	}
+
+	parts = append(parts, part.String())
+
+	if len(parts) != 3 || (parts[2] != "" && parts[2] != "g") {
+		return nil, fmt.Errorf("rewrite rule has to look like s#regex#replacement#, not '%s'", spec)
+	}
+
+	re, err := regexp.Compile(parts[0])
+
+	if err != nil {
+		return nil, fmt.Errorf("ERROR %s while compiling rewrite rule '%s'", err, spec)
+	}
+
+	return &rewriteRule{spec: spec, re: re, replacement: parts[1], global: parts[2] == "g"}, nil
+}
+
+// Apply replaces the first match (all of them with g flag) in the url
+func (r *rewriteRule) Apply(url string) string {
+	if r.global {
+		return r.re.ReplaceAllString(url, r.replacement)
+	}
+
+	match := r.re.FindStringSubmatchIndex(url)
+
+	if match == nil {
+		return url
+	}
+
+	result := r.re.ExpandString(nil, r.replacement, url, match)
+
+	return url[:match[0]] + string(result) + url[match[1]:]
+}
+
+// rewriteRules collects repeated -rewrite flags, rules are applied in the order they were given
+type rewriteRules []*rewriteRule
+
+func (r *rewriteRules) String() string {
+	var specs []string
+
+	for _, rule := range *r {
+		specs = append(specs, rule.spec)
+	}
+
+	return strings.Join(specs, " ")
+}
+
+func (r *rewriteRules) Set(spec string) error {
+	rule, err := parseRewriteRule(spec)
+
+	if err != nil {
+		return err
+	}
+
+	*r = append(*r, rule)
+
+	return nil
+}
+
+// Apply runs all rules on the url
+func (r rewriteRules) Apply(url string) string {
+	for _, rule := range r {
+		url = rule.Apply(url)
+	}
+
+	return url
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRewriteRule(t *testing.T) {
+	tests := []struct {
+		spec        string
+		re          string
+		replacement string
+		global      bool
+		err         string
+	}{
+		{spec: "s#/v1/#/v2/#", re: "/v1/", replacement: "/v2/"},
+		{spec: "s|a|b|g", re: "a", replacement: "b", global: true},
+		{spec: `s/\/old\//\/new\//`, re: "/old/", replacement: "/new/"},
+		{spec: "s#id=[0-9]+##", re: "id=[0-9]+", replacement: ""},
+		{spec: "s###", re: "", replacement: ""},
+		{spec: "", err: "has to look like"},
+		{spec: "s", err: "has to look like"},
+		{spec: "x#a#b#", err: "has to look like"},
+		{spec: "s#a#b", err: "has to look like"},
+		{spec: "s#a#b#i", err: "has to look like"},
+		{spec: "s#a#b#g#", err: "has to look like"},
+		{spec: "s#(#b#", err: "while compiling rewrite rule 's#(#b#'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := parseRewriteRule(tt.spec)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rule.re.String() != tt.re || rule.replacement != tt.replacement || rule.global != tt.global {
+				t.Errorf("rule %q -> %q (global %t), expected %q -> %q (global %t)", rule.re, rule.replacement, rule.global, tt.re, tt.replacement, tt.global)
+			}
+		})
+	}
+}
+
+func TestRewriteRulesApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		specs    []string
+		url      string
+		expected string
+	}{
+		{name: "first match", specs: []string{"s#a#b#"}, url: "/a/a?a=1", expected: "/b/a?a=1"},
+		{name: "global", specs: []string{"s#a#b#g"}, url: "/a/a?a=1", expected: "/b/b?b=1"},
+		{name: "no match", specs: []string{"s#x#y#"}, url: "/a", expected: "/a"},
+		{name: "group reference", specs: []string{`s#^/users/([0-9]+)#/api/users/${1}#`}, url: "/users/42?x=1", expected: "/api/users/42?x=1"},
+		{name: "empty replacement", specs: []string{`s#&?debug=[^&]*##g`}, url: "/a?x=1&debug=1&debug=2", expected: "/a?x=1"},
+		{name: "rules in order", specs: []string{"s#/v1/#/v2/#", "s#/v2/#/v3/#"}, url: "/v1/items", expected: "/v3/items"},
+		{name: "fragment", specs: []string{"s/#.*$//"}, url: "/page?x=1#top", expected: "/page?x=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules rewriteRules
+
+			for _, spec := range tt.specs {
+				if err := rules.Set(spec); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if url := rules.Apply(tt.url); url != tt.expected {
+				t.Errorf("rewritten to %q, expected %q", url, tt.expected)
+			}
+
+			if rules.String() != strings.Join(tt.specs, " ") {
+				t.Errorf("String() is %q", rules.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/Gonzih/log-replay/pkg/reader"
+	"github.com/Gonzih/log-replay/pkg/replay"
+)
+
+// script runs on_request and on_response functions of the -script Lua file,
+// calls are serialized since Lua state can not be used concurrently
+type script struct {
+	mu         sync.Mutex
+	state      *lua.LState
+	onRequest  *lua.LFunction
+	onResponse *lua.LFunction
+}
+
+// loadScript runs the Lua file and looks up its hooks, print writes to the log instead of STDOUT
+// which may be the output of results
+func loadScript(file string) (*script, error) {
+	state := lua.NewState()
+
+	state.SetGlobal("print", state.NewFunction(func(L *lua.LState) int {
+		var args []string
+
+		for i := 1; i <= L.GetTop(); i++ {
+			args = append(args, L.ToStringMeta(L.Get(i)).String())
+		}
+
+		log.Println(strings.Join(args, "\t"))
+
+		return 0
+	}))
+
+	if err := state.DoFile(file); err != nil {
+		state.Close()
+		return nil, fmt.Errorf("ERROR while loading script %s: %s", file, err)
+	}
+
+	s := &script{state: state}
+	s.onRequest, _ = state.GetGlobal("on_request").(*lua.LFunction)
+	s.onResponse, _ = state.GetGlobal("on_response").(*lua.LFunction)
+
+	if s.onRequest == nil && s.onResponse == nil {
+		state.Close()
+		return nil, fmt.Errorf("Script %s has neither on_request nor on_response function", file)
+	}
+
+	return s, nil
+}
+
+// call calls the hook with the table and returns its result, errors are reported without the stack
+// trace as they end up in single line outputs
+func (s *script) call(hook *lua.LFunction, table *lua.LTable) (lua.LValue, error) {
+	if err := s.state.CallByParam(lua.P{Fn: hook, NRet: 1, Protect: true}, table); err != nil {
+		if apiErr, ok := err.(*lua.ApiError); ok {
+			return lua.LNil, errors.New(apiErr.Object.String())
+		}
+
+		return lua.LNil, err
+	}
+
+	ret := s.state.Get(-1)
+	s.state.Pop(1)
+
+	return ret, nil
+}
+
+// request implements replay.RequestHook, on_request gets a table with the request and the log entry fields,
+// changes of method, url, payload and headers are sent and returning false skips the request
+func (s *script) request(entry *reader.LogEntry, req *replay.HookRequest) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	L := s.state
+	table := L.NewTable()
+	headers := L.NewTable()
+	logHeaders := L.NewTable()
+
+	for name := range entry.Headers {
+		logHeaders.RawSetString(name, lua.LString(entry.Headers.Get(name)))
+	}
+
+	table.RawSetString("method", lua.LString(req.Method))
+	table.RawSetString("url", lua.LString(req.URL))
+	table.RawSetString("payload", lua.LString(req.Payload))
+	table.RawSetString("headers", headers)
+	table.RawSetString("ua", lua.LString(entry.UA))
+	table.RawSetString("referer", lua.LString(entry.Referer))
+	table.RawSetString("host", lua.LString(entry.Host))
+	table.RawSetString("scheme", lua.LString(entry.Scheme))
+	table.RawSetString("content_type", lua.LString(entry.ContentType))
+	table.RawSetString("client_ip", lua.LString(entry.ClientIP))
+	table.RawSetString("log_headers", logHeaders)
+	table.RawSetString("status", lua.LNumber(entry.Status))
+	table.RawSetString("duration", lua.LNumber(entry.Duration.Seconds()))
+	table.RawSetString("time", lua.LNumber(float64(entry.Time.UnixNano())/1e9))
+
+	ret, err := s.call(s.onRequest, table)
+
+	if err != nil {
+		return false, fmt.Errorf("on_request failed: %s", err)
+	}
+
+	req.Method = lua.LVAsString(table.RawGetString("method"))
+	req.URL = lua.LVAsString(table.RawGetString("url"))
+	req.Payload = lua.LVAsString(table.RawGetString("payload"))
+
+	if headers, ok := table.RawGetString("headers").(*lua.LTable); ok {
+		headers.ForEach(func(name, value lua.LValue) {
+			req.Header.Set(lua.LVAsString(name), lua.LVAsString(value))
+		})
+	}
+
+	return ret != lua.LFalse, nil
+}
+
+// Write implements replay.ResultSink calling on_response with a table of the result,
+// body is present when it was captured with -capture-body
+func (s *script) Write(res *replay.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	L := s.state
+	table := L.NewTable()
+	headers := L.NewTable()
+
+	for name, value := range res.Headers {
+		headers.RawSetString(name, lua.LString(value))
+	}
+
+	table.RawSetString("method", lua.LString(res.Method))
+	table.RawSetString("url", lua.LString(res.URL))
+	table.RawSetString("target", lua.LString(res.Target))
+	table.RawSetString("status", lua.LNumber(res.Status))
+	table.RawSetString("duration", lua.LNumber(res.Duration.Seconds()))
+	table.RawSetString("attempts", lua.LNumber(res.Attempts))
+	table.RawSetString("headers", headers)
+
+	if res.Err != nil {
+		table.RawSetString("error", lua.LString(res.Err.Error()))
+	}
+
+	if res.Body != nil {
+		table.RawSetString("body", lua.LString(res.Body))
+	}
+
+	if _, err := s.call(s.onResponse, table); err != nil {
+		return fmt.Errorf("on_response failed: %s", err)
+	}
+
+	return nil
+}
+
+func (s *script) Close() {
+	s.state.Close()
+}
+
+// hooks returns the request hook and the result sink of the functions the script has
+func (s *script) hooks() (replay.RequestHook, replay.ResultSink) {
+	var hook replay.RequestHook
+	var sink replay.ResultSink
+
+	if s.onRequest != nil {
+		hook = s.request
+	}
+
+	if s.onResponse != nil {
+		sink = s
+	}
+
+	return hook, sink
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusSet matches response statuses given as a comma separated list
+// of codes (404), classes (5xx) and ranges (500-503)
+type statusSet struct {
+	codes   map[int]bool
+	classes map[int]bool
+	ranges  [][2]int
+}
+
+func parseStatusSet(spec string) (*statusSet, error) {
+	set := &statusSet{codes: make(map[int]bool), classes: make(map[int]bool)}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+
+		if item == "" {
+			continue
+		}
+
+		if len(item) == 3 && strings.HasSuffix(item, "xx") && item[0] >= '1' && item[0] <= '5' {
+			set.classes[int(item[0]-'0')] = true
+			continue
+		}
+
+		if parts := strings.SplitN(item, "-", 2); len(parts) == 2 {
+			from, fromErr := strconv.Atoi(parts[0])
+			to, toErr := strconv.Atoi(parts[1])
+
+			if fromErr != nil || toErr != nil || from > to {
+				return nil, fmt.Errorf("Invalid status range '%s'", item)
+			}
+
+			set.ranges = append(set.ranges, [2]int{from, to})
+			continue
+		}
+
+		code, err := strconv.Atoi(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid status '%s', expected code (404), class (5xx) or range (500-503)", item)
+		}
+
+		set.codes[code] = true
+	}
+
+	return set, nil
+}
+
+// Match tells whether the status belongs to the set
+func (s *statusSet) Match(status int) bool {
+	if s.codes[status] || s.classes[status/100] {
+		return true
+	}
+
+	for _, r := range s.ranges {
+		if status >= r[0] && status <= r[1] {
+			return true
+		}
+	}
+
+	return false
+}
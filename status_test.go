@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusSet(t *testing.T) {
+	tests := []struct {
+		spec       string
+		matched    []int
+		notMatched []int
+		err        string
+	}{
+		{spec: "404", matched: []int{404}, notMatched: []int{0, 400, 403, 405}},
+		{spec: "4xx", matched: []int{400, 404, 499}, notMatched: []int{399, 500}},
+		{spec: " 5XX , 304 ", matched: []int{304, 500, 599}, notMatched: []int{200, 404}},
+		{spec: "500-503", matched: []int{500, 501, 503}, notMatched: []int{499, 504}},
+		{spec: "500-599,499", matched: []int{499, 500, 599}, notMatched: []int{498, 600}},
+		{spec: "1xx,2xx", matched: []int{100, 204}, notMatched: []int{300}},
+		{spec: "", notMatched: []int{0, 200}},
+		{spec: "6xx", err: "Invalid status '6xx'"},
+		{spec: "abc", err: "Invalid status 'abc'"},
+		{spec: "503-500", err: "Invalid status range '503-500'"},
+		{spec: "500-", err: "Invalid status range '500-'"},
+		{spec: "x-503", err: "Invalid status range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			set, err := parseStatusSet(tt.spec)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, status := range tt.matched {
+				if !set.Match(status) {
+					t.Errorf("%d does not match", status)
+				}
+			}
+
+			for _, status := range tt.notMatched {
+				if set.Match(status) {
+					t.Errorf("%d matches", status)
+				}
+			}
+		})
+	}
+}
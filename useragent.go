@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadUserAgents reads -random-ua file, one User-Agent per line, empty lines and lines starting with # are skipped
+func loadUserAgents(file string) ([]string, error) {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var agents []string
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		agents = append(agents, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("No User-Agents found in %s", file)
+	}
+
+	return agents, nil
+}